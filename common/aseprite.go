@@ -0,0 +1,492 @@
+package common
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	asepriteHeaderMagic = 0xA5E0
+	asepriteFrameMagic  = 0xF1FA
+
+	asepriteChunkOldPalette  = 0x0004
+	asepriteChunkOldPalette2 = 0x0011
+	asepriteChunkLayer       = 0x2004
+	asepriteChunkCel         = 0x2005
+	asepriteChunkTags        = 0x2018
+	asepriteChunkPalette     = 0x2019
+
+	asepriteCelRaw        = 0
+	asepriteCelLinked     = 1
+	asepriteCelCompressed = 2
+
+	asepriteLayerVisible = 1
+	asepriteLayerNormal  = 0
+
+	asepriteFlagLayerOpacity = 1
+
+	asepriteDepthRGBA      = 32
+	asepriteDepthGrayscale = 16
+	asepriteDepthIndexed   = 8
+)
+
+// AsepriteFile is the parts of an .ase file needed to draw it, every frame is the full size
+// of the sprite with cels placed on layers.
+type AsepriteFile struct {
+	Width            int
+	Height           int
+	Frames           []*AsepriteFrame
+	Layers           []*AsepriteLayer
+	Tags             []*SpriteTag
+	depth            int
+	flags            uint32
+	transparentIndex int
+	palette          []color.NRGBA
+}
+
+type AsepriteFrame struct {
+	Duration float64
+	Cels     []*AsepriteCel
+}
+
+type AsepriteLayer struct {
+	Name       string
+	Visible    bool
+	Type       int
+	ChildLevel int
+	Opacity    int
+	parent     *AsepriteLayer
+}
+
+type AsepriteCel struct {
+	Layer   int
+	X       int
+	Y       int
+	Opacity int
+	Image   *image.NRGBA
+	// linkedFrame is the frame to take the image from for linked cels, or -1
+	linkedFrame int
+}
+
+var asepriteTagDirections = []string{DirectionForward, DirectionReverse, DirectionPingPong, DirectionPingPongReverse}
+
+func LoadAseprite(fileName string) (*AsepriteFile, error) {
+	b, err := ioutil.ReadFile("res/" + fileName)
+	if err != nil {
+		return nil, err
+	}
+	return ReadAseprite(bytes.NewReader(b))
+}
+
+// ReadAseprite parses the binary .ase / .aseprite format, old palette chunks and the
+// chunks that only matter to the editor are skipped.
+func ReadAseprite(reader io.Reader) (*AsepriteFile, error) {
+	r := &asepriteReader{reader: reader}
+	r.dword() // file size
+	if magic := r.word(); magic != asepriteHeaderMagic && r.err == nil {
+		return nil, fmt.Errorf("not an aseprite file, magic number is %x", magic)
+	}
+	numFrames := int(r.word())
+	a := &AsepriteFile{
+		Width:  int(r.word()),
+		Height: int(r.word()),
+		depth:  int(r.word()),
+		flags:  r.dword(),
+	}
+	r.skip(2 + 4 + 4) // speed and two reserved dwords
+	a.transparentIndex = int(r.byte())
+	r.skip(3 + 2 + 1 + 1 + 2 + 2 + 2 + 2 + 84)
+	if r.err != nil {
+		return nil, fmt.Errorf("reading aseprite header: %v", r.err)
+	}
+	switch a.depth {
+	case asepriteDepthRGBA, asepriteDepthGrayscale, asepriteDepthIndexed:
+	default:
+		return nil, fmt.Errorf("unsupported color depth %v", a.depth)
+	}
+
+	for i := 0; i < numFrames; i++ {
+		frame, err := a.readFrame(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading frame %v: %v", i, err)
+		}
+		a.Frames = append(a.Frames, frame)
+	}
+	for _, tag := range a.Tags {
+		if tag.From < 0 || tag.To < tag.From || tag.To >= len(a.Frames) {
+			return nil, fmt.Errorf("tag %v has frames %v to %v, there are %v", tag.Name, tag.From, tag.To, len(a.Frames))
+		}
+	}
+	return a, nil
+}
+
+func (a *AsepriteFile) readFrame(r *asepriteReader) (*AsepriteFrame, error) {
+	// what is left of the frame after its header, chunks must fit inside it
+	remaining := int(r.dword()) - 16
+	if magic := r.word(); magic != asepriteFrameMagic && r.err == nil {
+		return nil, fmt.Errorf("bad frame magic number %x", magic)
+	}
+	numChunks := int(r.word())
+	frame := &AsepriteFrame{
+		Duration: float64(r.word()) / 1000.0,
+	}
+	r.skip(2)
+	if newChunks := int(r.dword()); newChunks != 0 {
+		numChunks = newChunks
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	for c := 0; c < numChunks; c++ {
+		size := int(r.dword())
+		chunkType := r.word()
+		if r.err != nil {
+			return nil, r.err
+		}
+		if size < 6 || size > remaining {
+			return nil, fmt.Errorf("chunk %x has a size of %v", chunkType, size)
+		}
+		remaining = remaining - size
+		data := r.bytes(size - 6)
+		if r.err != nil {
+			return nil, r.err
+		}
+		chunk := &asepriteReader{reader: bytes.NewReader(data)}
+		var err error
+		switch chunkType {
+		case asepriteChunkLayer:
+			a.readLayer(chunk)
+		case asepriteChunkCel:
+			var cel *AsepriteCel
+			cel, err = a.readCel(chunk)
+			if cel != nil {
+				frame.Cels = append(frame.Cels, cel)
+			}
+		case asepriteChunkTags:
+			err = a.readTags(chunk)
+		case asepriteChunkPalette:
+			a.readPalette(chunk)
+		case asepriteChunkOldPalette, asepriteChunkOldPalette2:
+			// only used when there is no new palette chunk
+			if len(a.palette) == 0 {
+				a.readOldPalette(chunk)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if chunk.err != nil {
+			return nil, fmt.Errorf("reading chunk %x: %v", chunkType, chunk.err)
+		}
+	}
+	return frame, nil
+}
+
+func (a *AsepriteFile) readLayer(r *asepriteReader) {
+	flags := r.word()
+	layer := &AsepriteLayer{
+		Visible: flags&asepriteLayerVisible != 0,
+		Type:    int(r.word()),
+	}
+	layer.ChildLevel = int(r.word())
+	r.skip(2 + 2 + 2) // default size and blend mode
+	layer.Opacity = int(r.byte())
+	if a.flags&asepriteFlagLayerOpacity == 0 {
+		layer.Opacity = 255
+	}
+	r.skip(3)
+	layer.Name = r.string()
+	// the parent is the closest layer above this one in the list that is one level up
+	for i := len(a.Layers) - 1; i >= 0; i-- {
+		if a.Layers[i].ChildLevel == layer.ChildLevel-1 {
+			layer.parent = a.Layers[i]
+			break
+		}
+	}
+	a.Layers = append(a.Layers, layer)
+}
+
+func (a *AsepriteFile) readCel(r *asepriteReader) (*AsepriteCel, error) {
+	cel := &AsepriteCel{
+		Layer:       int(r.word()),
+		X:           int(int16(r.word())),
+		Y:           int(int16(r.word())),
+		Opacity:     int(r.byte()),
+		linkedFrame: -1,
+	}
+	if cel.Layer >= len(a.Layers) && r.err == nil {
+		return nil, fmt.Errorf("cel is on layer %v, there are %v", cel.Layer, len(a.Layers))
+	}
+	celType := r.word()
+	r.skip(2 + 5) // z-index and reserved
+	switch celType {
+	case asepriteCelLinked:
+		cel.linkedFrame = int(r.word())
+		return cel, nil
+	case asepriteCelRaw, asepriteCelCompressed:
+		w, h := int(r.word()), int(r.word())
+		if r.err != nil {
+			return nil, r.err
+		}
+		// check the size before making room for the pixels, raw pixels have to fit in what is
+		// left of the chunk and compressed ones can't be much bigger than the canvas
+		limit := r.remaining()
+		if celType == asepriteCelCompressed {
+			limit = a.Width * a.Height * 4 * (a.depth / 8)
+		}
+		if w*h*(a.depth/8) > limit {
+			return nil, fmt.Errorf("cel is %vx%v, too big for a %vx%v sprite", w, h, a.Width, a.Height)
+		}
+		pixels := r.reader
+		if celType == asepriteCelCompressed {
+			z, err := zlib.NewReader(r.reader)
+			if err != nil {
+				return nil, fmt.Errorf("opening compressed cel: %v", err)
+			}
+			defer z.Close()
+			pixels = z
+		}
+		img, err := a.readPixels(pixels, w, h)
+		if err != nil {
+			return nil, err
+		}
+		cel.Image = img
+		return cel, nil
+	}
+	// tilemaps and unknown cel types are left out
+	return nil, nil
+}
+
+func (a *AsepriteFile) readPixels(reader io.Reader, w, h int) (*image.NRGBA, error) {
+	bytesPerPixel := a.depth / 8
+	data := make([]byte, w*h*bytesPerPixel)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, fmt.Errorf("reading cel pixels: %v", err)
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < w*h; i++ {
+		var c color.NRGBA
+		switch a.depth {
+		case asepriteDepthRGBA:
+			c = color.NRGBA{R: data[i*4], G: data[i*4+1], B: data[i*4+2], A: data[i*4+3]}
+		case asepriteDepthGrayscale:
+			c = color.NRGBA{R: data[i*2], G: data[i*2], B: data[i*2], A: data[i*2+1]}
+		case asepriteDepthIndexed:
+			index := int(data[i])
+			if index != a.transparentIndex && index < len(a.palette) {
+				c = a.palette[index]
+			}
+		}
+		img.SetNRGBA(i%w, i/w, c)
+	}
+	return img, nil
+}
+
+func (a *AsepriteFile) readTags(r *asepriteReader) error {
+	numTags := int(r.word())
+	r.skip(8)
+	for i := 0; i < numTags; i++ {
+		tag := &SpriteTag{
+			From: int(r.word()),
+			To:   int(r.word()),
+		}
+		direction := int(r.byte())
+		r.skip(2 + 6 + 3 + 1) // repeat, reserved and colour
+		tag.Name = r.string()
+		if direction >= len(asepriteTagDirections) {
+			return fmt.Errorf("tag %v has unknown direction %v", tag.Name, direction)
+		}
+		tag.Direction = asepriteTagDirections[direction]
+		a.Tags = append(a.Tags, tag)
+	}
+	return nil
+}
+
+func (a *AsepriteFile) readPalette(r *asepriteReader) {
+	size := int(r.dword())
+	first, last := int(r.dword()), int(r.dword())
+	r.skip(8)
+	if size > len(a.palette) {
+		a.palette = append(a.palette, make([]color.NRGBA, size-len(a.palette))...)
+	}
+	for i := first; i <= last && r.err == nil; i++ {
+		flags := r.word()
+		c := color.NRGBA{R: r.byte(), G: r.byte(), B: r.byte(), A: r.byte()}
+		if flags&1 != 0 {
+			r.string()
+		}
+		if i < len(a.palette) {
+			a.palette[i] = c
+		}
+	}
+}
+
+func (a *AsepriteFile) readOldPalette(r *asepriteReader) {
+	numPackets := int(r.word())
+	index := 0
+	for p := 0; p < numPackets && r.err == nil; p++ {
+		index = index + int(r.byte())
+		numColors := int(r.byte())
+		if numColors == 0 {
+			numColors = 256
+		}
+		for i := 0; i < numColors && r.err == nil; i++ {
+			c := color.NRGBA{R: r.byte(), G: r.byte(), B: r.byte(), A: 255}
+			for len(a.palette) <= index {
+				a.palette = append(a.palette, color.NRGBA{})
+			}
+			a.palette[index] = c
+			index++
+		}
+	}
+}
+
+// isVisible is true when the layer and every group it is inside are visible.
+func (r *AsepriteLayer) isVisible() bool {
+	for l := r; l != nil; l = l.parent {
+		if !l.Visible {
+			return false
+		}
+	}
+	return true
+}
+
+// Composite draws the visible layers of every frame into a single row, in the same layout
+// as the png files the game loads, with the sprite sheet describing each frame. Every layer
+// is drawn with normal blending.
+func (a *AsepriteFile) Composite() (*image.NRGBA, *SpriteSheet) {
+	sheetImage := image.NewNRGBA(image.Rect(0, 0, a.Width*len(a.Frames), a.Height))
+	sheet := &SpriteSheet{
+		Frames: []*SpriteFrame{},
+		Tags:   map[string]*SpriteTag{},
+	}
+	for i, frame := range a.Frames {
+		offsetX := i * a.Width
+		for _, cel := range a.sortedCels(frame) {
+			layer := a.Layers[cel.Layer]
+			if layer.Type != asepriteLayerNormal || !layer.isVisible() {
+				continue
+			}
+			img := cel.Image
+			if cel.linkedFrame >= 0 {
+				img = a.findLinkedImage(cel)
+			}
+			if img == nil {
+				continue
+			}
+			opacity := (cel.Opacity * layer.Opacity) / 255
+			drawNormal(sheetImage, img, offsetX+cel.X, cel.Y, offsetX, offsetX+a.Width, a.Height, opacity)
+		}
+		duration := frame.Duration
+		if duration <= 0 {
+			duration = defaultFrameDuration
+		}
+		sheet.Frames = append(sheet.Frames, &SpriteFrame{
+			Rect:     image.Rect(offsetX, 0, offsetX+a.Width, a.Height),
+			Duration: duration,
+		})
+	}
+	for _, tag := range a.Tags {
+		sheet.Tags[tag.Name] = tag
+	}
+	return sheetImage, sheet
+}
+
+// sortedCels returns the cels of the frame from the bottom layer to the top.
+func (a *AsepriteFile) sortedCels(frame *AsepriteFrame) []*AsepriteCel {
+	cels := []*AsepriteCel{}
+	for layer := range a.Layers {
+		for _, cel := range frame.Cels {
+			if cel.Layer == layer {
+				cels = append(cels, cel)
+			}
+		}
+	}
+	return cels
+}
+
+func (a *AsepriteFile) findLinkedImage(cel *AsepriteCel) *image.NRGBA {
+	if cel.linkedFrame >= len(a.Frames) {
+		return nil
+	}
+	for _, other := range a.Frames[cel.linkedFrame].Cels {
+		if other.Layer == cel.Layer {
+			return other.Image
+		}
+	}
+	return nil
+}
+
+// drawNormal draws src over dst at x, y, clipped to the frame between minX and maxX.
+func drawNormal(dst *image.NRGBA, src *image.NRGBA, x, y, minX, maxX, maxY int, opacity int) {
+	bounds := src.Bounds()
+	for sy := 0; sy < bounds.Dy(); sy++ {
+		for sx := 0; sx < bounds.Dx(); sx++ {
+			dx, dy := x+sx, y+sy
+			if dx < minX || dx >= maxX || dy < 0 || dy >= maxY {
+				continue
+			}
+			s := src.NRGBAAt(sx, sy)
+			sa := (int(s.A) * opacity) / 255
+			if sa == 0 {
+				continue
+			}
+			d := dst.NRGBAAt(dx, dy)
+			da := (int(d.A) * (255 - sa)) / 255
+			outA := sa + da
+			blend := func(sc, dc uint8) uint8 {
+				return uint8(((int(sc) * sa) + (int(dc) * da)) / outA)
+			}
+			dst.SetNRGBA(dx, dy, color.NRGBA{R: blend(s.R, d.R), G: blend(s.G, d.G), B: blend(s.B, d.B), A: uint8(outA)})
+		}
+	}
+}
+
+// asepriteReader reads little endian values, after the first error every read returns zero.
+type asepriteReader struct {
+	reader io.Reader
+	err    error
+}
+
+func (r *asepriteReader) bytes(n int) []byte {
+	b := make([]byte, n)
+	if r.err != nil {
+		return b
+	}
+	_, r.err = io.ReadFull(r.reader, b)
+	return b
+}
+
+// remaining is how much of a chunk is left to read.
+func (r *asepriteReader) remaining() int {
+	if b, ok := r.reader.(*bytes.Reader); ok {
+		return b.Len()
+	}
+	return 0
+}
+
+func (r *asepriteReader) skip(n int) {
+	r.bytes(n)
+}
+
+func (r *asepriteReader) byte() uint8 {
+	return r.bytes(1)[0]
+}
+
+func (r *asepriteReader) word() uint16 {
+	return binary.LittleEndian.Uint16(r.bytes(2))
+}
+
+func (r *asepriteReader) dword() uint32 {
+	return binary.LittleEndian.Uint32(r.bytes(4))
+}
+
+func (r *asepriteReader) string() string {
+	return string(r.bytes(int(r.word())))
+}
@@ -0,0 +1,239 @@
+package common
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testAseprite writes .ase files for the tests, one rgba layer with a chunk list per frame.
+type testAseprite struct {
+	width, height int
+	frames        []testAseFrame
+}
+
+type testAseFrame struct {
+	durationMs int
+	chunks     [][]byte
+}
+
+func (r *testAseprite) bytes() []byte {
+	var body bytes.Buffer
+	for _, frame := range r.frames {
+		var chunks bytes.Buffer
+		for _, chunk := range frame.chunks {
+			chunks.Write(chunk)
+		}
+		writeLE(&body, uint32(16+chunks.Len()), uint16(asepriteFrameMagic), uint16(len(frame.chunks)),
+			uint16(frame.durationMs), uint16(0), uint32(len(frame.chunks)))
+		body.Write(chunks.Bytes())
+	}
+	var header bytes.Buffer
+	writeLE(&header, uint32(128+body.Len()), uint16(asepriteHeaderMagic), uint16(len(r.frames)),
+		uint16(r.width), uint16(r.height), uint16(asepriteDepthRGBA), uint32(asepriteFlagLayerOpacity))
+	header.Write(make([]byte, 128-header.Len()))
+	return append(header.Bytes(), body.Bytes()...)
+}
+
+func writeLE(b *bytes.Buffer, values ...interface{}) {
+	for _, v := range values {
+		binary.Write(b, binary.LittleEndian, v)
+	}
+}
+
+func testChunk(chunkType uint16, data []byte) []byte {
+	var b bytes.Buffer
+	writeLE(&b, uint32(6+len(data)), chunkType)
+	b.Write(data)
+	return b.Bytes()
+}
+
+func testString(b *bytes.Buffer, s string) {
+	writeLE(b, uint16(len(s)))
+	b.WriteString(s)
+}
+
+func testLayerChunk(name string) []byte {
+	var b bytes.Buffer
+	writeLE(&b, uint16(asepriteLayerVisible), uint16(asepriteLayerNormal), uint16(0), uint16(0), uint16(0), uint16(0), uint8(255))
+	b.Write(make([]byte, 3))
+	testString(&b, name)
+	return testChunk(asepriteChunkLayer, b.Bytes())
+}
+
+// testCelChunk is a raw cel of one colour.
+func testCelChunk(layer, x, y, w, h int, c color.NRGBA) []byte {
+	var b bytes.Buffer
+	writeLE(&b, uint16(layer), int16(x), int16(y), uint8(255), uint16(asepriteCelRaw), int16(0))
+	b.Write(make([]byte, 5))
+	writeLE(&b, uint16(w), uint16(h))
+	for i := 0; i < w*h; i++ {
+		b.Write([]byte{c.R, c.G, c.B, c.A})
+	}
+	return testChunk(asepriteChunkCel, b.Bytes())
+}
+
+// testHugeCelChunk claims a cel far bigger than the pixels that follow it.
+func testHugeCelChunk(celType int) []byte {
+	var b bytes.Buffer
+	writeLE(&b, uint16(0), int16(0), int16(0), uint8(255), uint16(celType), int16(0))
+	b.Write(make([]byte, 5))
+	writeLE(&b, uint16(65535), uint16(65535))
+	pixels := []byte{testRed.R, testRed.G, testRed.B, testRed.A}
+	if celType == asepriteCelCompressed {
+		var z bytes.Buffer
+		w := zlib.NewWriter(&z)
+		w.Write(pixels)
+		w.Close()
+		pixels = z.Bytes()
+	}
+	b.Write(pixels)
+	return testChunk(asepriteChunkCel, b.Bytes())
+}
+
+func testLinkedCelChunk(layer, x, y, frame int) []byte {
+	var b bytes.Buffer
+	writeLE(&b, uint16(layer), int16(x), int16(y), uint8(255), uint16(asepriteCelLinked), int16(0))
+	b.Write(make([]byte, 5))
+	writeLE(&b, uint16(frame))
+	return testChunk(asepriteChunkCel, b.Bytes())
+}
+
+func testTagsChunk(tags ...*SpriteTag) []byte {
+	var b bytes.Buffer
+	writeLE(&b, uint16(len(tags)))
+	b.Write(make([]byte, 8))
+	for _, tag := range tags {
+		direction := 0
+		for i, d := range asepriteTagDirections {
+			if d == tag.Direction {
+				direction = i
+			}
+		}
+		writeLE(&b, uint16(tag.From), uint16(tag.To), uint8(direction))
+		b.Write(make([]byte, 2+6+3+1))
+		testString(&b, tag.Name)
+	}
+	return testChunk(asepriteChunkTags, b.Bytes())
+}
+
+var testRed = color.NRGBA{R: 255, A: 255}
+
+func TestReadAsepriteFramesAndTags(t *testing.T) {
+	ase := &testAseprite{width: 4, height: 4, frames: []testAseFrame{
+		{durationMs: 100, chunks: [][]byte{
+			testLayerChunk("body"),
+			testTagsChunk(&SpriteTag{Name: "wobble", From: 1, To: 2, Direction: DirectionPingPong}),
+			testCelChunk(0, 1, 1, 2, 2, testRed),
+		}},
+		{durationMs: 250, chunks: [][]byte{testCelChunk(0, 0, 0, 1, 1, testRed)}},
+		{durationMs: 100, chunks: [][]byte{testLinkedCelChunk(0, 1, 1, 0)}},
+	}}
+	a, err := ReadAseprite(bytes.NewReader(ase.bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Frames) != 3 || len(a.Layers) != 1 {
+		t.Fatalf("read %v frames and %v layers, want 3 and 1", len(a.Frames), len(a.Layers))
+	}
+	if a.Frames[1].Duration != 0.25 {
+		t.Errorf("second frame lasts %v, want 0.25", a.Frames[1].Duration)
+	}
+
+	img, sheet := a.Composite()
+	if img.Bounds().Dx() != 12 || img.Bounds().Dy() != 4 {
+		t.Errorf("sheet is %v, want the frames side by side in 12x4", img.Bounds())
+	}
+	// the first cel is placed at 1, 1 and the linked third frame shows the same
+	for _, x := range []int{1, 9} {
+		if img.NRGBAAt(x, 1) != testRed || img.NRGBAAt(x-1, 0).A != 0 {
+			t.Errorf("cel is not drawn at %v, 1", x)
+		}
+	}
+	tag, ok := sheet.Tags["wobble"]
+	if !ok || tag.From != 1 || tag.To != 2 || tag.Direction != DirectionPingPong {
+		t.Errorf("wobble tag is %+v", tag)
+	}
+}
+
+func TestReadAsepriteRejectsMalformedFiles(t *testing.T) {
+	files := map[string]*testAseprite{
+		"cel on a missing layer": {width: 4, height: 4, frames: []testAseFrame{
+			{durationMs: 100, chunks: [][]byte{testLayerChunk("body"), testCelChunk(3, 0, 0, 1, 1, testRed)}},
+		}},
+		"cel before any layer": {width: 4, height: 4, frames: []testAseFrame{
+			{durationMs: 100, chunks: [][]byte{testCelChunk(0, 0, 0, 1, 1, testRed)}},
+		}},
+		"raw cel bigger than its chunk": {width: 4, height: 4, frames: []testAseFrame{
+			{durationMs: 100, chunks: [][]byte{testLayerChunk("body"), testHugeCelChunk(asepriteCelRaw)}},
+		}},
+		"compressed cel far bigger than the canvas": {width: 4, height: 4, frames: []testAseFrame{
+			{durationMs: 100, chunks: [][]byte{testLayerChunk("body"), testHugeCelChunk(asepriteCelCompressed)}},
+		}},
+		"tag past the last frame": {width: 4, height: 4, frames: []testAseFrame{
+			{durationMs: 100, chunks: [][]byte{testLayerChunk("body"), testTagsChunk(&SpriteTag{Name: "run", From: 0, To: 4})}},
+		}},
+	}
+	for name, ase := range files {
+		if _, err := ReadAseprite(bytes.NewReader(ase.bytes())); err == nil {
+			t.Errorf("%v was read without an error", name)
+		}
+	}
+
+	// cut short and with a chunk bigger than its frame
+	good := (&testAseprite{width: 4, height: 4, frames: []testAseFrame{
+		{durationMs: 100, chunks: [][]byte{testLayerChunk("body"), testCelChunk(0, 0, 0, 2, 2, testRed)}},
+	}}).bytes()
+	if _, err := ReadAseprite(bytes.NewReader(good[:len(good)-5])); err == nil {
+		t.Errorf("a file cut short was read without an error")
+	}
+	oversized := append([]byte{}, good...)
+	binary.LittleEndian.PutUint32(oversized[128+16:], 1<<30)
+	if _, err := ReadAseprite(bytes.NewReader(oversized)); err == nil {
+		t.Errorf("a chunk bigger than its frame was read without an error")
+	}
+}
+
+// every .ase in res reads and matches the sprite sheet json written from it
+func TestReadShippedAsepriteFiles(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "res", "*.ase"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no .ase files found, %v", err)
+	}
+	for _, fileName := range files {
+		f, err := os.Open(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := ReadAseprite(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: %v", fileName, err)
+			continue
+		}
+		data, err := ioutil.ReadFile(strings.TrimSuffix(fileName, ".ase") + ".json")
+		if err != nil {
+			t.Errorf("%v has no sprite sheet: %v", fileName, err)
+			continue
+		}
+		sheet, err := ParseSpriteSheet(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sheet.Frames) != len(a.Frames) {
+			t.Errorf("%v has %v frames, its sprite sheet %v", fileName, len(a.Frames), len(sheet.Frames))
+			continue
+		}
+		for i, frame := range a.Frames {
+			if sheet.Frames[i].Duration != frame.Duration {
+				t.Errorf("%v frame %v lasts %v, its sprite sheet says %v", fileName, i, frame.Duration, sheet.Frames[i].Duration)
+			}
+		}
+	}
+}
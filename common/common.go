@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/hajimehoshi/ebiten/v2"
 	"image"
+	"image/draw"
 	"io/ioutil"
 	"log"
 
@@ -26,15 +27,41 @@ func LoadImage(imageFileName string) *ebiten.Image {
 }
 
 func loadImage(imageFileName string) *ebiten.Image {
-	b, err := ioutil.ReadFile(imageFileName)
+	img, err := ReadImage(imageFileName)
 	if err != nil {
 		log.Fatalf("failed to open file: %v", err)
 	}
-	img, _, err := image.Decode(bytes.NewReader(b))
+	return img
+}
+
+func ReadImage(imageFileName string) (*ebiten.Image, error) {
+	img, err := DecodeImage(imageFileName)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	return ebiten.NewImageFromImage(img)
+	return ebiten.NewImageFromImage(img), nil
+}
+
+func DecodeImage(imageFileName string) (image.Image, error) {
+	b, err := ioutil.ReadFile(imageFileName)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(b))
+	return img, err
+}
+
+// ReplacePixels copies src into dst so everything drawing dst sees the new pixels, it
+// returns false when the sizes are different and nothing was copied.
+func ReplacePixels(dst *ebiten.Image, src image.Image) bool {
+	bounds := src.Bounds()
+	if dst.Bounds().Dx() != bounds.Dx() || dst.Bounds().Dy() != bounds.Dy() {
+		return false
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	dst.ReplacePixels(rgba.Pix)
+	return true
 }
 
 func Overlap(x1, y1, w1, h1, x2, y2, w2, h2 float64) bool {
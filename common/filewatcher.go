@@ -0,0 +1,54 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// FileWatcher polls the modification time of every file under a directory, so it works
+// everywhere without needing file system notifications.
+type FileWatcher struct {
+	directory string
+	modTimes  map[string]time.Time
+}
+
+func NewFileWatcher(directory string) *FileWatcher {
+	w := &FileWatcher{
+		directory: directory,
+	}
+	w.modTimes = w.scan()
+	return w
+}
+
+// GetChanges returns the files that were added or modified since it was last called, relative
+// to the watched directory and using forward slashes.
+func (r *FileWatcher) GetChanges() []string {
+	modTimes := r.scan()
+	changes := []string{}
+	for name, modTime := range modTimes {
+		if old, ok := r.modTimes[name]; !ok || !old.Equal(modTime) {
+			changes = append(changes, name)
+		}
+	}
+	r.modTimes = modTimes
+	sort.Strings(changes)
+	return changes
+}
+
+func (r *FileWatcher) scan() map[string]time.Time {
+	modTimes := map[string]time.Time{}
+	filepath.Walk(r.directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		name, err := filepath.Rel(r.directory, path)
+		if err != nil {
+			return nil
+		}
+		modTimes[filepath.ToSlash(name)] = info.ModTime()
+		return nil
+	})
+	return modTimes
+}
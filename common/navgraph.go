@@ -0,0 +1,338 @@
+package common
+
+import (
+	"container/heap"
+	"math"
+)
+
+const (
+	NavWalk = "walk"
+	NavDrop = "drop"
+	NavJump = "jump"
+)
+
+const (
+	maxNavDropTiles = 12
+	navArcStep      = 0.02
+	// navDropTileCost is what each tile of a drop adds to its link, the cheapest way to
+	// cover ground so the path search never guesses more than that
+	navDropTileCost = 0.5
+)
+
+// NavProfile describes how a ground enemy moves, the jump links in a graph are only
+// added where a body with this profile could actually make the jump.
+type NavProfile struct {
+	JumpHeight float64
+	JumpTime   float64
+	Speed      float64
+	Height     float64
+}
+
+type NavNode struct {
+	X     int
+	Y     int
+	Links []*NavLink
+}
+
+type NavLink struct {
+	From *NavNode
+	To   *NavNode
+	Kind string
+	cost float64
+}
+
+// NavGraph has a node for every tile a body can stand in, with links for walking to the
+// neighbouring tile, dropping off a ledge and jumping to another surface.
+type NavGraph struct {
+	Nodes       []*NavNode
+	nodes       map[int]*NavNode
+	tiledGrid   *TiledGrid
+	profile     NavProfile
+	heightTiles int
+}
+
+func NewNavGraph(tiledGrid *TiledGrid, profile NavProfile) *NavGraph {
+	g := &NavGraph{
+		Nodes:       []*NavNode{},
+		nodes:       map[int]*NavNode{},
+		tiledGrid:   tiledGrid,
+		profile:     profile,
+		heightTiles: int(math.Ceil(profile.Height / TileSize)),
+	}
+	width, height := tiledGrid.GroundLayer.Width, tiledGrid.GroundLayer.Height
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if g.isStandable(x, y) {
+				n := &NavNode{X: x, Y: y}
+				g.nodes[g.key(x, y)] = n
+				g.Nodes = append(g.Nodes, n)
+			}
+		}
+	}
+	for _, n := range g.Nodes {
+		g.addWalkAndDropLinks(n)
+		g.addJumpLinks(n)
+	}
+	return g
+}
+
+func (r *NavGraph) key(x, y int) int {
+	return (y * r.tiledGrid.GroundLayer.Width) + x
+}
+
+func (r *NavGraph) GetNode(x, y int) *NavNode {
+	if x < 0 || y < 0 || x >= r.tiledGrid.GroundLayer.Width {
+		return nil
+	}
+	return r.nodes[r.key(x, y)]
+}
+
+// FindNode returns the node under the given feet position, looking a few tiles down for
+// bodies that are in the air.
+func (r *NavGraph) FindNode(feetX, feetY float64) *NavNode {
+	tx, ty := int(feetX/TileSize), int((feetY-1)/TileSize)
+	for i := 0; i < 4; i++ {
+		if n := r.GetNode(tx, ty+i); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+func (r *NavGraph) isClear(x, y int) bool {
+	for k := 0; k < r.heightTiles; k++ {
+		td := r.tiledGrid.GetTileData(x, y-k)
+		if td.Block || td.Damage {
+			return false
+		}
+	}
+	return true
+}
+
+// isOpen is true when a body can move sideways into the tile, ground enemies treat platforms
+// as walls so they can't be inside one.
+func (r *NavGraph) isOpen(x, y int) bool {
+	if !r.isClear(x, y) {
+		return false
+	}
+	for k := 0; k < r.heightTiles; k++ {
+		if r.tiledGrid.GetTileData(x, y-k).Platform {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *NavGraph) isStandable(x, y int) bool {
+	if !r.isOpen(x, y) {
+		return false
+	}
+	below := r.tiledGrid.GetTileData(x, y+1)
+	return (below.Block || below.Platform) && !below.Damage
+}
+
+func (r *NavGraph) addLink(from, to *NavNode, kind string, cost float64) {
+	from.Links = append(from.Links, &NavLink{
+		From: from,
+		To:   to,
+		Kind: kind,
+		cost: cost,
+	})
+}
+
+func (r *NavGraph) addWalkAndDropLinks(n *NavNode) {
+	for _, dx := range []int{-1, 1} {
+		x := n.X + dx
+		if next := r.GetNode(x, n.Y); next != nil {
+			r.addLink(n, next, NavWalk, 1)
+			continue
+		}
+		if next := r.slopeNeighbour(n, dx); next != nil {
+			r.addLink(n, next, NavWalk, 1)
+			continue
+		}
+		if !r.isOpen(x, n.Y) {
+			continue
+		}
+		for y := n.Y + 1; y <= n.Y+maxNavDropTiles; y++ {
+			td := r.tiledGrid.GetTileData(x, y)
+			if td.Block || td.Damage {
+				break
+			}
+			if landing := r.GetNode(x, y); landing != nil {
+				r.addLink(n, landing, NavDrop, 1+(float64(y-n.Y)*navDropTileCost))
+				break
+			}
+		}
+	}
+}
+
+// slopeNeighbour is the node a row up or down that a body walks onto off the top or bottom
+// of a slope.
+func (r *NavGraph) slopeNeighbour(n *NavNode, dx int) *NavNode {
+	if td := r.tiledGrid.GetTileData(n.X, n.Y); td.Slope != nil && td.Slope.Rises(dx) {
+		return r.GetNode(n.X+dx, n.Y-1)
+	}
+	if td := r.tiledGrid.GetTileData(n.X+dx, n.Y+1); td.Slope != nil && td.Slope.Rises(-dx) {
+		return r.GetNode(n.X+dx, n.Y+1)
+	}
+	return nil
+}
+
+func (r *NavGraph) addJumpLinks(n *NavNode) {
+	p := r.profile
+	if p.JumpHeight <= 0 || p.JumpTime <= 0 {
+		return
+	}
+	gravity := (2 * p.JumpHeight) / (p.JumpTime * p.JumpTime)
+	maxUp := int(p.JumpHeight / TileSize)
+	for dy := -maxNavDropTiles; dy <= maxUp; dy++ {
+		rise := float64(dy) * TileSize
+		if rise > p.JumpHeight {
+			continue
+		}
+		// time until the body comes back down to the height of the target surface
+		airTime := p.JumpTime + math.Sqrt((2*(p.JumpHeight-rise))/gravity)
+		reach := int((p.Speed * airTime) / TileSize)
+		for dx := -reach; dx <= reach; dx++ {
+			if dx == 0 || (dy == 0 && (dx == 1 || dx == -1)) {
+				continue
+			}
+			target := r.GetNode(n.X+dx, n.Y-dy)
+			if target == nil || (dy == 0 && r.isWalkable(n, target)) {
+				continue
+			}
+			if !r.arcIsClear(n, target, gravity) {
+				continue
+			}
+			distance := math.Sqrt(float64((dx * dx) + (dy * dy)))
+			r.addLink(n, target, NavJump, distance+2)
+		}
+	}
+}
+
+// isWalkable is true when every tile between two nodes on the same row can be stood on.
+func (r *NavGraph) isWalkable(from, to *NavNode) bool {
+	step := 1
+	if to.X < from.X {
+		step = -1
+	}
+	for x := from.X; x != to.X; x = x + step {
+		if r.GetNode(x, from.Y) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// arcIsClear follows the jump from one node to the other, stopping at ceilings and sliding
+// up walls the same way a body does when it is blocked, and checks that it lands on the target.
+func (r *NavGraph) arcIsClear(from, to *NavNode, gravity float64) bool {
+	p := r.profile
+	x, y := (float64(from.X)+0.5)*TileSize, float64(from.Y+1)*TileSize
+	endX, endY := (float64(to.X)+0.5)*TileSize, float64(to.Y+1)*TileSize
+	velocityY := (2 * p.JumpHeight) / p.JumpTime
+	direction := 1.0
+	if endX < x {
+		direction = -1
+	}
+	for t := 0.0; t < 10; t = t + navArcStep {
+		newY := y - (velocityY * navArcStep)
+		velocityY = velocityY - (gravity * navArcStep)
+		if velocityY < 0 && newY >= endY && x == endX {
+			return true
+		}
+		if r.isClear(int(x/TileSize), int((newY-1)/TileSize)) {
+			y = newY
+		} else if velocityY < 0 {
+			// landed somewhere other than the target
+			return false
+		} else {
+			velocityY = 0
+		}
+		newX := x + (direction * p.Speed * navArcStep)
+		if (direction > 0 && newX > endX) || (direction < 0 && newX < endX) {
+			newX = endX
+		}
+		if r.isOpen(int(newX/TileSize), int((y-1)/TileSize)) {
+			x = newX
+		}
+	}
+	return false
+}
+
+// FindPath returns the links to follow to get from one node to another, or nil if there
+// is no way there.
+func (r *NavGraph) FindPath(from, to *NavNode) []*NavLink {
+	if from == nil || to == nil {
+		return nil
+	}
+	if from == to {
+		return []*NavLink{}
+	}
+	cameFrom := map[*NavNode]*NavLink{}
+	costSoFar := map[*NavNode]float64{from: 0}
+	open := &navQueue{}
+	heap.Push(open, &navQueueItem{node: from, priority: 0})
+	for open.Len() > 0 {
+		current := heap.Pop(open).(*navQueueItem).node
+		if current == to {
+			break
+		}
+		for _, link := range current.Links {
+			cost := costSoFar[current] + link.cost
+			existing, seen := costSoFar[link.To]
+			if seen && cost >= existing {
+				continue
+			}
+			costSoFar[link.To] = cost
+			cameFrom[link.To] = link
+			heap.Push(open, &navQueueItem{node: link.To, priority: cost + navDistance(link.To, to)})
+		}
+	}
+	if _, ok := cameFrom[to]; !ok {
+		return nil
+	}
+	path := []*NavLink{}
+	for n := to; n != from; n = cameFrom[n].From {
+		path = append([]*NavLink{cameFrom[n]}, path...)
+	}
+	return path
+}
+
+// GetSurfaceExtent returns the left and right most nodes that can be walked to from the given node.
+func (r *NavGraph) GetSurfaceExtent(n *NavNode) (*NavNode, *NavNode) {
+	left, right := n, n
+	for next := r.GetNode(left.X-1, left.Y); next != nil; next = r.GetNode(left.X-1, left.Y) {
+		left = next
+	}
+	for next := r.GetNode(right.X+1, right.Y); next != nil; next = r.GetNode(right.X+1, right.Y) {
+		right = next
+	}
+	return left, right
+}
+
+// navDistance is the least the path between two nodes can cost, no link covers a tile of
+// distance for less than a long drop does.
+func navDistance(a, b *NavNode) float64 {
+	dx, dy := float64(a.X-b.X), float64(a.Y-b.Y)
+	return math.Sqrt((dx*dx)+(dy*dy)) * navDropTileCost
+}
+
+type navQueueItem struct {
+	node     *NavNode
+	priority float64
+}
+
+type navQueue []*navQueueItem
+
+func (q navQueue) Len() int            { return len(q) }
+func (q navQueue) Less(i, j int) bool  { return q[i].priority < q[j].priority }
+func (q navQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *navQueue) Push(x interface{}) { *q = append(*q, x.(*navQueueItem)) }
+func (q *navQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,209 @@
+package common
+
+import (
+	"testing"
+)
+
+// testNavProfile jumps a bit more than two tiles and about four tiles across.
+var testNavProfile = NavProfile{
+	JumpHeight: 40,
+	JumpTime:   0.35,
+	Speed:      100,
+	Height:     14,
+}
+
+// testGrid builds a level from rows of text, # is a wall, = a platform and ^ spikes.
+func testGrid(rows ...string) *TiledGrid {
+	tiles := map[rune]int{'#': 1, '=': 2, '^': 3}
+	layer := &Layer{Width: len(rows[0]), Height: len(rows), Name: "Ground"}
+	for _, row := range rows {
+		for _, c := range row {
+			layer.Data = append(layer.Data, tiles[c])
+		}
+	}
+	return &TiledGrid{
+		TileSet: &TileSet{FirstGid: 1},
+		TileMap: map[int]*TileData{
+			0: {Block: true, Friction: 1},
+			1: {Platform: true, Friction: 1},
+			2: {Damage: true, DamageAmount: 1, Friction: 1},
+		},
+		GroundLayer: layer,
+	}
+}
+
+// checkPath fails unless the links join up from one node to the other.
+func checkPath(t *testing.T, path []*NavLink, from, to *NavNode) {
+	t.Helper()
+	if path == nil {
+		t.Fatalf("no path from %v,%v to %v,%v", from.X, from.Y, to.X, to.Y)
+	}
+	at := from
+	for _, link := range path {
+		if link.From != at {
+			t.Fatalf("link starts at %v,%v, want %v,%v", link.From.X, link.From.Y, at.X, at.Y)
+		}
+		at = link.To
+	}
+	if at != to {
+		t.Errorf("path ends at %v,%v, want %v,%v", at.X, at.Y, to.X, to.Y)
+	}
+}
+
+func pathKinds(path []*NavLink) map[string]int {
+	kinds := map[string]int{}
+	for _, link := range path {
+		kinds[link.Kind]++
+	}
+	return kinds
+}
+
+func TestNavGraphWalksAndDrops(t *testing.T) {
+	graph := NewNavGraph(testGrid(
+		"..........",
+		"####......",
+		"..........",
+		"..........",
+		"##########",
+	), testNavProfile)
+
+	from, to := graph.GetNode(0, 0), graph.GetNode(9, 3)
+	path := graph.FindPath(from, to)
+	checkPath(t, path, from, to)
+	kinds := pathKinds(path)
+	if kinds[NavDrop] != 1 || kinds[NavJump] != 0 {
+		t.Errorf("path is %v, want to drop off the ledge once", kinds)
+	}
+
+	if graph.GetNode(2, 2) != nil {
+		t.Errorf("there is a node under the ledge")
+	}
+	if n := graph.FindNode((5*TileSize)+4, TileSize*2); n != graph.GetNode(5, 3) {
+		t.Errorf("a body falling over the floor should find the floor")
+	}
+	if path := graph.FindPath(from, from); path == nil || len(path) != 0 {
+		t.Errorf("the path to where the body already is should be empty, got %v", path)
+	}
+}
+
+func TestNavGraphJumpsGapsAndAvoidsSpikes(t *testing.T) {
+	graph := NewNavGraph(testGrid(
+		"............",
+		"............",
+		"............",
+		"####...#####",
+		"####^^^#####",
+	), testNavProfile)
+
+	from, to := graph.GetNode(0, 2), graph.GetNode(11, 2)
+	path := graph.FindPath(from, to)
+	checkPath(t, path, from, to)
+	if pathKinds(path)[NavJump] != 1 {
+		t.Errorf("path is %v, want one jump over the spikes", pathKinds(path))
+	}
+	for _, n := range graph.Nodes {
+		if n.Y == 3 {
+			t.Errorf("there is a node on the spikes at %v,%v", n.X, n.Y)
+		}
+	}
+}
+
+func TestNavGraphJumpsOntoPlatforms(t *testing.T) {
+	graph := NewNavGraph(testGrid(
+		"..........",
+		"..........",
+		"..........",
+		"....====..",
+		"..........",
+		"##########",
+	), testNavProfile)
+
+	from, to := graph.GetNode(0, 4), graph.GetNode(5, 2)
+	path := graph.FindPath(from, to)
+	checkPath(t, path, from, to)
+	if pathKinds(path)[NavJump] == 0 {
+		t.Errorf("path is %v, want a jump up to the platform", pathKinds(path))
+	}
+}
+
+func TestNavGraphCantReachHighLedges(t *testing.T) {
+	graph := NewNavGraph(testGrid(
+		"......",
+		"...###",
+		"...###",
+		"...###",
+		"...###",
+		"######",
+	), testNavProfile)
+
+	from, to := graph.GetNode(0, 4), graph.GetNode(4, 0)
+	if to == nil {
+		t.Fatal("there is no node on the ledge")
+	}
+	if path := graph.FindPath(from, to); path != nil {
+		t.Errorf("found a way up a wall higher than the jump, %v links", len(path))
+	}
+	if path := graph.FindPath(to, from); path == nil {
+		t.Errorf("there should be a way down off the ledge")
+	}
+	left, right := graph.GetSurfaceExtent(from)
+	if left.X != 0 || right.X != 2 {
+		t.Errorf("the floor goes from %v to %v, want 0 to 2", left.X, right.X)
+	}
+}
+
+func TestNavGraphTakesTheCheapestDrop(t *testing.T) {
+	// walking along the ledge and dropping the whole way is cheaper than dropping twice on
+	// the left and walking back along the floor
+	walker := testNavProfile
+	walker.JumpHeight = 0
+	graph := NewNavGraph(testGrid(
+		"#............",
+		"#............",
+		"#.########...",
+		"#............",
+		"#............",
+		"#............",
+		"#............",
+		"#............",
+		"##...........",
+		"#............",
+		"#............",
+		"#............",
+		"#............",
+		"#............",
+		"#############",
+	), walker)
+
+	from, to := graph.GetNode(2, 1), graph.GetNode(10, 13)
+	path := graph.FindPath(from, to)
+	checkPath(t, path, from, to)
+	if kinds := pathKinds(path); kinds[NavDrop] != 1 || kinds[NavWalk] != 7 {
+		t.Errorf("path is %v, want to walk along the ledge and drop once", kinds)
+	}
+}
+
+func TestNavGraphTreatsPlatformsAsWalls(t *testing.T) {
+	graph := NewNavGraph(testGrid(
+		"........",
+		"........",
+		"...=....",
+		"........",
+		"########",
+	), testNavProfile)
+
+	if graph.GetNode(3, 1) == nil || graph.GetNode(3, 3) == nil {
+		t.Fatal("a body a tile tall should stand on the platform and under it")
+	}
+	tall := testNavProfile
+	tall.Height = 24
+	graph = NewNavGraph(graph.tiledGrid, tall)
+	if graph.GetNode(3, 3) != nil {
+		t.Errorf("a body two tiles tall can stand inside the platform")
+	}
+	for _, link := range graph.GetNode(2, 3).Links {
+		if link.Kind == NavWalk && link.To.X == 3 {
+			t.Errorf("a body two tiles tall can walk into the side of the platform")
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package common
+
+import "math"
+
+type RayHit struct {
+	Hit      bool
+	X        float64
+	Y        float64
+	TileX    int
+	TileY    int
+	Distance float64
+}
+
+// Raycast walks every tile the segment passes over, in order, and stops at the first tile
+// that isOpaque returns true for. When nothing is hit the result is the end of the segment.
+func (tg *TiledGrid) Raycast(x1, y1, x2, y2 float64, isOpaque func(td *TileData) bool) RayHit {
+	dx, dy := x2-x1, y2-y1
+	length := math.Hypot(dx, dy)
+	result := RayHit{
+		X:        x2,
+		Y:        y2,
+		Distance: length,
+	}
+	if length == 0 {
+		return result
+	}
+	dirX, dirY := dx/length, dy/length
+	tx, ty := int(math.Floor(x1/TileSize)), int(math.Floor(y1/TileSize))
+	stepX, tMaxX, tDeltaX := raycastAxis(x1, dirX, tx)
+	stepY, tMaxY, tDeltaY := raycastAxis(y1, dirY, ty)
+
+	t := 0.0
+	for t <= length {
+		if isOpaque(tg.GetTileData(tx, ty)) {
+			result.Hit = true
+			result.TileX = tx
+			result.TileY = ty
+			result.Distance = t
+			result.X = x1 + (dirX * t)
+			result.Y = y1 + (dirY * t)
+			return result
+		}
+		if tMaxX < tMaxY {
+			t = tMaxX
+			tMaxX = tMaxX + tDeltaX
+			tx = tx + stepX
+		} else {
+			t = tMaxY
+			tMaxY = tMaxY + tDeltaY
+			ty = ty + stepY
+		}
+	}
+	return result
+}
+
+// raycastAxis returns the tile step direction, the distance along the ray to the first tile
+// boundary and the distance between boundaries for one axis.
+func raycastAxis(start, dir float64, tile int) (int, float64, float64) {
+	if dir > 0 {
+		return 1, ((float64(tile+1) * TileSize) - start) / dir, TileSize / dir
+	}
+	if dir < 0 {
+		return -1, (start - (float64(tile) * TileSize)) / -dir, TileSize / -dir
+	}
+	return 0, math.Inf(1), math.Inf(1)
+}
+
+// SegmentIntersectsBox returns whether the segment passes through the box, and how far along
+// the segment it first enters it, from 0 at the start to 1 at the end.
+func SegmentIntersectsBox(x1, y1, x2, y2, bx, by, bw, bh float64) (bool, float64) {
+	tMin, tMax := 0.0, 1.0
+	for _, axis := range [][4]float64{{x1, x2 - x1, bx, bx + bw}, {y1, y2 - y1, by, by + bh}} {
+		start, d, low, high := axis[0], axis[1], axis[2], axis[3]
+		if d == 0 {
+			if start < low || start > high {
+				return false, 0
+			}
+			continue
+		}
+		t1, t2 := (low-start)/d, (high-start)/d
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tMin = math.Max(tMin, t1)
+		tMax = math.Min(tMax, t2)
+		if tMin > tMax {
+			return false, 0
+		}
+	}
+	return true, tMin
+}
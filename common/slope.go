@@ -0,0 +1,39 @@
+package common
+
+import "math"
+
+// Slope is a floor that rises or falls across a tile, set with the "slope" tileset property.
+// Left and Right are the height of the floor at each edge, up from the bottom of the tile.
+type Slope struct {
+	Left  float64
+	Right float64
+}
+
+// slopeTypes are the values of the slope property, up slopes rise to the right. The 45
+// degree slopes fill one tile, the 22 degree ones take two, a low tile then a high one.
+var slopeTypes = map[string]*Slope{
+	"45-up":        {Left: 0, Right: TileSize},
+	"45-down":      {Left: TileSize, Right: 0},
+	"22-up-low":    {Left: 0, Right: TileSize / 2},
+	"22-up-high":   {Left: TileSize / 2, Right: TileSize},
+	"22-down-high": {Left: TileSize, Right: TileSize / 2},
+	"22-down-low":  {Left: TileSize / 2, Right: 0},
+}
+
+// HeightAt is the height of the floor x across the tile.
+func (s *Slope) HeightAt(x float64) float64 {
+	x = math.Max(0, math.Min(TileSize, x))
+	return s.Left + ((s.Right - s.Left) * x / TileSize)
+}
+
+// Rises is true when the floor goes up moving in the direction.
+func (s *Slope) Rises(direction int) bool {
+	return (s.Right-s.Left)*float64(direction) > 0
+}
+
+// Normal points out of the floor, leaning away from the high side.
+func (s *Slope) Normal() (float64, float64) {
+	rise := s.Right - s.Left
+	length := math.Sqrt((rise * rise) + (TileSize * TileSize))
+	return -rise / length, -TileSize / length
+}
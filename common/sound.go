@@ -4,12 +4,24 @@ import (
 	"fmt"
 	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+	"io"
 	"os"
+	"strings"
 )
 
 type SoundManager struct {
 	ctx    *audio.Context
 	sounds map[string]*audio.Player
+	files  map[string]string
+	// loops are sounds that start again when they reach the end, like music
+	loops map[string]bool
+}
+
+// soundStream is a decoded ogg or wav file.
+type soundStream interface {
+	io.ReadSeeker
+	Length() int64
 }
 
 const sampleRate = 44100
@@ -17,18 +29,38 @@ const sampleRate = 44100
 func NewManager() *SoundManager {
 	m := &SoundManager{
 		sounds: map[string]*audio.Player{},
+		files:  map[string]string{},
+		loops:  map[string]bool{},
 		ctx:    audio.NewContext(sampleRate),
 	}
 	return m
 }
 
+// NewSilentManager never opens an audio device, sounds can be added but playing them does
+// nothing. It is for running the game without speakers.
+func NewSilentManager() *SoundManager {
+	return &SoundManager{
+		sounds: map[string]*audio.Player{},
+		files:  map[string]string{},
+		loops:  map[string]bool{},
+	}
+}
+
+// LoadSound decodes an ogg or wav file.
 func (r *SoundManager) LoadSound(name string, file string) {
-	oggSound, err := os.Open(file)
+	if r.ctx == nil {
+		return
+	}
+	s, err := decodeSound(file)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprint(os.Stderr, "failed to decode sound "+err.Error())
+		return
 	}
-	s, err := vorbis.DecodeWithSampleRate(sampleRate, oggSound)
-	player, err := r.ctx.NewPlayer(s)
+	var stream io.Reader = s
+	if r.loops[name] {
+		stream = audio.NewInfiniteLoop(s, s.Length())
+	}
+	player, err := r.ctx.NewPlayer(stream)
 	if err != nil {
 		fmt.Fprint(os.Stderr, "failed to create player "+err.Error())
 		return
@@ -36,8 +68,63 @@ func (r *SoundManager) LoadSound(name string, file string) {
 	r.sounds[name] = player
 }
 
-func (r *SoundManager) PlaySound(name string) {
+func decodeSound(file string) (soundStream, error) {
+	soundFile, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(file, ".wav") {
+		return wav.DecodeWithSampleRate(sampleRate, soundFile)
+	}
+	return vorbis.DecodeWithSampleRate(sampleRate, soundFile)
+}
+
+// AddSoundFile registers a sound that is loaded the first time it is played.
+func (r *SoundManager) AddSoundFile(name string, file string) {
+	r.files[name] = file
+}
+
+// AddLoopingSoundFile registers a sound that plays over and over until it is stopped.
+func (r *SoundManager) AddLoopingSoundFile(name string, file string) {
+	r.files[name] = file
+	r.loops[name] = true
+}
+
+// UnloadSound frees the decoded sound, it is loaded again from its file if it is played later.
+func (r *SoundManager) UnloadSound(name string) {
 	p, ok := r.sounds[name]
+	if !ok {
+		return
+	}
+	if p != nil {
+		p.Close()
+	}
+	delete(r.sounds, name)
+}
+
+// getPlayer returns the player for the sound, loading it first if its file was registered.
+func (r *SoundManager) getPlayer(name string) (*audio.Player, bool) {
+	p, ok := r.sounds[name]
+	if !ok {
+		file, hasFile := r.files[name]
+		if !hasFile {
+			return nil, false
+		}
+		r.LoadSound(name, file)
+		p, ok = r.sounds[name]
+		if !ok {
+			// don't keep trying a file that can't be loaded
+			delete(r.files, name)
+		}
+	}
+	return p, ok
+}
+
+func (r *SoundManager) PlaySound(name string) {
+	if r.ctx == nil {
+		return
+	}
+	p, ok := r.getPlayer(name)
 	if !ok {
 		fmt.Fprint(os.Stderr, "failed to play sound, not loaded: "+name)
 		return
@@ -49,3 +136,28 @@ func (r *SoundManager) PlaySound(name string) {
 	p.Rewind()
 	p.Play()
 }
+
+func (r *SoundManager) StopSound(name string) {
+	p, ok := r.sounds[name]
+	if !ok || p == nil {
+		return
+	}
+	p.Pause()
+}
+
+// HasSound is true when the sound is loaded or can be loaded from a registered file.
+func (r *SoundManager) HasSound(name string) bool {
+	_, loaded := r.sounds[name]
+	_, hasFile := r.files[name]
+	return loaded || hasFile
+}
+
+func (r *SoundManager) PlaySoundWithVolume(name string, volume float64) {
+	p, ok := r.getPlayer(name)
+	if !ok || p == nil {
+		return
+	}
+	p.SetVolume(volume)
+	p.Rewind()
+	p.Play()
+}
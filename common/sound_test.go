@@ -0,0 +1,31 @@
+package common
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestShippedSoundsDecode(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "res", "sounds", "*.*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := 0
+	for _, fileName := range files {
+		if filepath.Ext(fileName) != ".wav" && filepath.Ext(fileName) != ".ogg" {
+			continue
+		}
+		s, err := decodeSound(fileName)
+		if err != nil {
+			t.Errorf("%v: %v", fileName, err)
+			continue
+		}
+		if s.Length() == 0 {
+			t.Errorf("%v is silent", fileName)
+		}
+		decoded++
+	}
+	if decoded == 0 {
+		t.Errorf("there are no sounds in res/sounds")
+	}
+}
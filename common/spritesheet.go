@@ -0,0 +1,269 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	DirectionForward         = "forward"
+	DirectionReverse         = "reverse"
+	DirectionPingPong        = "pingpong"
+	DirectionPingPongReverse = "pingpong_reverse"
+)
+
+// defaultFrameDuration is what Aseprite gives a frame when the duration is never changed.
+const defaultFrameDuration = 0.1
+
+// SpriteSheet is the frame layout, timing and tags of an image, read from the .ase file or
+// from the json that Aseprite exports next to the png.
+type SpriteSheet struct {
+	Frames []*SpriteFrame
+	Tags   map[string]*SpriteTag
+	// Version goes up every time the sheet is reloaded, so anything built from it can tell
+	Version int
+}
+
+type SpriteFrame struct {
+	Rect     image.Rectangle
+	Duration float64
+}
+
+type SpriteTag struct {
+	Name      string
+	From      int
+	To        int
+	Direction string
+}
+
+type asepriteRect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type asepriteSize struct {
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type asepriteFrame struct {
+	Filename         string       `json:"filename"`
+	Frame            asepriteRect `json:"frame"`
+	Rotated          bool         `json:"rotated"`
+	Trimmed          bool         `json:"trimmed"`
+	SpriteSourceSize asepriteRect `json:"spriteSourceSize"`
+	SourceSize       asepriteSize `json:"sourceSize"`
+	Duration         int          `json:"duration"`
+}
+
+type asepriteTag struct {
+	Name      string `json:"name"`
+	From      int    `json:"from"`
+	To        int    `json:"to"`
+	Direction string `json:"direction"`
+}
+
+type asepriteMeta struct {
+	App       string         `json:"app"`
+	Image     string         `json:"image"`
+	Format    string         `json:"format"`
+	Size      asepriteSize   `json:"size"`
+	Scale     string         `json:"scale"`
+	FrameTags []*asepriteTag `json:"frameTags"`
+}
+
+type asepriteExport struct {
+	Frames json.RawMessage `json:"frames"`
+	Meta   asepriteMeta    `json:"meta"`
+}
+
+func LoadSpriteSheet(fileName string) (*SpriteSheet, error) {
+	b, err := ioutil.ReadFile("res/" + fileName)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSpriteSheet(b)
+}
+
+// ParseSpriteSheet reads an Aseprite json export, the frames can be exported either as
+// an array or as a hash keyed by file name.
+func ParseSpriteSheet(data []byte) (*SpriteSheet, error) {
+	export := &asepriteExport{}
+	if err := json.Unmarshal(data, export); err != nil {
+		return nil, err
+	}
+	frames := []*asepriteFrame{}
+	if err := json.Unmarshal(export.Frames, &frames); err != nil {
+		hash := map[string]*asepriteFrame{}
+		if err := json.Unmarshal(export.Frames, &hash); err != nil {
+			return nil, fmt.Errorf("reading sprite sheet frames: %v", err)
+		}
+		for name, f := range hash {
+			f.Filename = name
+			frames = append(frames, f)
+		}
+		sort.Slice(frames, func(i, j int) bool {
+			return frameNumber(frames[i].Filename) < frameNumber(frames[j].Filename)
+		})
+	}
+	sheet := &SpriteSheet{
+		Frames: []*SpriteFrame{},
+		Tags:   map[string]*SpriteTag{},
+	}
+	for _, f := range frames {
+		duration := defaultFrameDuration
+		if f.Duration > 0 {
+			duration = float64(f.Duration) / 1000.0
+		}
+		sheet.Frames = append(sheet.Frames, &SpriteFrame{
+			Rect:     image.Rect(f.Frame.X, f.Frame.Y, f.Frame.X+f.Frame.W, f.Frame.Y+f.Frame.H),
+			Duration: duration,
+		})
+	}
+	for _, t := range export.Meta.FrameTags {
+		if t.From < 0 || t.To >= len(sheet.Frames) || t.From > t.To {
+			return nil, fmt.Errorf("tag %v has frames %v to %v but there are only %v frames", t.Name, t.From, t.To, len(sheet.Frames))
+		}
+		sheet.Tags[t.Name] = &SpriteTag{
+			Name:      t.Name,
+			From:      t.From,
+			To:        t.To,
+			Direction: t.Direction,
+		}
+	}
+	return sheet, nil
+}
+
+// EncodeSpriteSheet writes the sheet in the same json array format that Aseprite exports,
+// so it can be read back with ParseSpriteSheet.
+func EncodeSpriteSheet(sheet *SpriteSheet, name string, imageFileName string, width, height int) ([]byte, error) {
+	frames := []*asepriteFrame{}
+	for i, f := range sheet.Frames {
+		w, h := f.Rect.Dx(), f.Rect.Dy()
+		frames = append(frames, &asepriteFrame{
+			Filename:         fmt.Sprintf("%v %v.ase", name, i),
+			Frame:            asepriteRect{X: f.Rect.Min.X, Y: f.Rect.Min.Y, W: w, H: h},
+			SpriteSourceSize: asepriteRect{W: w, H: h},
+			SourceSize:       asepriteSize{W: w, H: h},
+			Duration:         int(math.Round(f.Duration * 1000)),
+		})
+	}
+	framesJson, err := json.MarshalIndent(frames, "", " ")
+	if err != nil {
+		return nil, err
+	}
+	tagNames := []string{}
+	for tagName := range sheet.Tags {
+		tagNames = append(tagNames, tagName)
+	}
+	sort.Slice(tagNames, func(i, j int) bool {
+		return sheet.Tags[tagNames[i]].From < sheet.Tags[tagNames[j]].From
+	})
+	export := &asepriteExport{
+		Frames: framesJson,
+		Meta: asepriteMeta{
+			App:       "http://www.aseprite.org/",
+			Image:     imageFileName,
+			Format:    "RGBA8888",
+			Size:      asepriteSize{W: width, H: height},
+			Scale:     "1",
+			FrameTags: []*asepriteTag{},
+		},
+	}
+	for _, tagName := range tagNames {
+		tag := sheet.Tags[tagName]
+		export.Meta.FrameTags = append(export.Meta.FrameTags, &asepriteTag{
+			Name:      tag.Name,
+			From:      tag.From,
+			To:        tag.To,
+			Direction: tag.Direction,
+		})
+	}
+	b, err := json.MarshalIndent(export, "", " ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// NewStripSpriteSheet treats the image as a row of square frames as tall as the image,
+// for images that were never exported with a json file.
+func NewStripSpriteSheet(width, height int) *SpriteSheet {
+	sheet := &SpriteSheet{
+		Frames: []*SpriteFrame{},
+		Tags:   map[string]*SpriteTag{},
+	}
+	if height == 0 {
+		return sheet
+	}
+	for x := 0; x+height <= width; x = x + height {
+		sheet.Frames = append(sheet.Frames, &SpriteFrame{
+			Rect:     image.Rect(x, 0, x+height, height),
+			Duration: defaultFrameDuration,
+		})
+	}
+	return sheet
+}
+
+// GetSequence returns the frames in the order they are played for the tag, ping-pong tags
+// come back through their middle frames. An empty tag plays every frame forwards.
+func (r *SpriteSheet) GetSequence(tagName string, isLoop bool) ([]*SpriteFrame, error) {
+	if tagName == "" {
+		return r.Frames, nil
+	}
+	tag, ok := r.Tags[tagName]
+	if !ok {
+		return nil, fmt.Errorf("missing tag %v", tagName)
+	}
+	forward := r.Frames[tag.From : tag.To+1]
+	backward := []*SpriteFrame{}
+	for i := len(forward) - 1; i >= 0; i-- {
+		backward = append(backward, forward[i])
+	}
+	switch tag.Direction {
+	case DirectionReverse:
+		return backward, nil
+	case DirectionPingPong:
+		return pingPong(forward, backward, isLoop), nil
+	case DirectionPingPongReverse:
+		return pingPong(backward, forward, isLoop), nil
+	}
+	return forward, nil
+}
+
+// pingPong joins the two halves without repeating the frame they share, when looping the
+// first frame is also left off the end as the loop goes back to it.
+func pingPong(there, back []*SpriteFrame, isLoop bool) []*SpriteFrame {
+	sequence := append([]*SpriteFrame{}, there...)
+	if len(back) < 2 {
+		return sequence
+	}
+	back = back[1:]
+	if isLoop {
+		back = back[:len(back)-1]
+	}
+	return append(sequence, back...)
+}
+
+// frameNumber is the number Aseprite puts at the end of each frame name, like "player 3.ase".
+func frameNumber(name string) int {
+	name = strings.TrimSuffix(name, ".ase")
+	name = strings.TrimSuffix(name, ".aseprite")
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return 0
+	}
+	n, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil {
+		return 0
+	}
+	return n
+}
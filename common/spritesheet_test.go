@@ -0,0 +1,91 @@
+package common
+
+import (
+	"fmt"
+	"testing"
+)
+
+// testSheetJson is an export of four 8x8 frames, with the frames as a hash as Aseprite can
+// also write them.
+const testSheetJson = `{
+ "frames": {
+  "walk 2.ase": {"frame": {"x": 16, "y": 0, "w": 8, "h": 8}, "duration": 100},
+  "walk 0.ase": {"frame": {"x": 0, "y": 0, "w": 8, "h": 8}, "duration": 100},
+  "walk 3.ase": {"frame": {"x": 24, "y": 0, "w": 8, "h": 8}, "duration": 0},
+  "walk 1.ase": {"frame": {"x": 8, "y": 0, "w": 8, "h": 8}, "duration": 250}
+ },
+ "meta": {
+  "frameTags": [
+   {"name": "all", "from": 0, "to": 3, "direction": "forward"},
+   {"name": "back", "from": 1, "to": 3, "direction": "reverse"},
+   {"name": "bob", "from": 0, "to": 2, "direction": "pingpong"},
+   {"name": "sway", "from": 1, "to": 3, "direction": "pingpong_reverse"}
+  ]
+ }
+}`
+
+// frameXs is where each frame of the sequence starts, in frames.
+func frameXs(frames []*SpriteFrame) string {
+	xs := []int{}
+	for _, f := range frames {
+		xs = append(xs, f.Rect.Min.X/8)
+	}
+	return fmt.Sprint(xs)
+}
+
+func TestParseSpriteSheet(t *testing.T) {
+	sheet, err := ParseSpriteSheet([]byte(testSheetJson))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := frameXs(sheet.Frames); got != "[0 1 2 3]" {
+		t.Errorf("frames are in the order %v, want them sorted by number", got)
+	}
+	if sheet.Frames[1].Duration != 0.25 {
+		t.Errorf("frame 1 lasts %v, want 0.25", sheet.Frames[1].Duration)
+	}
+	if sheet.Frames[3].Duration != defaultFrameDuration {
+		t.Errorf("a frame with no duration lasts %v, want %v", sheet.Frames[3].Duration, defaultFrameDuration)
+	}
+	if len(sheet.Tags) != 4 || sheet.Tags["bob"].Direction != DirectionPingPong {
+		t.Errorf("tags are %v", sheet.Tags)
+	}
+
+	if _, err := ParseSpriteSheet([]byte(`{"frames": [{"frame": {"w": 8, "h": 8}}], "meta": {"frameTags": [{"name": "x", "from": 0, "to": 2}]}}`)); err == nil {
+		t.Errorf("a tag past the last frame was accepted")
+	}
+}
+
+func TestSpriteSheetSequences(t *testing.T) {
+	sheet, err := ParseSpriteSheet([]byte(testSheetJson))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		tag    string
+		isLoop bool
+		want   string
+	}{
+		{"", true, "[0 1 2 3]"},
+		{"all", false, "[0 1 2 3]"},
+		{"back", false, "[3 2 1]"},
+		// a looping ping-pong goes back to its first frame by starting again
+		{"bob", true, "[0 1 2 1]"},
+		{"bob", false, "[0 1 2 1 0]"},
+		{"sway", true, "[3 2 1 2]"},
+		{"sway", false, "[3 2 1 2 3]"},
+	}
+	for _, test := range tests {
+		sequence, err := sheet.GetSequence(test.tag, test.isLoop)
+		if err != nil {
+			t.Errorf("tag %v: %v", test.tag, err)
+			continue
+		}
+		if got := frameXs(sequence); got != test.want {
+			t.Errorf("tag %v looping %v plays %v, want %v", test.tag, test.isLoop, got, test.want)
+		}
+	}
+	if _, err := sheet.GetSequence("missing", true); err == nil {
+		t.Errorf("a missing tag gave a sequence")
+	}
+}
@@ -6,7 +6,7 @@ import (
 )
 
 var (
-	textImage           = loadImage("common/text-source.png")
+	textImage           *ebiten.Image
 	textCharacterImages = map[rune]*ebiten.Image{}
 
 	characterInfo = map[rune]charInfo{
@@ -77,9 +77,26 @@ var (
 		'Y':  {index: 64, width: 5},
 		'Z':  {index: 65, width: 5},
 		'\'': {index: 66, width: 2},
+		'-':  {index: 67, width: 3},
+		':':  {index: 68, width: 1},
+		'_':  {index: 69, width: 5},
+		'>':  {index: 70, width: 4},
+		'/':  {index: 71, width: 5},
+		'=':  {index: 72, width: 4},
+		'+':  {index: 73, width: 5},
+		'(':  {index: 74, width: 2},
+		')':  {index: 75, width: 2},
 	}
 )
 
+const defaultTextImage = "common/text-source.png"
+
+// SetTextImage changes the image the characters are cut from.
+func SetTextImage(img *ebiten.Image) {
+	textImage = img
+	textCharacterImages = map[rune]*ebiten.Image{}
+}
+
 type charInfo struct {
 	index int
 	width int
@@ -141,6 +158,9 @@ func drawText(drawable Drawable, str string, ox, oy float64, alpha float64) {
 		}
 		s, ok := textCharacterImages[c]
 		if !ok {
+			if textImage == nil {
+				textImage = loadImage(defaultTextImage)
+			}
 			sx := ci.index * cw
 			rect := image.Rect(sx, 0, sx+ci.width, ch-1)
 			s = textImage.SubImage(rect).(*ebiten.Image)
@@ -3,6 +3,7 @@ package common
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"github.com/hajimehoshi/ebiten/v2"
 	"image"
 	_ "image/png"
@@ -10,6 +11,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 const (
@@ -24,30 +26,36 @@ const (
 type TiledGrid struct {
 	Layers            []*Layer            `json:"layers"`
 	TileSetReferences []*TileSetReference `json:"tilesets"`
+	Properties        []*TileConfigProp   `json:"properties"`
+	NextObjectId      int                 `json:"nextobjectid"`
 	TileSet           *TileSet
 	TileMap           map[int]*TileData
 	GroundLayer       *Layer
 	ObjectLayer       *Layer
 	BackgroundImage   string
+	fileName          string
 }
 
 type Layer struct {
-	Data    []int         `json:"Data"`
-	Height  int           `json:"height"`
-	Width   int           `json:"width"`
-	Name    string        `json:"name"`
-	Image   string        `json:"image"`
-	Objects []TiledObject `json:"objects"`
+	Data    []int          `json:"Data"`
+	Height  int            `json:"height"`
+	Width   int            `json:"width"`
+	Name    string         `json:"name"`
+	Image   string         `json:"image"`
+	Objects []*TiledObject `json:"objects"`
 }
 
 type TiledObject struct {
+	Id         int               `json:"id"`
 	Name       string            `json:"name"`
 	Type       string            `json:"type"`
 	X          int               `json:"x"`
 	Y          int               `json:"y"`
 	Width      int               `json:"width"`
 	Height     int               `json:"height"`
-	Properties []*TileConfigProp `json:"properties"`
+	Rotation   float64           `json:"rotation"`
+	Visible    bool              `json:"visible"`
+	Properties []*TileConfigProp `json:"properties,omitempty"`
 }
 
 type TileSetReference struct {
@@ -63,6 +71,7 @@ type TileSet struct {
 	numTilesY     int
 	FirstGid      int
 	Tiles         []*TileConfig `json:"tiles"`
+	source        image.Image
 	image         *ebiten.Image
 }
 
@@ -72,23 +81,60 @@ type TileConfig struct {
 }
 
 type TileConfigProp struct {
-	Name  string      `json:"Name"`
+	Name  string      `json:"name"`
 	Type  string      `json:"type"`
 	Value interface{} `json:"value"`
 }
 
+// GetLevelNames returns the names of the maps in the levels directory, as NewTileGrid takes them.
+func GetLevelNames() []string {
+	files, err := filepath.Glob(filepath.Join(resourceLevelsDirectory, "*.json"))
+	if err != nil {
+		log.Println("listing levels", err)
+		return []string{}
+	}
+	names := []string{}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		header := struct {
+			Type string `json:"type"`
+		}{}
+		if json.Unmarshal(b, &header) != nil || header.Type != "map" {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(filepath.Base(file), ".json"))
+	}
+	return names
+}
+
 func NewTileGrid(fileName string) *TiledGrid {
+	tiledGrid, err := LoadTileGrid(fileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return tiledGrid
+}
+
+// LoadTileGrid reads the map and its tileset, returning what went wrong instead of stopping
+// the game, for reloading files that may be half written.
+func LoadTileGrid(fileName string) (*TiledGrid, error) {
 	println("new tiled grid ", fileName)
-	var tiledGrid TiledGrid
+	tiledGrid := TiledGrid{
+		fileName: fileName,
+	}
 
 	levelFile, err := os.Open(filepath.Join(resourceLevelsDirectory, fileName+".json"))
 	if err != nil {
-		log.Fatal("opening config file", err.Error())
+		return nil, fmt.Errorf("opening config file %v: %w", fileName, err)
 	}
+	defer levelFile.Close()
 
 	jsonParser := json.NewDecoder(levelFile)
 	if err = jsonParser.Decode(&tiledGrid); err != nil {
-		log.Fatal("parsing config file", err.Error())
+		return nil, fmt.Errorf("parsing config file %v: %w", fileName, err)
 	}
 	for _, l := range tiledGrid.Layers {
 		if l.Name == groundLayer {
@@ -101,13 +147,46 @@ func NewTileGrid(fileName string) *TiledGrid {
 			tiledGrid.BackgroundImage = l.Image
 		}
 	}
+	if tiledGrid.GroundLayer == nil || len(tiledGrid.TileSetReferences) == 0 {
+		return nil, fmt.Errorf("config file %v needs a %v layer and a tileset", fileName, groundLayer)
+	}
 
-	tiledGrid.TileSet = loadTileSet(resourceLevelsDirectory, tiledGrid.TileSetReferences[0])
+	tiledGrid.TileSet, err = loadTileSet(resourceLevelsDirectory, tiledGrid.TileSetReferences[0])
+	if err != nil {
+		return nil, err
+	}
+	tiledGrid.loadTileMap()
+
+	return &tiledGrid, nil
+}
 
-	tiledGrid.TileMap = map[int]*TileData{}
-	for _, tile := range tiledGrid.TileSet.Tiles {
+// ReloadTileSet reads the tileset and its image again, keeping the same TileSet so anything
+// holding on to it sees the change. When it cannot be read the old tileset is kept.
+func (tg *TiledGrid) ReloadTileSet() error {
+	tileSet, err := loadTileSet(resourceLevelsDirectory, tg.TileSetReferences[0])
+	if err != nil {
+		return err
+	}
+	oldImage := tg.TileSet.image
+	*tg.TileSet = *tileSet
+	if oldImage != nil {
+		oldImage.Dispose()
+	}
+	tg.loadTileMap()
+	return nil
+}
+
+// UsesFile is true when the file, relative to res/, is the tileset or the tileset image.
+func (tg *TiledGrid) UsesFile(fileName string) bool {
+	levelsDirectory := strings.TrimPrefix(resourceLevelsDirectory, resourceDirectory)
+	return fileName == levelsDirectory+tg.TileSetReferences[0].Source || fileName == levelsDirectory+tg.TileSet.ImageFileName
+}
 
-		td := &TileData{}
+func (tg *TiledGrid) loadTileMap() {
+	tg.TileMap = map[int]*TileData{}
+	for _, tile := range tg.TileSet.Tiles {
+
+		td := &TileData{Friction: 1}
 		for _, prop := range tile.Properties {
 			if prop.Name == "block" && prop.Value != nil {
 				td.Block = (prop.Value).(bool)
@@ -121,40 +200,64 @@ func NewTileGrid(fileName string) *TiledGrid {
 			if prop.Name == "damage" && prop.Value != nil {
 				td.Damage = (prop.Value).(bool)
 			}
+			if prop.Name == "damage-amount" && prop.Value != nil {
+				td.DamageAmount = int((prop.Value).(float64))
+			}
+			if prop.Name == "water" && prop.Value != nil {
+				td.Water = (prop.Value).(bool)
+			}
+			if prop.Name == "friction" && prop.Value != nil {
+				td.Friction = (prop.Value).(float64)
+			}
+			if prop.Name == "conveyor" && prop.Value != nil {
+				td.Conveyor = (prop.Value).(float64)
+			}
+			if prop.Name == "bounce" && prop.Value != nil {
+				td.Bounce = (prop.Value).(float64)
+			}
+			if prop.Name == "slope" && prop.Value != nil {
+				slope, ok := slopeTypes[(prop.Value).(string)]
+				if !ok {
+					log.Println("warning: unknown slope", prop.Value, "on tile", tile.Id)
+				}
+				td.Slope = slope
+			}
+		}
+		if td.Damage && td.DamageAmount == 0 {
+			td.DamageAmount = 1
 		}
 		tileId := tile.Id
-		tiledGrid.TileMap[tileId] = td
+		tg.TileMap[tileId] = td
 	}
-
-	return &tiledGrid
 }
 
-func loadTileSet(levelDirectory string, ref *TileSetReference) *TileSet {
+func loadTileSet(levelDirectory string, ref *TileSetReference) (*TileSet, error) {
 	tileSetConfigFile, err := os.Open(filepath.Join(levelDirectory, ref.Source))
 	if err != nil {
-		log.Fatal("opening config file", err.Error())
+		return nil, fmt.Errorf("opening config file %v: %w", ref.Source, err)
 	}
+	defer tileSetConfigFile.Close()
 
 	var tileSet TileSet
 	jsonParser := json.NewDecoder(tileSetConfigFile)
 	if err = jsonParser.Decode(&tileSet); err != nil {
-		log.Fatal("parsing config file", err.Error())
+		return nil, fmt.Errorf("parsing config file %v: %w", ref.Source, err)
 	}
 	tileSet.numTilesX = tileSet.ImageWidth / TileSize
 	tileSet.numTilesY = tileSet.ImageHeight / TileSize
 
 	b, err := ioutil.ReadFile(filepath.Join(levelDirectory, tileSet.ImageFileName))
 	if err != nil {
-		log.Fatalf("failed to open file: %v", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	img, _, err := image.Decode(bytes.NewReader(b))
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("decoding %v: %w", tileSet.ImageFileName, err)
 	}
 
-	tileSet.image = ebiten.NewImageFromImage(img)
+	tileSet.source = img
 	tileSet.FirstGid = ref.FirstGid
-	return &tileSet
+	return &tileSet, nil
 }
 
 func (tg *TiledGrid) Draw(camera Camera) {
@@ -164,21 +267,44 @@ func (tg *TiledGrid) Draw(camera Camera) {
 				continue
 			}
 
-			ts := tg.TileSet
-
 			op := &ebiten.DrawImageOptions{}
 			px, py := float64(((i)%layer.Width)*TileSize), float64(((i)/layer.Width)*TileSize)
 			op.GeoM.Translate(px, py)
 			op.GeoM.Scale(Scale, Scale)
 
-			sx := ((tileIndex - ts.FirstGid) % ts.numTilesX) * TileSize
-			sy := ((tileIndex - ts.FirstGid) / ts.numTilesX) * TileSize
-
-			camera.DrawImage(ts.image.SubImage(image.Rect(sx, sy, sx+TileSize, sy+TileSize)).(*ebiten.Image), op)
+			camera.DrawImage(tg.GetTileImage(tileIndex), op)
 		}
 	}
 }
 
+// GetTileImage returns the part of the tileset image for the tile with the global id, as it is
+// stored in the layer data.
+func (tg *TiledGrid) GetTileImage(tileIndex int) *ebiten.Image {
+	ts := tg.TileSet
+	sx := ((tileIndex - ts.FirstGid) % ts.numTilesX) * TileSize
+	sy := ((tileIndex - ts.FirstGid) / ts.numTilesX) * TileSize
+	return ts.getImage().SubImage(image.Rect(sx, sy, sx+TileSize, sy+TileSize)).(*ebiten.Image)
+}
+
+// GetTileSetImage returns the whole tileset image, laid out in rows of tiles.
+func (tg *TiledGrid) GetTileSetImage() *ebiten.Image {
+	return tg.TileSet.getImage()
+}
+
+// getImage makes the tileset image the first time it is drawn, so maps can be loaded without
+// a screen to draw them on.
+func (ts *TileSet) getImage() *ebiten.Image {
+	if ts.image == nil {
+		ts.image = ebiten.NewImageFromImage(ts.source)
+	}
+	return ts.image
+}
+
+// GetTileSetSize is the number of tiles across and down the tileset image.
+func (tg *TiledGrid) GetTileSetSize() (int, int) {
+	return tg.TileSet.numTilesX, tg.TileSet.numTilesY
+}
+
 type ObjectData struct {
 	Name       string
 	ObjectType string
@@ -225,14 +351,34 @@ func (tg *TiledGrid) GetObjectData() []*ObjectData {
 	return ods
 }
 
+// GetProperty returns the value of a custom property set on the map, or nil when it is not set.
+func (tg *TiledGrid) GetProperty(name string) interface{} {
+	for _, prop := range tg.Properties {
+		if prop.Name == name {
+			return prop.Value
+		}
+	}
+	return nil
+}
+
 type TileData struct {
-	Block    bool
-	Platform bool
-	Ladder   bool
-	Damage   bool
+	Block        bool
+	Platform     bool
+	Ladder       bool
+	Damage       bool
+	DamageAmount int
+	Slope        *Slope
+	Water        bool
+	// Friction scales how quickly bodies standing on the tile speed up and slow down, 1 is
+	// normal and ice is less
+	Friction float64
+	// Conveyor is the speed bodies standing on the tile are carried at, negative is to the left
+	Conveyor float64
+	// Bounce is the upward velocity a spring tile launches bodies with
+	Bounce float64
 }
 
-var EmptyTile = &TileData{}
+var EmptyTile = &TileData{Friction: 1}
 
 func (tg *TiledGrid) GetTileData(x int, y int) *TileData {
 
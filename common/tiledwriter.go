@@ -0,0 +1,201 @@
+package common
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// numberArray matches json arrays holding only integers, like the layer data.
+var numberArray = regexp.MustCompile(`\[(\s*-?\d+,)*\s*-?\d+\s*\]`)
+
+// GetTile returns the global tile id at the tile position in the ground layer, 0 is empty.
+func (tg *TiledGrid) GetTile(x, y int) int {
+	if x < 0 || y < 0 || x >= tg.GroundLayer.Width || y >= tg.GroundLayer.Height {
+		return 0
+	}
+	return tg.GroundLayer.Data[(y*tg.GroundLayer.Width)+x]
+}
+
+// SetTile changes the global tile id at the tile position in the ground layer, it returns
+// false when the position is outside the map.
+func (tg *TiledGrid) SetTile(x, y int, tileIndex int) bool {
+	if x < 0 || y < 0 || x >= tg.GroundLayer.Width || y >= tg.GroundLayer.Height {
+		return false
+	}
+	tg.GroundLayer.Data[(y*tg.GroundLayer.Width)+x] = tileIndex
+	return true
+}
+
+// NewObject makes an object with the next free id, it is not in the map until it is inserted.
+func (tg *TiledGrid) NewObject(name string, x, y, w, h int) *TiledObject {
+	if tg.NextObjectId == 0 {
+		tg.NextObjectId = 1
+	}
+	if tg.ObjectLayer != nil {
+		for _, obj := range tg.ObjectLayer.Objects {
+			if obj.Id >= tg.NextObjectId {
+				tg.NextObjectId = obj.Id + 1
+			}
+		}
+	}
+	obj := &TiledObject{
+		Id:      tg.NextObjectId,
+		Name:    name,
+		X:       x,
+		Y:       y,
+		Width:   w,
+		Height:  h,
+		Visible: true,
+	}
+	tg.NextObjectId = tg.NextObjectId + 1
+	return obj
+}
+
+// InsertObject puts the object in the objects layer at the index, the layer is made if the
+// map does not have one.
+func (tg *TiledGrid) InsertObject(index int, obj *TiledObject) {
+	if tg.ObjectLayer == nil {
+		tg.ObjectLayer = &Layer{
+			Name:    objectsLayer,
+			Objects: []*TiledObject{},
+		}
+		tg.Layers = append(tg.Layers, tg.ObjectLayer)
+	}
+	objects := tg.ObjectLayer.Objects
+	if index < 0 || index > len(objects) {
+		index = len(objects)
+	}
+	objects = append(objects, nil)
+	copy(objects[index+1:], objects[index:])
+	objects[index] = obj
+	tg.ObjectLayer.Objects = objects
+}
+
+// RemoveObject takes the object out of the objects layer and returns where it was, or -1.
+func (tg *TiledGrid) RemoveObject(obj *TiledObject) int {
+	if tg.ObjectLayer == nil {
+		return -1
+	}
+	for i, o := range tg.ObjectLayer.Objects {
+		if o == obj {
+			tg.ObjectLayer.Objects = append(tg.ObjectLayer.Objects[:i], tg.ObjectLayer.Objects[i+1:]...)
+			return i
+		}
+	}
+	return -1
+}
+
+// GetObjects returns the objects in the objects layer, drawn last is last.
+func (tg *TiledGrid) GetObjects() []*TiledObject {
+	if tg.ObjectLayer == nil {
+		return []*TiledObject{}
+	}
+	return tg.ObjectLayer.Objects
+}
+
+// Save writes the ground tiles and the objects back over the level file it was loaded from.
+// The rest of the file is kept as it was, so Tiled can still open it.
+func (tg *TiledGrid) Save() error {
+	fileName := filepath.Join(resourceLevelsDirectory, tg.fileName+".json")
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	out, err := tg.updateLevelJson(b)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, out, 0644)
+}
+
+// updateLevelJson puts the ground tiles and objects into the level file's json.
+func (tg *TiledGrid) updateLevelJson(b []byte) ([]byte, error) {
+	level := map[string]interface{}{}
+	if err := json.Unmarshal(b, &level); err != nil {
+		return nil, err
+	}
+	layers, _ := level["layers"].([]interface{})
+	hasObjectLayer := false
+	maxLayerId := 0
+	for _, l := range layers {
+		layer, ok := l.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if id, ok := layer["id"].(float64); ok && int(id) > maxLayerId {
+			maxLayerId = int(id)
+		}
+		switch layer["name"] {
+		case groundLayer:
+			layer["data"] = tg.GroundLayer.Data
+		case objectsLayer:
+			layer["objects"] = mergeObjects(layer["objects"], tg.GetObjects())
+			hasObjectLayer = true
+		}
+	}
+	if !hasObjectLayer && tg.ObjectLayer != nil {
+		layers = append(layers, map[string]interface{}{
+			"draworder": "topdown",
+			"id":        maxLayerId + 1,
+			"name":      objectsLayer,
+			"objects":   tg.GetObjects(),
+			"opacity":   1,
+			"type":      "objectgroup",
+			"visible":   true,
+			"x":         0,
+			"y":         0,
+		})
+		level["layers"] = layers
+		level["nextlayerid"] = maxLayerId + 2
+	}
+	level["nextobjectid"] = tg.NextObjectId
+	out, err := json.MarshalIndent(level, "", " ")
+	if err != nil {
+		return nil, err
+	}
+	// keep the layer data on one line, as Tiled writes it, rather than a line per tile
+	out = numberArray.ReplaceAllFunc(out, func(array []byte) []byte {
+		joined := strings.Join(strings.Fields(string(array)), " ")
+		return []byte("[" + strings.TrimSpace(joined[1:len(joined)-1]) + "]")
+	})
+	return append(out, '\n'), nil
+}
+
+// mergeObjects updates the objects in the file with the ones in the grid, matching them by
+// id. Only what the editor changes is written, so keys like gid, polygon or template that
+// TiledObject does not hold are kept. Objects new to the grid are added and the ones gone
+// from it are left out.
+func mergeObjects(fileObjects interface{}, objects []*TiledObject) []interface{} {
+	existing := map[int]map[string]interface{}{}
+	list, _ := fileObjects.([]interface{})
+	for _, o := range list {
+		if object, ok := o.(map[string]interface{}); ok {
+			if id, ok := object["id"].(float64); ok {
+				existing[int(id)] = object
+			}
+		}
+	}
+	merged := []interface{}{}
+	for _, obj := range objects {
+		object, ok := existing[obj.Id]
+		if !ok {
+			merged = append(merged, obj)
+			continue
+		}
+		object["name"] = obj.Name
+		object["x"] = obj.X
+		object["y"] = obj.Y
+		object["width"] = obj.Width
+		object["height"] = obj.Height
+		if len(obj.Properties) > 0 {
+			object["properties"] = obj.Properties
+		} else {
+			delete(object, "properties")
+		}
+		merged = append(merged, object)
+	}
+	return merged
+}
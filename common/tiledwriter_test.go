@@ -0,0 +1,69 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// testLevelJson has objects with keys that TiledObject does not hold, as Tiled writes them
+// for tile objects, polygons and templates.
+const testLevelJson = `{
+ "layers": [
+  {"data": [0, 0], "height": 1, "id": 1, "name": "ground", "type": "tilelayer", "width": 2},
+  {"id": 2, "name": "objects", "type": "objectgroup", "objects": [
+   {"id": 1, "name": "sign", "x": 0, "y": 0, "width": 16, "height": 16, "gid": 5, "class": "prop"},
+   {"id": 2, "name": "water", "x": 0, "y": 0, "width": 0, "height": 0, "polygon": [{"x": 0, "y": 0}, {"x": 16, "y": 16}]},
+   {"id": 3, "name": "crawler", "x": 32, "y": 0, "width": 16, "height": 16, "template": "crawler.tx"}
+  ]}
+ ],
+ "nextobjectid": 4,
+ "tilesets": [{"firstgid": 1, "source": "tileset.json"}],
+ "type": "map"
+}`
+
+func TestSaveKeepsObjectKeysItDoesNotEdit(t *testing.T) {
+	tg := &TiledGrid{}
+	if err := json.Unmarshal([]byte(testLevelJson), tg); err != nil {
+		t.Fatal(err)
+	}
+	tg.GroundLayer, tg.ObjectLayer = tg.Layers[0], tg.Layers[1]
+	objects := tg.GetObjects()
+	objects[0].X = 48
+	tg.RemoveObject(objects[2])
+	tg.InsertObject(-1, tg.NewObject("spawn", 16, 0, 16, 16))
+	tg.SetTile(1, 0, 7)
+
+	out, err := tg.updateLevelJson([]byte(testLevelJson))
+	if err != nil {
+		t.Fatal(err)
+	}
+	level := struct {
+		Layers []struct {
+			Data    []int                    `json:"data"`
+			Objects []map[string]interface{} `json:"objects"`
+		} `json:"layers"`
+		NextObjectId int `json:"nextobjectid"`
+	}{}
+	if err := json.Unmarshal(out, &level); err != nil {
+		t.Fatal(err)
+	}
+	if data := level.Layers[0].Data; len(data) != 2 || data[1] != 7 {
+		t.Errorf("ground is %v, want the new tile", data)
+	}
+	saved := level.Layers[1].Objects
+	if len(saved) != 3 {
+		t.Fatalf("saved %v objects, want the sign, the water and the new spawn", len(saved))
+	}
+	if saved[0]["x"] != 48.0 || saved[0]["gid"] != 5.0 || saved[0]["class"] != "prop" {
+		t.Errorf("the sign was saved as %v", saved[0])
+	}
+	if _, ok := saved[1]["polygon"]; !ok {
+		t.Errorf("the water lost its polygon, %v", saved[1])
+	}
+	if saved[2]["name"] != "spawn" || saved[2]["id"] != 4.0 {
+		t.Errorf("the new object was saved as %v", saved[2])
+	}
+	if level.NextObjectId != 5 {
+		t.Errorf("next object id is %v, want 5", level.NextObjectId)
+	}
+}
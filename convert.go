@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"platformer/common"
+	"strings"
+)
+
+const convertAseCommand = "convert-ase"
+const resourceDirectory = "res"
+
+// convertAsepriteFiles writes a png and a json sprite sheet next to every .ase file in the
+// directory, files are only rewritten when what they contain has changed. When check is
+// set nothing is written, and it fails if any file is out of date.
+func convertAsepriteFiles(directory string, check bool) error {
+	files, err := filepath.Glob(filepath.Join(directory, "*.ase"))
+	if err != nil {
+		return err
+	}
+	outOfDate := []string{}
+	for _, fileName := range files {
+		changed, err := convertAsepriteFile(fileName, check)
+		if err != nil {
+			return fmt.Errorf("converting %v: %v", fileName, err)
+		}
+		outOfDate = append(outOfDate, changed...)
+	}
+	for _, fileName := range outOfDate {
+		if check {
+			log.Println("out of date", fileName)
+		} else {
+			log.Println("wrote", fileName)
+		}
+	}
+	if check && len(outOfDate) > 0 {
+		return fmt.Errorf("%v files do not match their .ase source, run %v", len(outOfDate), convertAseCommand)
+	}
+	return nil
+}
+
+func convertAsepriteFile(fileName string, check bool) ([]string, error) {
+	aseFile, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	ase, err := common.ReadAseprite(aseFile)
+	aseFile.Close()
+	if err != nil {
+		return nil, err
+	}
+	img, sheet := ase.Composite()
+
+	baseName := strings.TrimSuffix(fileName, ".ase")
+	pngFileName, jsonFileName := baseName+".png", baseName+".json"
+	sheetJson, err := common.EncodeSpriteSheet(sheet, filepath.Base(baseName), filepath.Base(pngFileName), img.Bounds().Dx(), img.Bounds().Dy())
+	if err != nil {
+		return nil, err
+	}
+	changed := []string{}
+	if !pngMatches(pngFileName, img) {
+		changed = append(changed, pngFileName)
+		if !check {
+			var b bytes.Buffer
+			if err := png.Encode(&b, img); err != nil {
+				return nil, err
+			}
+			if err := ioutil.WriteFile(pngFileName, b.Bytes(), 0644); err != nil {
+				return nil, err
+			}
+		}
+	}
+	existingJson, err := ioutil.ReadFile(jsonFileName)
+	if err != nil || !bytes.Equal(existingJson, sheetJson) {
+		changed = append(changed, jsonFileName)
+		if !check {
+			if err := ioutil.WriteFile(jsonFileName, sheetJson, 0644); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return changed, nil
+}
+
+// pngMatches compares pixels rather than bytes, so a png saved by another tool is left alone.
+func pngMatches(fileName string, img *image.NRGBA) bool {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	existing, err := png.Decode(f)
+	if err != nil {
+		return false
+	}
+	bounds := existing.Bounds()
+	if bounds.Dx() != img.Bounds().Dx() || bounds.Dy() != img.Bounds().Dy() {
+		return false
+	}
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			r1, g1, b1, a1 := img.At(x, y).RGBA()
+			r2, g2, b2, a2 := existing.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			if a1 == 0 && a2 == 0 {
+				continue
+			}
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"platformer/common"
+	"testing"
+)
+
+// findAbility returns the pickup in the level that unlocks the ability.
+func findAbility(t *testing.T, game *Game, ability string) *Pickup {
+	t.Helper()
+	for _, p := range game.Level.pickups {
+		if effect, ok := p.effect.(*AbilityEffect); ok && effect.ability == ability {
+			return p
+		}
+	}
+	t.Fatalf("there is no %v pickup in the level", ability)
+	return nil
+}
+
+// collect walks the player onto the pickup.
+func collect(t *testing.T, game *Game, pickup *Pickup) {
+	t.Helper()
+	game.Player.x, game.Player.y = pickup.x, pickup.y-8
+	step(t, game, 2)
+	for _, p := range game.Level.pickups {
+		if p == pickup {
+			t.Fatal("the player did not pick it up")
+		}
+	}
+}
+
+// climbShaft jumps from wall to wall, pressing jump again as it reaches each one, and returns
+// the highest the player got.
+func climbShaft(t *testing.T, game *Game, input *ScriptedInput) float64 {
+	t.Helper()
+	arrows := map[float64]ebiten.Key{-1: ebiten.KeyArrowLeft, 1: ebiten.KeyArrowRight}
+	direction, holding := 1.0, true
+	// catch the script up with the game first, frames added after it ran out are never read
+	input.Hold(input.frame-input.Frames()).Press(arrows[direction], ebiten.KeySpace)
+	top := game.Player.y
+	for i := 0; i < 300; i++ {
+		step(t, game, 1)
+		if game.Player.y < top {
+			top = game.Player.y
+		}
+		keys := []ebiten.Key{arrows[direction]}
+		if game.Player.wallDirection == direction {
+			if !holding {
+				direction = -direction
+				keys = append(keys, ebiten.KeySpace)
+			}
+			holding = !holding
+		} else if holding {
+			keys = append(keys, ebiten.KeySpace)
+		}
+		input.Press(keys...)
+	}
+	return top
+}
+
+func TestWallJumpPickupClimbsShaft(t *testing.T) {
+	game, input := newTestGame("level-gamma")
+	pickup := findAbility(t, game, abilityWallJump)
+	// the shaft is the three tiles before the first wall past the pickup
+	grid := game.Level.tiledGrid
+	tx, ty := int(pickup.x/common.TileSize), int(pickup.y/common.TileSize)
+	for !grid.GetTileData(tx, ty).Block {
+		tx++
+	}
+	ledgeY := float64(ty+1) * common.TileSize
+	for grid.GetTileData(tx, int(ledgeY/common.TileSize)-1).Block {
+		ledgeY -= common.TileSize
+	}
+	inShaft := func() {
+		game.Player.x = float64(tx-2) * common.TileSize
+		game.Player.y = float64(ty) * common.TileSize
+		game.Player.velocityX, game.Player.velocityY = 0, 0
+		step(t, game, 30)
+	}
+
+	inShaft()
+	if top := climbShaft(t, game, input); top+game.Player.sizey <= ledgeY {
+		t.Fatalf("the player got up to %v without wall jumping, the ledge is at %v", top, ledgeY)
+	}
+	collect(t, game, pickup)
+	if !game.PlayerProgress.HasAbility(abilityWallJump) || !game.Player.HasAbility(abilityWallJump) {
+		t.Fatal("the pickup did not unlock wall jumping")
+	}
+	inShaft()
+	if top := climbShaft(t, game, input); top+game.Player.sizey > ledgeY {
+		t.Errorf("the player only got up to %v wall jumping, the ledge is at %v", top, ledgeY)
+	}
+}
+
+func TestDoubleJumpPickupAndCrystal(t *testing.T) {
+	game, input := newTestGame("level-gamma")
+	pickup := findAbility(t, game, abilityDoubleJump)
+	if len(game.Level.crystals) == 0 {
+		t.Fatal("there are no crystals in level-gamma")
+	}
+	// jump, let go and jump again at the top
+	jump := func() float64 {
+		step(t, game, 30)
+		floor := game.Player.y
+		input.Hold(input.frame-input.Frames()).Hold(25, ebiten.KeySpace).Hold(2).Hold(40, ebiten.KeySpace).Hold(60)
+		top := floor
+		for i := 0; i < 127; i++ {
+			step(t, game, 1)
+			if game.Player.y < top {
+				top = game.Player.y
+			}
+		}
+		return floor - top
+	}
+
+	single := jump()
+	collect(t, game, pickup)
+	if !game.Player.HasAbility(abilityDoubleJump) {
+		t.Fatal("the pickup did not unlock double jumping")
+	}
+	if double := jump(); double < single*1.5 || double < 4*common.TileSize {
+		t.Errorf("jumping twice reached %v, want more than one jump of %v and the four tile step", double, single)
+	}
+
+	crystal := game.Level.crystals[0]
+	game.Player.doubleJumpUsed = true
+	game.Player.x, game.Player.y = crystal.x, crystal.y
+	step(t, game, 1)
+	if game.Player.doubleJumpUsed || crystal.timer <= 0 {
+		t.Errorf("touching the crystal did not give back the double jump")
+	}
+}
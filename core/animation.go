@@ -3,34 +3,123 @@ package core
 import (
 	"github.com/hajimehoshi/ebiten/v2"
 	"image"
+	"log"
+	"math"
+	"platformer/common"
 )
 
+// minFrameDuration stops a frame with no duration from holding Update in its loop forever.
+const minFrameDuration = 0.001
+
 type Animation struct {
-	image           *ebiten.Image
-	numFrames       int
-	size            int
-	frameTimeAmount float64
-	isLoop          bool
+	image  *ebiten.Image
+	frames []*animationFrame
+	isLoop bool
+	// the frames are built again from the sheet when it is reloaded
+	sheet     *common.SpriteSheet
+	tag       string
+	frameTime float64
+	version   int
 	// state
 	frame  int
 	timer  float64
 	isDone bool
 }
 
+type animationFrame struct {
+	rect     image.Rectangle
+	duration float64
+}
+
+// newAnimation plays every frame of the image, with the timing from its sprite sheet.
+func newAnimation(game *Game, name string, isLoop bool) *Animation {
+	return newTagAnimation(game, name, "", isLoop)
+}
+
+// newTagAnimation plays only the frames of one tag in the image's sprite sheet, in the
+// direction set on the tag.
+func newTagAnimation(game *Game, name string, tag string, isLoop bool) *Animation {
+	r := &Animation{
+		image:  game.res.GetImage(name),
+		isLoop: isLoop,
+		sheet:  game.res.GetSpriteSheet(name),
+		tag:    tag,
+	}
+	if err := r.buildFrames(); err != nil {
+		log.Fatal("animation ", name, " ", err.Error())
+	}
+	return r
+}
+
+// buildFrames reads the frames from the sprite sheet, keeping the frame the animation is on
+// where it can.
+func (r *Animation) buildFrames() error {
+	r.version = r.sheet.Version
+	sequence, err := r.sheet.GetSequence(r.tag, r.isLoop)
+	if err != nil {
+		return err
+	}
+	frames := []*animationFrame{}
+	for _, f := range sequence {
+		duration := f.Duration
+		if r.frameTime > 0 {
+			duration = r.frameTime
+		}
+		if duration < minFrameDuration {
+			duration = minFrameDuration
+		}
+		frames = append(frames, &animationFrame{
+			rect:     f.Rect,
+			duration: duration,
+		})
+	}
+	r.frames = frames
+	if r.frame >= len(r.frames) {
+		r.frame = len(r.frames) - 1
+	}
+	return nil
+}
+
+// newTaggedAnimations builds an animation for every tag in the image's sprite sheet, keyed by tag name.
+func newTaggedAnimations(game *Game, name string, isLoop bool) map[string]*Animation {
+	animations := map[string]*Animation{}
+	for tag := range game.res.GetSpriteSheet(name).Tags {
+		animations[tag] = newTagAnimation(game, name, tag, isLoop)
+	}
+	return animations
+}
+
+// withFrameTime gives every frame the same duration, for when something reuses another
+// sprite at a different speed.
+func (r *Animation) withFrameTime(frameTime float64) *Animation {
+	r.frameTime = math.Max(frameTime, minFrameDuration)
+	for _, f := range r.frames {
+		f.duration = r.frameTime
+	}
+	return r
+}
+
 func (r *Animation) Update(delta float64) {
-	if r.isDone {
+	if r.sheet != nil && r.version != r.sheet.Version {
+		// the sheet was reloaded, a sheet that no longer fits keeps the old frames
+		if err := r.buildFrames(); err != nil {
+			log.Println("warning: reloading animation", err.Error())
+		}
+	}
+	if r.isDone || len(r.frames) == 0 {
 		return
 	}
 	r.timer = r.timer + delta
-	if r.timer > r.frameTimeAmount {
-		r.timer = r.timer - r.frameTimeAmount
+	for r.timer > r.frames[r.frame].duration {
+		r.timer = r.timer - r.frames[r.frame].duration
 		r.frame = r.frame + 1
-		if r.frame == r.numFrames {
+		if r.frame == len(r.frames) {
 			if r.isLoop {
 				r.frame = 0
 			} else {
-				r.frame = r.numFrames - 1
+				r.frame = len(r.frames) - 1
 				r.isDone = true
+				return
 			}
 		}
 	}
@@ -52,8 +141,20 @@ func (r *Animation) SnapToAnimation(other *Animation) {
 	r.timer = other.timer
 	r.frame = other.frame
 	r.isDone = other.isDone
+	if r.frame >= len(r.frames) {
+		r.frame = len(r.frames) - 1
+	}
+}
+
+// GetDuration is how long it takes to play every frame once.
+func (r *Animation) GetDuration() float64 {
+	total := 0.0
+	for _, f := range r.frames {
+		total = total + f.duration
+	}
+	return total
 }
 
 func (r *Animation) GetCurrentFrame() *ebiten.Image {
-	return r.image.SubImage(image.Rect(r.frame*r.size, 0, (r.frame+1)*r.size, r.size)).(*ebiten.Image)
+	return r.image.SubImage(r.frames[r.frame].rect).(*ebiten.Image)
 }
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"image"
+	"platformer/common"
+	"testing"
+)
+
+// testSheet is a row of 8 pixel frames with the given durations and a ping-pong tag over
+// all of them.
+func testSheet(durations ...float64) *common.SpriteSheet {
+	sheet := &common.SpriteSheet{
+		Tags: map[string]*common.SpriteTag{
+			"bob": {Name: "bob", From: 0, To: len(durations) - 1, Direction: common.DirectionPingPong},
+		},
+	}
+	for i, d := range durations {
+		sheet.Frames = append(sheet.Frames, &common.SpriteFrame{Rect: image.Rect(i*8, 0, (i+1)*8, 8), Duration: d})
+	}
+	return sheet
+}
+
+func TestAnimationPlaysPingPongTag(t *testing.T) {
+	animation := &Animation{sheet: testSheet(0.1, 0.2, 0.1), tag: "bob", isLoop: true}
+	if err := animation.buildFrames(); err != nil {
+		t.Fatal(err)
+	}
+	played := []int{}
+	for i := 0; i < 6; i++ {
+		played = append(played, animation.frames[animation.frame].rect.Min.X/8)
+		// just past the end of the frame
+		animation.Update(animation.frames[animation.frame].duration + 0.001 - animation.timer)
+	}
+	want := []int{0, 1, 2, 1, 0, 1}
+	for i := range want {
+		if played[i] != want[i] {
+			t.Fatalf("played frames %v, want %v", played, want)
+		}
+	}
+
+	once := &Animation{sheet: testSheet(0.1, 0.1, 0.1), tag: "bob"}
+	if err := once.buildFrames(); err != nil {
+		t.Fatal(err)
+	}
+	once.Update(1)
+	if !once.isDone || once.frames[once.frame].rect.Min.X != 0 {
+		t.Errorf("a ping-pong played once should end back on its first frame")
+	}
+}
+
+func TestAnimationWithoutFrameDurationsFinishes(t *testing.T) {
+	animation := &Animation{sheet: testSheet(0, 0), isLoop: true}
+	if err := animation.buildFrames(); err != nil {
+		t.Fatal(err)
+	}
+	// would never return if the frames lasted no time at all
+	animation.Update(0.5)
+	for _, f := range animation.frames {
+		if f.duration < minFrameDuration {
+			t.Errorf("frame lasts %v, want at least %v", f.duration, minFrameDuration)
+		}
+	}
+
+	animation.withFrameTime(0)
+	animation.Update(0.5)
+	if animation.frames[0].duration < minFrameDuration {
+		t.Errorf("a frame time of 0 was kept")
+	}
+}
+
+func TestJsonSpriteSheetMatchesAse(t *testing.T) {
+	game, _ := newTestGame("level-alpha")
+	// the manifest loads spell-bullet from the json export next to its png
+	sheet := game.res.GetSpriteSheet("spell-bullet")
+	ase, err := common.LoadAseprite("spell-bullet.ase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, want := ase.Composite()
+	if len(sheet.Frames) != len(want.Frames) {
+		t.Fatalf("the json has %v frames, the .ase has %v", len(sheet.Frames), len(want.Frames))
+	}
+	for i, f := range sheet.Frames {
+		if f.Rect != want.Frames[i].Rect || f.Duration != want.Frames[i].Duration {
+			t.Errorf("frame %v is %v for %v, the .ase has %v for %v", i, f.Rect, f.Duration, want.Frames[i].Rect, want.Frames[i].Duration)
+		}
+	}
+}
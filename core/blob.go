@@ -2,7 +2,6 @@ package core
 
 import (
 	"github.com/hajimehoshi/ebiten/v2"
-	"image/color"
 	"math"
 	"math/rand"
 	"platformer/common"
@@ -13,10 +12,14 @@ const (
 	thinkStateTarget = "target"
 )
 
+// blobRepathTime is how long a blob follows a path before looking for a new one, in case
+// the way it found has stopped being the best one.
+const blobRepathTime = 1.0
+
 type BlobEnemy struct {
 	x                float64
 	y                float64
-	sizeX            float64
+	def              *EnemyDefinition
 	currentAnimation string
 	animations       map[string]*Animation
 	health           int
@@ -26,73 +29,51 @@ type BlobEnemy struct {
 	targetY          float64
 	moveSpeed        float64
 	hurtTimer        float64
-	hurtAmountTime   float64
 	thinkState       string
 	lastKnownPlayerX float64
+	lastKnownPlayerY float64
 	tryJumpTimer     float64
+	wantsJump        bool
 	velocityY        float64
 	jumpTimer        float64
 	touchingGround   bool
+	standingOn       *common.TileData
+	velocityX        float64
+	knockbackX       float64
+	inWater          bool
+	splashTimer      float64
+	// path is what is left of the way to pathGoal, nil when the goal can't be reached
+	path        []*common.NavLink
+	pathGoal    *common.NavNode
+	repathTimer float64
 }
 
-func NewBlobEnemy(x float64, y float64, game *Game) *BlobEnemy {
+func NewBlobEnemy(x float64, y float64, def *EnemyDefinition, game *Game) *BlobEnemy {
 	return &BlobEnemy{
 		x:                x,
 		y:                y,
-		sizeX:            32,
+		def:              def,
 		currentAnimation: "run",
-		animations: map[string]*Animation{
-			"run": {
-				image:           game.res.GetImage("blob-run"),
-				numFrames:       2,
-				size:            32,
-				frameTimeAmount: 0.2,
-				isLoop:          true,
-			},
-			"idle": {
-				image:           game.res.GetImage("blob-idle"),
-				numFrames:       1,
-				size:            32,
-				frameTimeAmount: 1,
-				isLoop:          true,
-			},
-			"hurt": {
-				image:           game.res.GetImage("blob-hurt"),
-				numFrames:       2,
-				size:            32,
-				frameTimeAmount: 0.1,
-				isLoop:          true,
-			},
-			"attack": {
-				image:           game.res.GetImage("blob-attack"),
-				numFrames:       2,
-				size:            32,
-				frameTimeAmount: 0.5,
-				isLoop:          true,
-			},
-			"jump": {
-				image:           game.res.GetImage("blob-jump"),
-				numFrames:       1,
-				size:            32,
-				frameTimeAmount: 1,
-				isLoop:          true,
-			},
-		},
-		health:         2,
-		directionX:     1,
-		moveSpeed:      80,
-		hurtAmountTime: 0.4,
-		thinkState:     thinkStateIdle,
-		tryJumpTimer:   rand.Float64() * 100,
+		animations:       def.newAnimations(game),
+		health:           def.Health,
+		directionX:       1,
+		moveSpeed:        def.Speed,
+		thinkState:       thinkStateIdle,
+		tryJumpTimer:     rand.Float64() * 100,
+		standingOn:       common.EmptyTile,
 	}
 }
 
 func (r *BlobEnemy) Update(delta float64, game *Game) {
-	cb := r.GetCollisionBox()
-	if common.Overlap(game.Player.x+4, game.Player.y+8, 8, 8, cb.x, cb.y, cb.w, cb.h) {
-		game.Player.TakeDamage(game)
+	cb, pb := r.GetCollisionBox(), game.Player.GetCollisionBox()
+	if common.Overlap(pb.x, pb.y, pb.w, pb.h, cb.x, cb.y, cb.w, cb.h) {
+		game.Player.TakeDamage(r.def.contactDamage(cb), game)
 	}
+	r.repathTimer = r.repathTimer - delta
 	if r.hurtTimer > 0 {
+		// keep falling and sliding back from the hit while hurt
+		r.targetX = r.x
+		r.move(delta, game)
 		r.currentAnimation = "hurt"
 		r.hurtTimer = r.hurtTimer - delta
 	} else {
@@ -102,114 +83,100 @@ func (r *BlobEnemy) Update(delta float64, game *Game) {
 	r.animations[r.currentAnimation].Update(delta)
 }
 
-const blobJumpHeight = 2.0 * common.TileSize
-const blobJumpTime = 0.5
-const timeBetweenJumps = 2.0
-const (
-	blobViewXDistance   = common.TileSize * 8
-	blobViewYDistance   = common.TileSize * 3
-	blobMoveSpeedGround = 20.0
-)
-
 func (r *BlobEnemy) move(delta float64, game *Game) {
-	moveX := 0.0
-
-	gravity := (blobJumpHeight * -2) / (blobJumpTime * blobJumpTime)
+	gravity := (r.def.JumpHeight * -2) / (r.def.JumpTime * r.def.JumpTime)
+	body := r.GetCollisionBox()
+	wasInWater := r.inWater
+	r.inWater = game.Level.IsInWater(body.x+(body.w/2), body.y+(body.h/2))
+	r.splashTimer = r.splashTimer - delta
+	if r.inWater != wasInWater && canSplash(r.velocityY, r.splashTimer) {
+		r.splashTimer = splashCooldown
+		game.SpawnEffect(effectSplash, body.x+(body.w/2), body.y+(body.h/2), false, 0)
+	}
+	if r.inWater {
+		// blobs float, bobbing at the surface as they rise out of the water and fall back in
+		gravity = blobBuoyancy
+	}
 
 	moveY := (r.velocityY * delta) + (0.5 * gravity * delta * delta)
 	r.velocityY = r.velocityY + (gravity * delta)
+	if r.inWater {
+		r.velocityY = math.Min(r.velocityY, blobMaxFloatVelocity)
+	}
 
 	actualSpeed := r.moveSpeed
 	if r.touchingGround {
-		actualSpeed = blobMoveSpeedGround
+		actualSpeed = r.def.GroundSpeed
 	}
-	if math.Abs(r.x-r.targetX) < (r.moveSpeed * delta) {
+	// blobs speed up and slow down with the grip of the floor, on ice they slide past where
+	// they were going and have to come back
+	acc := surfaceAcc(r.def.acceleration(actualSpeed, delta), r.standingOn, r.touchingGround)
+	targetVelocityX := 0.0
+	dx := r.targetX - r.x
+	if math.Abs(dx) < (r.moveSpeed*delta) && math.Abs(r.velocityX) <= acc {
 		r.x = r.targetX
-		r.currentAnimation = "idle"
+		r.velocityX = 0
+	} else if r.velocityX*dx <= 0 || math.Abs(dx) > (r.velocityX*r.velocityX*delta)/(2*acc) {
+		// head for the target until it is time to slow down to stop on it
+		targetVelocityX = math.Copysign(actualSpeed, dx)
 	}
-	if r.x < r.targetX {
-		moveX = actualSpeed * delta
+	r.velocityX = approach(r.velocityX, targetVelocityX, acc)
+	r.currentAnimation = "idle"
+	if r.velocityX != 0 {
 		r.currentAnimation = "run"
 	}
-	if r.x > r.targetX {
-		moveX = -(actualSpeed * delta)
-		r.currentAnimation = "run"
-	}
-
-	// alter movement after checking or collision
-	cb := r.GetCollisionBox()
-	oldX := cb.x
-	newX := cb.x + moveX
-	oldY := cb.y + cb.h
-	newY := cb.y + cb.h - moveY
 
-	// x movement collision
-	tx, ty := int((newX)/common.TileSize), int(oldY/common.TileSize)
-	game.debug.DrawBox(color.RGBA{R: 244, G: 12, B: 9, A: 244}, float64(tx*common.TileSize), float64(ty*common.TileSize), common.TileSize, common.TileSize)
-	td := game.Level.tiledGrid.GetTileData(tx, ty)
-	if td.Block || td.Damage || td.Platform {
-		newX = oldX
-	}
-	tx, ty = int((newX+cb.w)/common.TileSize), int(oldY/common.TileSize)
-	game.debug.DrawBox(color.RGBA{R: 244, G: 12, B: 9, A: 244}, float64(tx*common.TileSize), float64(ty*common.TileSize), common.TileSize, common.TileSize)
-	td = game.Level.tiledGrid.GetTileData(tx, ty)
-	if td.Block || td.Damage || td.Platform {
-		newX = oldX
-	}
+	// conveyors carry blobs along and knockback slides further on slippery floors
+	moveX := surfaceMoveX(r.velocityX+r.knockbackX, r.standingOn, delta)
+	r.knockbackX = reduceKnockback(r.knockbackX, delta*r.standingOn.Friction)
 
-	// y movement collision
-	r.touchingGround = false
-	tx, ty = int((oldX)/common.TileSize), int(newY/common.TileSize)
-	game.debug.DrawBox(color.RGBA{R: 244, G: 12, B: 9, A: 244}, float64(tx*common.TileSize), float64(ty*common.TileSize), common.TileSize, common.TileSize)
-	td = game.Level.tiledGrid.GetTileData(tx, ty)
-	if td.Block || td.Platform {
-		newY = oldY
+	cb := r.GetCollisionBox()
+	cr := DoCollision(cb, moveX, -moveY, game.Level, CollisionOptions{
+		avoidHazards:  true,
+		platformWalls: true,
+		groundSnap:    r.touchingGround && r.velocityY <= 0,
+	})
+	r.touchingGround = cr.hitFloor
+	r.standingOn = cr.floorTile()
+	if cr.hitFloor || cr.hitCeiling {
 		r.velocityY = 0
-		r.touchingGround = true
 	}
-	if td.Damage {
-		r.GetHurt(game)
+	if cr.hitWall {
+		r.velocityX = 0
 	}
-	tx, ty = int((oldX+cb.w)/common.TileSize), int(newY/common.TileSize)
-	game.debug.DrawBox(color.RGBA{R: 244, G: 12, B: 9, A: 244}, float64(tx*common.TileSize), float64(ty*common.TileSize), common.TileSize, common.TileSize)
-	td = game.Level.tiledGrid.GetTileData(tx, ty)
-	if td.Block || td.Platform {
-		newY = oldY
-		r.velocityY = 0
-		r.touchingGround = true
-	}
-	if td.Damage {
-		r.GetHurt(game)
+	if bounce, bounced := surfaceBounce(cr, r.velocityY); bounced {
+		r.velocityY = bounce
+		r.touchingGround = false
 	}
-	tx, ty = int((oldX+8)/common.TileSize), int((newY-cb.h)/common.TileSize)
-	game.debug.DrawBox(color.RGBA{R: 244, G: 12, B: 9, A: 244}, float64(tx*common.TileSize), float64(ty*common.TileSize), common.TileSize, common.TileSize)
-	td = game.Level.tiledGrid.GetTileData(tx, ty)
-	if td.Block || td.Damage || td.Platform {
-		newY = oldY
-		r.velocityY = 0
-		r.touchingGround = true
+	r.x = cr.newX - r.def.Hitbox.X
+	r.y = cr.newY - r.def.Hitbox.Y
+	if cr.damageTile != nil {
+		r.GetHurt(game, newTileDamage(cr.damageTile.DamageAmount, cr.damageTileX, cr.damageTileY))
 	}
 
 	// jumping
 	r.tryJumpTimer = r.tryJumpTimer + delta
-	if r.touchingGround && r.tryJumpTimer > timeBetweenJumps && r.thinkState == thinkStateTarget {
+	if r.touchingGround && r.wantsJump && r.tryJumpTimer > r.def.TimeBetweenJumps && r.thinkState == thinkStateTarget {
 		r.tryJumpTimer = 0
-		r.velocityY = (2 * blobJumpHeight) / blobJumpTime
+		r.wantsJump = false
+		r.velocityY = (2 * r.def.JumpHeight) / r.def.JumpTime
 	}
 	if r.velocityY > 0 {
 		r.currentAnimation = "jump"
 	}
-
-	r.x = newX - 8
-	r.y = newY - 8 - cb.h
 }
 
 func (r *BlobEnemy) think(game *Game) {
 	canSeePlayer := false
-	if r.x < game.Player.x+blobViewXDistance && r.x > game.Player.x-blobViewXDistance {
-		if r.y < game.Player.y+blobViewYDistance && r.y > game.Player.y-blobViewYDistance {
+	if r.x < game.Player.x+r.def.ViewDistanceX && r.x > game.Player.x-r.def.ViewDistanceX {
+		cb := r.GetCollisionBox()
+		eyeX, eyeY := cb.x+(cb.w/2), cb.y+4
+		playerX, playerY := game.Player.x+(game.Player.sizex/2), game.Player.y+(game.Player.sizey/2)
+		inRange := r.y < game.Player.y+r.def.ViewDistanceY && r.y > game.Player.y-r.def.ViewDistanceY
+		if inRange && game.HasLineOfSight(eyeX, eyeY, playerX, playerY) {
 			canSeePlayer = true
-			r.lastKnownPlayerX = game.Player.x
+			r.lastKnownPlayerX = game.Player.x + (game.Player.sizex / 2)
+			r.lastKnownPlayerY = game.Player.y + game.Player.sizey
 		}
 	}
 	atTarget := false
@@ -225,7 +192,7 @@ func (r *BlobEnemy) think(game *Game) {
 		}
 	case thinkStateTarget:
 		if r.touchingGround {
-			r.targetX = r.lastKnownPlayerX
+			r.followPath(game)
 		}
 		if !canSeePlayer && atTarget {
 			r.thinkState = thinkStateIdle
@@ -233,33 +200,113 @@ func (r *BlobEnemy) think(game *Game) {
 	}
 }
 
+// followPath picks the next step towards where the player was last seen, if the player
+// can't be reached the blob stays on the surface it is standing on. The path is only
+// searched for again when the player moves to another node, the blob gets off it or the
+// repath timer runs out.
+func (r *BlobEnemy) followPath(game *Game) {
+	graph := game.Level.GetNavGraph(r.def.navProfile())
+	cb := r.GetCollisionBox()
+	from := graph.FindNode(cb.x+(cb.w/2), cb.y+cb.h)
+	to := graph.FindNode(r.lastKnownPlayerX, r.lastKnownPlayerY)
+	r.wantsJump = false
+	for len(r.path) > 0 && r.path[0].To == from {
+		r.path = r.path[1:]
+	}
+	if r.needsPath(from, to) {
+		r.path = graph.FindPath(from, to)
+		r.pathGoal = to
+		r.repathTimer = blobRepathTime
+	}
+	if r.path == nil {
+		r.targetX = r.lastKnownPlayerX - r.def.Hitbox.X - (cb.w / 2)
+		if from != nil {
+			left, right := graph.GetSurfaceExtent(from)
+			r.targetX = math.Max(r.targetX, r.tileToX(left.X))
+			r.targetX = math.Min(r.targetX, r.tileToX(right.X))
+		}
+		return
+	}
+	if len(r.path) == 0 {
+		r.targetX = r.tileToX(from.X)
+		return
+	}
+	next := r.path[0]
+	r.targetX = r.tileToX(next.To.X)
+	r.wantsJump = next.Kind == common.NavJump
+}
+
+// needsPath is true when the cached path no longer leads from the blob to the goal.
+func (r *BlobEnemy) needsPath(from, to *common.NavNode) bool {
+	if r.repathTimer <= 0 || to != r.pathGoal {
+		return true
+	}
+	if len(r.path) > 0 {
+		return r.path[0].From != from
+	}
+	// arrived, or the goal couldn't be reached and the timer decides when to try again
+	return r.path != nil && from != to
+}
+
+// tileToX returns the x position that puts the middle of the blob over the middle of the tile.
+func (r *BlobEnemy) tileToX(tx int) float64 {
+	return (float64(tx) * common.TileSize) + (common.TileSize / 2) - r.def.Hitbox.X - (r.def.Hitbox.W / 2)
+}
+
 func (r *BlobEnemy) Draw(camera common.Camera) {
 
 	op := &ebiten.DrawImageOptions{}
 	if r.directionX > 0 {
 		op.GeoM.Scale(-1, 1)
-		op.GeoM.Translate(r.sizeX, 0)
+		op.GeoM.Translate(r.def.DrawSize, 0)
 	}
-	op.GeoM.Translate(r.x, r.y)
+	op.GeoM.Translate(r.x+r.def.DrawOffsetX, r.y+r.def.DrawOffsetY)
 	op.GeoM.Scale(common.Scale, common.Scale)
+	r.def.applyTint(op)
 	camera.DrawImage(r.animations[r.currentAnimation].GetCurrentFrame(), op)
 }
 
-func (r *BlobEnemy) GetHurt(game *Game) {
-	r.health = r.health - 1
-	r.hurtTimer = r.hurtAmountTime
+func (r *BlobEnemy) GetHurt(game *Game, damage DamageInfo) {
+	if r.health <= 0 {
+		return
+	}
+	r.health = r.health - damage.Amount
+	r.hurtTimer = r.def.HurtTime
+	cb := r.GetCollisionBox()
+	dirX, _ := damage.directionFrom(cb.x+(cb.w/2), cb.y+(cb.h/2))
+	r.knockbackX = dirX * damage.Knockback
 	r.animations["hurt"].Play()
-	if r.health == 0 {
-		game.SpawnEffect(effectBlobDeath, r.x, r.y, r.directionX > 0, 0)
+	game.Feedback(hurtFeedback(damage))
+	if r.def.HurtEffect != "" {
+		game.SpawnEffect(r.def.HurtEffect, r.x, r.y, r.directionX > 0, 0)
+	}
+	if r.health <= 0 {
+		game.SpawnEffect(r.def.DeathEffect, r.x, r.y, r.directionX > 0, 0)
 		game.Level.RemoveEnemy(r)
 	}
 }
 
+func (r *BlobEnemy) describeDebug(game *Game) debugInfo {
+	info := debugInfo{
+		state:     r.thinkState,
+		velocityX: r.velocityX + r.knockbackX,
+		velocityY: -r.velocityY,
+	}
+	if r.hurtTimer > 0 {
+		info.state = "hurt"
+	}
+	if r.thinkState == thinkStateTarget {
+		info.hasTarget = true
+		info.targetX, info.targetY = r.lastKnownPlayerX, r.lastKnownPlayerY
+	}
+	return info
+}
+
 func (r *BlobEnemy) GetCollisionBox() CollisionBox {
 	return CollisionBox{
-		x: r.x + 8,
-		y: r.y + 8,
-		w: 16,
-		h: 24,
+		x: r.x + r.def.Hitbox.X,
+		y: r.y + r.def.Hitbox.Y,
+		w: r.def.Hitbox.W,
+		h: r.def.Hitbox.H,
 	}
 }
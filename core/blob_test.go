@@ -0,0 +1,67 @@
+package core
+
+import (
+	"platformer/common"
+	"testing"
+)
+
+// countSearches steps the game and counts the updates where the blob looked for a new path.
+func countSearches(t *testing.T, game *Game, blob *BlobEnemy, updates int) int {
+	t.Helper()
+	searches := 0
+	for i := 0; i < updates; i++ {
+		step(t, game, 1)
+		// the timer counts down before the blob thinks, so it is only full after a search
+		if blob.repathTimer == blobRepathTime {
+			searches++
+		}
+	}
+	return searches
+}
+
+func TestBlobKeepsPathUntilPlayerMoves(t *testing.T) {
+	game, _ := newTestGame("level-alpha")
+	step(t, game, 30)
+	blob := NewBlobEnemy(game.Player.x+96, game.Player.y-16, game.enemyDefinitions["blob"], game)
+	game.Level.AddEnemy(blob)
+	step(t, game, 30)
+	if blob.thinkState != thinkStateTarget || blob.path == nil {
+		t.Fatalf("the blob is %v and has no path to the player", blob.thinkState)
+	}
+	startX := blob.x
+
+	if searches := countSearches(t, game, blob, 30); searches != 0 {
+		t.Errorf("searched %v times while the player stood still, want 0", searches)
+	}
+	if blob.x >= startX {
+		t.Errorf("the blob did not move towards the player, it is at %v from %v", blob.x, startX)
+	}
+
+	game.Player.x = game.Player.x + 32
+	if searches := countSearches(t, game, blob, 2); searches != 1 {
+		t.Errorf("searched %v times after the player moved, want 1", searches)
+	}
+}
+
+func TestNavGraphsAreBuiltWithTheLevel(t *testing.T) {
+	for _, name := range []string{"level-gamma", "level-epsilon"} {
+		game, _ := newTestGame(name)
+		profiles := map[common.NavProfile]bool{}
+		for _, e := range game.Level.enemies {
+			if blob, ok := e.(*BlobEnemy); ok {
+				profiles[blob.def.navProfile()] = true
+			}
+		}
+		if arena := game.Level.arena; arena != nil {
+			profiles[game.enemyDefinitions[arena.boss.minion].navProfile()] = true
+		}
+		if len(profiles) == 0 {
+			t.Fatalf("there are no blobs in %v", name)
+		}
+		for profile := range profiles {
+			if _, ok := game.Level.navGraphs[profile]; !ok {
+				t.Errorf("%v has no nav graph for %+v until a blob asks for it", name, profile)
+			}
+		}
+	}
+}
@@ -0,0 +1,487 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"log"
+	"platformer/common"
+)
+
+const (
+	bossStateIdle   = "idle"
+	bossStateWalk   = "walk"
+	bossStateWindup = "windup"
+	bossStateCharge = "charge"
+	bossStateSlam   = "slam"
+	bossStateSummon = "summon"
+)
+
+const (
+	bossAttackCharge = "charge"
+	bossAttackSlam   = "slam"
+	bossAttackSummon = "summon"
+)
+
+const (
+	bossMusic            = "boss-music"
+	bossDrawSize         = 64
+	bossHurtTime         = 0.3
+	bossWindupTime       = 0.6
+	bossChargeTime       = 1.4
+	bossChargeMultiplier = 3.0
+	bossSlamHeight       = common.TileSize * 4
+	bossSlamTime         = 0.6
+	bossSlamRange        = common.TileSize * 3
+	bossSummonTime       = 0.8
+	bossMaxMinions       = 3
+	bossMinion           = "blob"
+	bossKnockback        = 160.0
+)
+
+// bossPhase is active while the boss health percent is at or below healthThreshold,
+// phases are ordered from the highest threshold to the lowest.
+type bossPhase struct {
+	healthThreshold    float64
+	attacks            []string
+	moveSpeed          float64
+	timeBetweenAttacks float64
+}
+
+var blobKingPhases = []*bossPhase{
+	{
+		healthThreshold:    1.0,
+		attacks:            []string{bossAttackCharge, bossAttackSlam},
+		moveSpeed:          30,
+		timeBetweenAttacks: 2.0,
+	},
+	{
+		healthThreshold:    0.6,
+		attacks:            []string{bossAttackSlam, bossAttackSummon, bossAttackCharge},
+		moveSpeed:          45,
+		timeBetweenAttacks: 1.4,
+	},
+	{
+		healthThreshold:    0.3,
+		attacks:            []string{bossAttackSlam, bossAttackCharge, bossAttackSlam, bossAttackSummon},
+		moveSpeed:          60,
+		timeBetweenAttacks: 0.8,
+	},
+}
+
+type BossEnemy struct {
+	x                float64
+	y                float64
+	spawnX           float64
+	spawnY           float64
+	name             string
+	currentAnimation string
+	animations       map[string]*Animation
+	health           int
+	maxHealth        int
+	// ai
+	phases         []*bossPhase
+	phaseIndex     int
+	attackIndex    int
+	attackTimer    float64
+	state          string
+	stateTimer     float64
+	directionX     int
+	velocityX      float64
+	velocityY      float64
+	hurtTimer      float64
+	touchingGround bool
+	minions        []Enemy
+	// minion names the enemy definition the boss summons
+	minion string
+	arena  *BossArena
+}
+
+func NewBossEnemy(x float64, y float64, name string, health int, minion string, game *Game) *BossEnemy {
+	return &BossEnemy{
+		x:                x,
+		y:                y,
+		spawnX:           x,
+		spawnY:           y,
+		name:             name,
+		currentAnimation: "idle",
+		animations: map[string]*Animation{
+			"run":    newAnimation(game, "blob-run", true).withFrameTime(0.3),
+			"idle":   newAnimation(game, "blob-idle", true),
+			"hurt":   newAnimation(game, "blob-hurt", true),
+			"attack": newAnimation(game, "blob-attack", true).withFrameTime(0.15),
+			"jump":   newAnimation(game, "blob-jump", true),
+		},
+		health:     health,
+		maxHealth:  health,
+		phases:     blobKingPhases,
+		state:      bossStateIdle,
+		directionX: -1,
+		minion:     minion,
+	}
+}
+
+func (r *BossEnemy) Update(delta float64, game *Game) {
+	cb, pb := r.GetCollisionBox(), game.Player.GetCollisionBox()
+	if common.Overlap(pb.x, pb.y, pb.w, pb.h, cb.x, cb.y, cb.w, cb.h) {
+		game.Player.TakeDamage(DamageInfo{
+			Amount:    1,
+			Type:      damageTypeContact,
+			SourceX:   cb.x + (cb.w / 2),
+			SourceY:   cb.y + (cb.h / 2),
+			Knockback: bossKnockback,
+		}, game)
+	}
+	if r.state != bossStateIdle {
+		r.think(delta, game)
+	}
+	r.move(delta, game)
+	if r.hurtTimer > 0 {
+		r.hurtTimer = r.hurtTimer - delta
+		r.currentAnimation = "hurt"
+	}
+	r.animations[r.currentAnimation].Update(delta)
+}
+
+func (r *BossEnemy) think(delta float64, game *Game) {
+	phase := r.phases[r.phaseIndex]
+	cb := r.GetCollisionBox()
+	playerCenterX := game.Player.x + (game.Player.sizex / 2)
+	bossCenterX := cb.x + (cb.w / 2)
+
+	r.stateTimer = r.stateTimer - delta
+	switch r.state {
+	case bossStateWalk:
+		r.currentAnimation = "run"
+		r.directionX = directionTo(bossCenterX, playerCenterX)
+		r.velocityX = float64(r.directionX) * phase.moveSpeed
+		r.attackTimer = r.attackTimer - delta
+		if r.attackTimer < 0 && r.touchingGround {
+			r.startAttack(phase.attacks[r.attackIndex%len(phase.attacks)], game)
+			r.attackIndex = r.attackIndex + 1
+		}
+	case bossStateWindup:
+		r.currentAnimation = "attack"
+		r.velocityX = 0
+		if r.stateTimer < 0 {
+			r.state = bossStateCharge
+			r.stateTimer = bossChargeTime
+			r.velocityX = float64(r.directionX) * phase.moveSpeed * bossChargeMultiplier
+		}
+	case bossStateCharge:
+		r.currentAnimation = "run"
+		if r.stateTimer < 0 || r.velocityX == 0 {
+			r.endAttack()
+		}
+	case bossStateSlam:
+		r.currentAnimation = "jump"
+		if r.touchingGround && r.velocityY <= 0 && r.stateTimer < 0 {
+			r.landSlam(game)
+			r.endAttack()
+		}
+	case bossStateSummon:
+		r.currentAnimation = "attack"
+		r.velocityX = 0
+		if r.stateTimer < 0 {
+			r.summon(game)
+			r.endAttack()
+		}
+	}
+}
+
+func (r *BossEnemy) startAttack(attack string, game *Game) {
+	switch attack {
+	case bossAttackCharge:
+		r.state = bossStateWindup
+		r.stateTimer = bossWindupTime
+		r.animations["attack"].Reset()
+	case bossAttackSlam:
+		cb := r.GetCollisionBox()
+		distance := (game.Player.x + (game.Player.sizex / 2)) - (cb.x + (cb.w / 2))
+		r.state = bossStateSlam
+		r.stateTimer = bossSlamTime
+		r.velocityY = (2 * bossSlamHeight) / bossSlamTime
+		r.velocityX = distance / (bossSlamTime * 2)
+		r.touchingGround = false
+	case bossAttackSummon:
+		r.state = bossStateSummon
+		r.stateTimer = bossSummonTime
+	}
+}
+
+func (r *BossEnemy) endAttack() {
+	r.state = bossStateWalk
+	r.velocityX = 0
+	r.attackTimer = r.phases[r.phaseIndex].timeBetweenAttacks
+}
+
+func (r *BossEnemy) landSlam(game *Game) {
+	cb, pb := r.GetCollisionBox(), game.Player.GetCollisionBox()
+	feetY := cb.y + cb.h
+	game.SpawnEffect(effectSpellHit, cb.x-8, feetY-16, false, 0)
+	game.SpawnEffect(effectSpellHit, cb.x+cb.w-8, feetY-16, true, 0)
+	game.Feedback(feedbackSlam)
+	if common.Overlap(pb.x, pb.y, pb.w, pb.h, cb.x-bossSlamRange, feetY-common.TileSize, cb.w+(bossSlamRange*2), common.TileSize) {
+		game.Player.TakeDamage(DamageInfo{
+			Amount:    1,
+			Type:      damageTypeSlam,
+			SourceX:   cb.x + (cb.w / 2),
+			SourceY:   feetY,
+			Knockback: bossKnockback,
+		}, game)
+	}
+}
+
+func (r *BossEnemy) summon(game *Game) {
+	liveMinions := []Enemy{}
+	for _, m := range r.minions {
+		for _, e := range game.Level.enemies {
+			if e == m {
+				liveMinions = append(liveMinions, m)
+			}
+		}
+	}
+	r.minions = liveMinions
+	// definitions are swapped out on reload, so look the minion up each time
+	def, ok := game.enemyDefinitions[r.minion]
+	if !ok {
+		log.Println("no enemy definition called", r.minion, "for", r.name, "to summon")
+		return
+	}
+	cb := r.GetCollisionBox()
+	for _, offset := range []float64{-common.TileSize * 2, cb.w + common.TileSize} {
+		if len(r.minions) >= bossMaxMinions {
+			return
+		}
+		minion := NewEnemy(cb.x+offset-8, cb.y+cb.h-32-fudge, def, game)
+		game.Level.AddEnemy(minion)
+		r.minions = append(r.minions, minion)
+	}
+}
+
+func (r *BossEnemy) move(delta float64, game *Game) {
+	gravity := (bossSlamHeight * -2) / (bossSlamTime * bossSlamTime)
+	moveY := (r.velocityY * delta) + (0.5 * gravity * delta * delta)
+	r.velocityY = r.velocityY + (gravity * delta)
+
+	cb := r.GetCollisionBox()
+	cr := DoCollision(cb, r.velocityX*delta, -moveY, game.Level, CollisionOptions{
+		groundSnap: r.touchingGround && r.velocityY <= 0,
+	})
+
+	r.touchingGround = cr.hitFloor
+	if cr.hitFloor || cr.hitCeiling {
+		r.velocityY = 0
+	}
+	if cr.hitWall {
+		r.velocityX = 0
+	}
+	r.x = cr.newX - (cb.x - r.x)
+	r.y = cr.newY - (cb.y - r.y)
+}
+
+func (r *BossEnemy) Draw(camera common.Camera) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(2, 2)
+	if r.directionX > 0 {
+		op.GeoM.Scale(-1, 1)
+		op.GeoM.Translate(bossDrawSize, 0)
+	}
+	op.GeoM.Translate(r.x, r.y)
+	op.GeoM.Scale(common.Scale, common.Scale)
+	op.ColorM.Scale(1, 0.6, 0.6, 1)
+	camera.DrawImage(r.animations[r.currentAnimation].GetCurrentFrame(), op)
+}
+
+// GetHurt ignores knockback, the boss is too heavy to push around.
+func (r *BossEnemy) GetHurt(game *Game, damage DamageInfo) {
+	if r.state == bossStateIdle || r.health <= 0 {
+		return
+	}
+	r.health = r.health - damage.Amount
+	r.hurtTimer = bossHurtTime
+	r.animations["hurt"].Play()
+	game.Feedback(hurtFeedback(damage))
+	if r.health <= 0 {
+		game.Feedback(feedbackBossDeath)
+		game.SpawnEffect(effectBlobDeath, r.x+16, r.y+32, r.directionX > 0, 0)
+		game.Level.RemoveEnemy(r)
+		r.arena.Defeat(game)
+		return
+	}
+	healthPercent := r.GetHealthPercent()
+	for i, phase := range r.phases {
+		if healthPercent <= phase.healthThreshold && i > r.phaseIndex {
+			r.phaseIndex = i
+			r.attackIndex = 0
+		}
+	}
+}
+
+func (r *BossEnemy) describeDebug(game *Game) debugInfo {
+	info := debugInfo{
+		state:     r.state,
+		velocityX: r.velocityX,
+		velocityY: -r.velocityY,
+	}
+	if r.state != bossStateIdle {
+		info.hasTarget = true
+		info.targetX, info.targetY = game.Player.x+(game.Player.sizex/2), game.Player.y+(game.Player.sizey/2)
+	}
+	return info
+}
+
+func (r *BossEnemy) GetCollisionBox() CollisionBox {
+	return CollisionBox{
+		x: r.x + 16,
+		y: r.y + 16,
+		w: 32,
+		h: 48,
+	}
+}
+
+func (r *BossEnemy) GetName() string {
+	return r.name
+}
+
+func (r *BossEnemy) GetHealthPercent() float64 {
+	return float64(r.health) / float64(r.maxHealth)
+}
+
+func (r *BossEnemy) Wake() {
+	r.state = bossStateWalk
+	r.attackTimer = r.phases[r.phaseIndex].timeBetweenAttacks
+}
+
+func (r *BossEnemy) Reset() {
+	r.x = r.spawnX
+	r.y = r.spawnY
+	r.health = r.maxHealth
+	r.phaseIndex = 0
+	r.attackIndex = 0
+	r.state = bossStateIdle
+	r.velocityX = 0
+	r.velocityY = 0
+	r.currentAnimation = "idle"
+}
+
+func directionTo(from float64, to float64) int {
+	if to < from {
+		return -1
+	}
+	return 1
+}
+
+// BossArena locks the player in with the boss once they walk into the trigger,
+// and unlocks again when the boss is defeated.
+type BossArena struct {
+	id          string
+	trigger     CollisionBox
+	barriers    []*Barrier
+	boss        *BossEnemy
+	music       string
+	rewardSpell string
+	rewardTitle string
+	isLocked    bool
+}
+
+func (r *BossArena) Update(delta float64, game *Game) {
+	if r.isLocked {
+		return
+	}
+	pb := game.Player.GetCollisionBox()
+	if common.Overlap(pb.x, pb.y, pb.w, pb.h, r.trigger.x, r.trigger.y, r.trigger.w, r.trigger.h) {
+		r.isLocked = true
+		r.boss.Wake()
+		game.sounds.PlaySound(r.music)
+	}
+}
+
+func (r *BossArena) Draw(camera common.Camera) {
+	if !r.isLocked {
+		return
+	}
+	for _, b := range r.barriers {
+		b.Draw(camera)
+	}
+}
+
+func (r *BossArena) Defeat(game *Game) {
+	r.isLocked = false
+	game.sounds.StopSound(r.music)
+	game.PlayerProgress.DefeatBoss(r.id)
+	cb := r.boss.GetCollisionBox()
+	x, y := cb.x+(cb.w/2)-8, cb.y+cb.h-common.TileSize
+	if r.rewardSpell != "" {
+		game.Level.AddPickup(&Pickup{
+			x:     x,
+			y:     y,
+			image: game.res.GetImage("book-pickup"),
+			effect: &BookEffect{
+				title: r.rewardTitle,
+				spell: r.rewardSpell,
+			},
+		})
+	} else {
+		game.Level.AddPickup(&Pickup{
+			x:     x,
+			y:     y,
+			image: game.res.GetImage("health-pickup"),
+			effect: &HealthEffect{
+				amount: 1,
+			},
+		})
+	}
+	game.Level.arena = nil
+}
+
+// Reset puts the fight back to the state it was in before the player triggered it.
+func (r *BossArena) Reset(game *Game) {
+	if r.isLocked {
+		game.sounds.StopSound(r.music)
+	}
+	r.isLocked = false
+	r.boss.Reset()
+	for _, m := range r.boss.minions {
+		game.Level.RemoveEnemy(m)
+	}
+	r.boss.minions = []Enemy{}
+}
+
+func (r *BossArena) GetColliders() []Collider {
+	var colliders = []Collider{}
+	if !r.isLocked {
+		return colliders
+	}
+	for _, b := range r.barriers {
+		colliders = append(colliders, b)
+	}
+	return colliders
+}
+
+type Barrier struct {
+	x     float64
+	y     float64
+	w     float64
+	h     float64
+	image *ebiten.Image
+}
+
+func (r *Barrier) GetCollisionBox() CollisionBox {
+	return CollisionBox{
+		x: r.x,
+		y: r.y,
+		w: r.w,
+		h: r.h,
+	}
+}
+
+func (r *Barrier) Draw(camera common.Camera) {
+	for y := 0.0; y < r.h; y = y + common.TileSize {
+		for x := 0.0; x < r.w; x = x + common.TileSize {
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Translate(r.x+x, r.y+y)
+			op.GeoM.Scale(common.Scale, common.Scale)
+			op.ColorM.Scale(1, 0.5, 0.5, 1)
+			camera.DrawImage(r.image, op)
+		}
+	}
+}
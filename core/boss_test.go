@@ -0,0 +1,87 @@
+package core
+
+import (
+	"testing"
+)
+
+// enterBossArena puts the player in the middle of level-epsilon's arena and lets it lock.
+func enterBossArena(t *testing.T) *Game {
+	t.Helper()
+	game, _ := newTestGame("level-epsilon")
+	arena := game.Level.arena
+	if arena == nil {
+		t.Fatal("level-epsilon has no boss arena")
+	}
+	game.Player.x = arena.trigger.x + 16
+	game.Player.y = arena.trigger.y + arena.trigger.h - game.Player.sizey
+	step(t, game, 1)
+	if !arena.isLocked {
+		t.Fatal("walking into the trigger did not start the fight")
+	}
+	return game
+}
+
+func TestBossArenaLoadsFromLevel(t *testing.T) {
+	game, _ := newTestGame("level-epsilon")
+	arena := game.Level.arena
+	if arena == nil {
+		t.Fatal("level-epsilon has no boss arena")
+	}
+	if len(arena.barriers) != 2 {
+		t.Errorf("there are %v barriers, want 2", len(arena.barriers))
+	}
+	if !game.sounds.HasSound(arena.music) {
+		t.Errorf("boss music %v is not a sound", arena.music)
+	}
+	found := false
+	for _, e := range game.Level.enemies {
+		if e == Enemy(arena.boss) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("the boss is not one of the level's enemies")
+	}
+}
+
+func TestBarrierStopsProjectiles(t *testing.T) {
+	game := enterBossArena(t)
+	barrier := game.Level.arena.barriers[0].GetCollisionBox()
+	bullet := NewSpellBullet(game, barrier.x+barrier.w+8, barrier.y+barrier.h-24, -spellBulletSpeed, 0)
+	game.AddProjectile(bullet)
+
+	step(t, game, 30)
+	for _, p := range game.projectiles {
+		if p == bullet {
+			t.Fatalf("the bullet is still going at %v, the barrier is at %v", bullet.x, barrier.x)
+		}
+	}
+	if bullet.x < barrier.x-bullet.w {
+		t.Errorf("the bullet got through to %v", bullet.x)
+	}
+}
+
+func TestBossResetRemovesMinions(t *testing.T) {
+	game := enterBossArena(t)
+	arena := game.Level.arena
+	enemies := len(game.Level.enemies)
+	arena.boss.summon(game)
+	if len(game.Level.enemies) == enemies {
+		t.Fatal("the boss did not summon any minions")
+	}
+
+	arena.Reset(game)
+	if len(game.Level.enemies) != enemies || len(arena.boss.minions) != 0 {
+		t.Errorf("there are %v enemies after the reset, want %v", len(game.Level.enemies), enemies)
+	}
+}
+
+func TestBossSkipsSummonWithoutMinionDefinition(t *testing.T) {
+	game := enterBossArena(t)
+	enemies := len(game.Level.enemies)
+	delete(game.enemyDefinitions, game.Level.arena.boss.minion)
+	game.Level.arena.boss.summon(game)
+	if len(game.Level.enemies) != enemies {
+		t.Errorf("the boss summoned %v minions with no definition for them", len(game.Level.enemies)-enemies)
+	}
+}
@@ -1,7 +1,9 @@
 package core
 
 import (
+	"fmt"
 	"github.com/hajimehoshi/ebiten/v2"
+	"math"
 	"platformer/common"
 )
 
@@ -10,53 +12,239 @@ type Camera struct {
 	y      float64
 	buffer *ebiten.Image
 	target CameraTarget
+	// centreX and centreY are where the camera is looking before it is kept inside the room
+	centreX float64
+	centreY float64
+	// focusX and focusY are the point the camera is moving to centre on
+	focusX     float64
+	focusY     float64
+	lookAheadX float64
+	// groundY is where the target last landed, the camera stays level with it while they jump
+	groundY       float64
+	lookTimer     float64
+	lookDirection float64
+	// snap jumps straight to the target on the next update instead of moving there
+	snap bool
+	// trauma is how much the camera is shaking, from 0 to 1
+	trauma    float64
+	shakeTime float64
+	shakeX    float64
+	shakeY    float64
+	// room is the camera room the target is in, transitions move the camera from where it
+	// was when the target crossed into it
+	room            *cameraRoom
+	transitionFromX float64
+	transitionFromY float64
+	transitionTime  float64
+	transitionTimer float64
+	scrolling       bool
 }
 
 type CameraTarget interface {
 	GetPos() (float64, float64)
 }
 
+// cameraFollower is a target that tells the camera how it is moving, so the camera can look
+// ahead of it and hold still while it jumps.
+type cameraFollower interface {
+	cameraFollow() cameraFollow
+}
+
+// cameraFollow is how a target is moving. facing is 1 for right and -1 for left, settled is
+// whether it is somewhere the camera should recentre on, like the floor, and look is -1 or 1
+// while it is asking to look up or down.
+type cameraFollow struct {
+	facing    float64
+	velocityX float64
+	settled   bool
+	look      float64
+}
+
 func NewCamera() *Camera {
 	return &Camera{
-		buffer: ebiten.NewImage(common.ScreenWidth*common.Scale, common.ScreenHeight*common.Scale),
+		snap: true,
 	}
 }
 
 func (c *Camera) Update(delta float64, game *Game) {
-	c.buffer.Clear()
 	if c.target != nil {
-		tx, ty := c.target.GetPos()
-		c.x = tx - (common.ScreenWidth / 2) + (common.TileSize / 2)
-		c.y = ty - (common.ScreenHeight / 2) + (common.TileSize / 2)
+		c.enterRoom(game.Level)
+		c.follow(delta, game.Level.camera)
+		c.x, c.y = c.centreX-(common.ScreenWidth/2), c.centreY-(common.ScreenHeight/2)
 	}
-	if c.x < 0 {
-		c.x = 0
+	bounds := game.Level.cameraBounds(c.room)
+	x := clampToBounds(c.x, common.ScreenWidth, bounds.x, bounds.w)
+	y := clampToBounds(c.y, common.ScreenHeight, bounds.y, bounds.h)
+	if c.transitionTimer > 0 {
+		c.transitionTimer = c.transitionTimer - delta
+		amount := 1 - (math.Max(0, c.transitionTimer) / c.transitionTime)
+		if !c.scrolling {
+			// ease in and out
+			amount = amount * amount * (3 - (2 * amount))
+		}
+		x = c.transitionFromX + ((x - c.transitionFromX) * amount)
+		y = c.transitionFromY + ((y - c.transitionFromY) * amount)
 	}
-	if c.y < 0 {
-		c.y = 0
+	c.x, c.y = x, y
+	c.shake(delta)
+}
+
+// enterRoom keeps track of the room the target is in, moving across to a room it crosses into
+// instead of jumping there.
+func (c *Camera) enterRoom(level *Level) {
+	tx, ty := c.target.GetPos()
+	room := level.cameraRoomAt(tx+(common.TileSize/2), ty+(common.TileSize/2), c.room)
+	if room == c.room {
+		return
 	}
-	maxWidth := float64((game.Level.tiledGrid.GroundLayer.Width * common.TileSize) - common.ScreenWidth)
-	if c.x > maxWidth {
-		c.x = maxWidth
+	if !c.snap && level.camera.RoomTransitionTime > 0 {
+		c.transitionFromX, c.transitionFromY = c.x, c.y
+		c.transitionTime = level.camera.RoomTransitionTime
+		c.transitionTimer = c.transitionTime
+		c.scrolling = room != nil && room.scroll
 	}
-	maxHeight := float64((game.Level.tiledGrid.GroundLayer.Height * common.TileSize) - common.ScreenHeight)
-	if c.y > maxHeight {
-		c.y = maxHeight
+	c.room = room
+}
+
+// IsScrolling is true while the camera scrolls into a room that is entered metroidvania style,
+// the game waits for it to finish.
+func (c *Camera) IsScrolling() bool {
+	return c.scrolling && c.transitionTimer > 0
+}
+
+// AddTrauma shakes the camera harder, up to the most it can shake.
+func (c *Camera) AddTrauma(trauma float64) {
+	c.trauma = math.Min(1, c.trauma+trauma)
+}
+
+// shake moves the picture about by the trauma squared as it wears off, so small hits barely
+// shake and big ones shake hard. Only drawing is moved, the camera stays where it is.
+func (c *Camera) shake(delta float64) {
+	c.trauma = math.Max(0, c.trauma-(traumaDecay*delta))
+	c.shakeTime = c.shakeTime + delta
+	distance := c.trauma * c.trauma * shakeDistance
+	c.shakeX = distance * math.Sin(c.shakeTime*shakeSpeed) * math.Cos(c.shakeTime*shakeSpeed*0.37)
+	c.shakeY = distance * math.Sin((c.shakeTime*shakeSpeed*1.3)+1) * math.Cos(c.shakeTime*shakeSpeed*0.21)
+}
+
+// follow moves the camera towards the target. The target can move about the deadzone without
+// the camera moving, the camera looks ahead the way it is going and only moves up and down to
+// where it lands, unless it jumps or falls out of the deadzone.
+func (c *Camera) follow(delta float64, settings *CameraSettings) {
+	tx, ty := c.target.GetPos()
+	tx, ty = tx+(common.TileSize/2), ty+(common.TileSize/2)
+	follow := cameraFollow{settled: true}
+	if follower, ok := c.target.(cameraFollower); ok {
+		follow = follower.cameraFollow()
+	}
+	lookAhead := (follow.facing * settings.LookAhead) + (follow.velocityX * settings.LookAheadVelocity)
+	if c.snap {
+		c.focusX, c.groundY, c.lookAheadX = tx+lookAhead, ty, lookAhead
+	}
+	c.lookAheadX = c.lookAheadX + ((lookAhead - c.lookAheadX) * smoothAmount(settings.LookAheadSmoothing, delta))
+	aheadX := tx + c.lookAheadX
+	c.focusX = math.Max(aheadX-(settings.DeadzoneWidth/2), math.Min(aheadX+(settings.DeadzoneWidth/2), c.focusX))
+
+	if follow.settled {
+		c.groundY = ty
+	} else {
+		c.groundY = math.Max(ty-(settings.DeadzoneHeight/2), math.Min(ty+(settings.DeadzoneHeight/2), c.groundY))
 	}
+	if follow.look == 0 || follow.look != c.lookDirection {
+		c.lookTimer = 0
+	}
+	c.lookDirection = follow.look
+	c.lookTimer = c.lookTimer + delta
+	c.focusY = c.groundY
+	if follow.look != 0 && c.lookTimer > settings.LookDelay {
+		c.focusY = c.groundY + (follow.look * settings.LookDistance)
+	}
+
+	amount := smoothAmount(settings.Smoothing, delta)
+	if c.snap {
+		amount = 1
+		c.snap = false
+	}
+	c.centreX = c.centreX + ((c.focusX - c.centreX) * amount)
+	c.centreY = c.centreY + ((c.focusY - c.centreY) * amount)
+}
+
+// smoothAmount is how much of the way to its target something easing at the rate gets in the
+// time, the same share every second however often it updates. A rate of 0 gets all the way.
+func smoothAmount(rate, delta float64) float64 {
+	if rate <= 0 {
+		return 1
+	}
+	return 1 - math.Exp(-rate*delta)
+}
+
+// drawDebug outlines what the camera can see and the camera rooms, with its centre, the
+// deadzone, the point it is moving to and the point it is following.
+func (c *Camera) drawDebug(debug *DebugDrawer, game *Game) {
+	w, h := float64(common.ScreenWidth), float64(common.ScreenHeight)
+	debug.DrawBox(debugCamera, debugCameraColor, c.x+1, c.y+1, w-2, 1)
+	debug.DrawBox(debugCamera, debugCameraColor, c.x+1, c.y+h-2, w-2, 1)
+	debug.DrawBox(debugCamera, debugCameraColor, c.x+1, c.y+1, 1, h-2)
+	debug.DrawBox(debugCamera, debugCameraColor, c.x+w-2, c.y+1, 1, h-2)
+	cx, cy := c.x+(w/2), c.y+(h/2)
+	debug.DrawLine(debugCamera, debugCameraColor, cx-4, cy, cx+4, cy)
+	debug.DrawLine(debugCamera, debugCameraColor, cx, cy-4, cx, cy+4)
+	for _, room := range game.Level.cameraRooms {
+		b := room.bounds
+		debug.DrawLine(debugCamera, debugRoomColor, b.x, b.y, b.x+b.w, b.y)
+		debug.DrawLine(debugCamera, debugRoomColor, b.x, b.y+b.h, b.x+b.w, b.y+b.h)
+		debug.DrawLine(debugCamera, debugRoomColor, b.x, b.y, b.x, b.y+b.h)
+		debug.DrawLine(debugCamera, debugRoomColor, b.x+b.w, b.y, b.x+b.w, b.y+b.h)
+	}
+	if c.target != nil {
+		settings := game.Level.camera
+		dx, dy := c.focusX-(settings.DeadzoneWidth/2), c.groundY-(settings.DeadzoneHeight/2)
+		debug.DrawLine(debugCamera, debugCameraColor, dx, dy, dx, dy+settings.DeadzoneHeight)
+		debug.DrawLine(debugCamera, debugCameraColor, dx+settings.DeadzoneWidth, dy, dx+settings.DeadzoneWidth, dy+settings.DeadzoneHeight)
+		debug.DrawLine(debugCamera, debugCameraColor, dx, c.groundY, dx+settings.DeadzoneWidth, c.groundY)
+		debug.DrawBox(debugCamera, debugCameraColor, c.focusX-1, c.focusY-1, 3, 3)
+		tx, ty := c.target.GetPos()
+		tx, ty = tx+(common.TileSize/2), ty+(common.TileSize/2)
+		debug.DrawLine(debugCamera, debugCameraColor, cx, cy, tx, ty)
+		debug.DrawBox(debugCamera, debugCameraColor, tx-1, ty-1, 3, 3)
+	}
+	debug.DrawText(debugCamera, fmt.Sprintf("camera %.0f %.0f  trauma %.2f", c.x, c.y, c.trauma), c.x+4, c.y+h-10)
 }
 
+// DrawBuffer draws what the camera has drawn this frame onto the screen, then clears it for
+// the next one.
 func (c *Camera) DrawBuffer(screen *ebiten.Image) {
+	if c.buffer == nil {
+		return
+	}
 	ops := &ebiten.DrawImageOptions{}
 	screen.DrawImage(c.buffer, ops)
+	c.buffer.Clear()
 }
 
+// DrawImage draws onto the camera's buffer, which is only made once something is drawn so a
+// camera that is never drawn needs no graphics.
 func (c *Camera) DrawImage(img *ebiten.Image, options *ebiten.DrawImageOptions) {
-	options.GeoM.Translate(-c.x*common.Scale, -c.y*common.Scale)
+	if c.buffer == nil {
+		c.buffer = ebiten.NewImage(common.ScreenWidth*common.Scale, common.ScreenHeight*common.Scale)
+	}
+	options.GeoM.Translate(-(c.x+c.shakeX)*common.Scale, -(c.y+c.shakeY)*common.Scale)
 	c.buffer.DrawImage(img, options)
 }
 
+// Target follows the target, jumping straight to it. With no target the camera stays where
+// it is put, anywhere in the level.
 func (c *Camera) Target(target CameraTarget) {
 	c.target = target
+	c.snap = true
+	c.room = nil
+	c.transitionTimer = 0
+}
+
+// leaveRoom forgets the room the camera is in, for when the level's rooms are built again. The
+// camera moves across to the room the target is in the next time it updates.
+func (c *Camera) leaveRoom() {
+	c.room = nil
 }
 
 func (c *Camera) GetPos() (float64, float64) {
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"math"
+	"platformer/common"
+)
+
+const cameraRoomObject = "camera-room"
+
+// cameraRoomScroll is the transition property for rooms entered metroidvania style, the game
+// stops while the camera scrolls across.
+const cameraRoomScroll = "scroll"
+
+// cameraRoom is an area drawn in the map that the camera stays inside while the player is in
+// it. Rooms smaller than the screen are centred.
+type cameraRoom struct {
+	bounds CollisionBox
+	scroll bool
+}
+
+func newCameraRoom(object *common.ObjectData) *cameraRoom {
+	room := &cameraRoom{
+		bounds: CollisionBox{
+			x: float64(object.X),
+			y: float64(object.Y),
+			w: float64(object.W),
+			h: float64(object.H),
+		},
+	}
+	for _, prop := range object.Properties {
+		if prop.Name == "transition" && prop.Value != nil {
+			room.scroll = (prop.Value).(string) == cameraRoomScroll
+		}
+	}
+	return room
+}
+
+// cameraRoomAt finds the room the point is in, staying in the current room for as long as the
+// point is inside it so overlapping rooms do not flick between each other.
+func (r *Level) cameraRoomAt(x, y float64, current *cameraRoom) *cameraRoom {
+	if current != nil && current.contains(x, y) {
+		return current
+	}
+	for _, room := range r.cameraRooms {
+		if room.contains(x, y) {
+			return room
+		}
+	}
+	return nil
+}
+
+func (r *cameraRoom) contains(x, y float64) bool {
+	return common.Contains(r.bounds.x, r.bounds.y, r.bounds.w, r.bounds.h, x, y)
+}
+
+// cameraBounds is the area the camera can see, the room it is in or else the whole level.
+func (r *Level) cameraBounds(room *cameraRoom) CollisionBox {
+	if room != nil {
+		return room.bounds
+	}
+	return CollisionBox{
+		w: float64(r.tiledGrid.GroundLayer.Width * common.TileSize),
+		h: float64(r.tiledGrid.GroundLayer.Height * common.TileSize),
+	}
+}
+
+// clampToBounds keeps the screen inside the bounds, centring it on bounds smaller than it.
+func clampToBounds(position, size, start, length float64) float64 {
+	if length <= size {
+		return start + ((length - size) / 2)
+	}
+	return math.Max(start, math.Min(start+length-size, position))
+}
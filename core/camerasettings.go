@@ -0,0 +1,74 @@
+package core
+
+import "platformer/common"
+
+// CameraSettings is how the camera follows the player. A map changes any of them with a
+// property of the same name starting with camera-, like camera-smoothing. Distances are in
+// pixels and times in seconds.
+type CameraSettings struct {
+	// DeadzoneWidth is how far the player can move across the middle of the screen before
+	// the camera follows, DeadzoneHeight is how far they can jump or fall away from the last
+	// platform they landed on.
+	DeadzoneWidth  float64
+	DeadzoneHeight float64
+	// Smoothing is how quickly the camera catches up, the larger the quicker, 0 snaps.
+	Smoothing float64
+	// LookAhead is how far in front of the player the camera looks, with LookAheadVelocity
+	// seconds of their running speed added on, turning to LookAheadSmoothing.
+	LookAhead          float64
+	LookAheadVelocity  float64
+	LookAheadSmoothing float64
+	// LookDistance is how far up or down the camera looks once the player has stood still
+	// holding up or down for LookDelay.
+	LookDistance float64
+	LookDelay    float64
+	// RoomTransitionTime is how long the camera takes to move into another camera room.
+	RoomTransitionTime float64
+}
+
+func newCameraSettings() *CameraSettings {
+	return &CameraSettings{
+		DeadzoneWidth:      24,
+		DeadzoneHeight:     128,
+		Smoothing:          8,
+		LookAhead:          16,
+		LookAheadVelocity:  0.2,
+		LookAheadSmoothing: 3,
+		LookDistance:       56,
+		LookDelay:          0.4,
+		RoomTransitionTime: 0.5,
+	}
+}
+
+// loadCameraSettings reads the camera properties of the map, anything missing keeps the
+// default.
+func loadCameraSettings(tiledGrid *common.TiledGrid) *CameraSettings {
+	settings := newCameraSettings()
+	for _, prop := range tiledGrid.Properties {
+		value, ok := prop.Value.(float64)
+		if !ok {
+			continue
+		}
+		switch prop.Name {
+		case "camera-deadzone-width":
+			settings.DeadzoneWidth = value
+		case "camera-deadzone-height":
+			settings.DeadzoneHeight = value
+		case "camera-smoothing":
+			settings.Smoothing = value
+		case "camera-look-ahead":
+			settings.LookAhead = value
+		case "camera-look-ahead-velocity":
+			settings.LookAheadVelocity = value
+		case "camera-look-ahead-smoothing":
+			settings.LookAheadSmoothing = value
+		case "camera-look-distance":
+			settings.LookDistance = value
+		case "camera-look-delay":
+			settings.LookDelay = value
+		case "camera-room-transition-time":
+			settings.RoomTransitionTime = value
+		}
+	}
+	return settings
+}
@@ -1,166 +1,288 @@
 package core
 
-import "platformer/common"
+import (
+	"math"
+	"platformer/common"
+)
+
+// collisionEpsilon is how close to a tile edge still counts as being on it, so rounding does
+// not stop a body standing on the floor from walking along it.
+const collisionEpsilon = 0.01
+
+// wallProbeDistance is how far to the side WallContact looks for a wall.
+const wallProbeDistance = 1.0
 
 type Collider interface {
 	GetCollisionBox() CollisionBox
 }
 
+// CollisionOptions changes how a body treats the tiles it moves through.
+type CollisionOptions struct {
+	// dropThrough lets the body fall through platform tiles
+	dropThrough bool
+	// avoidHazards makes damage tiles solid from the side and below, so enemies turn back from spikes
+	avoidHazards bool
+	// platformWalls makes platform tiles solid from the side, enemies have always turned back
+	// from them rather than walking through like the player
+	platformWalls bool
+	// groundSnap is set when the body was standing on the floor, it keeps to slopes on the way
+	// down and steps up onto the tile at the top of them
+	groundSnap bool
+}
+
+// Contact is a surface that stopped the body. The normal points out of the surface, back
+// towards the body. collider is what was hit, it is nil when the body hit a tile.
+type Contact struct {
+	normalX  float64
+	normalY  float64
+	collider Collider
+	tileX    int
+	tileY    int
+	tile     *common.TileData
+}
+
 type CollisionResult struct {
 	newX       float64
 	newY       float64
 	hitFloor   bool
 	hitCeiling bool
 	hitWall    bool
+	// contacts has at most one contact for each axis, the wall first
+	contacts []Contact
+	// damageTile is the first damage tile the body ends up in or standing on
+	damageTile  *common.TileData
+	damageTileX int
+	damageTileY int
 }
 
-func DoCollision(oldX, oldY, newX, newY, w, h float64, level *Level, tryFall bool) CollisionResult {
+// DoCollision moves the box by moveX then moveY, stopping it at the first tile or collider in
+// the way on each axis. Every tile the box sweeps over is checked, so fast bodies cannot pass
+// through thin walls. Platforms only stop bodies falling onto them from above. Slopes are
+// only solid from above, the floor height is taken from the slope under the middle of the box.
+func DoCollision(box CollisionBox, moveX, moveY float64, level *Level, options CollisionOptions) CollisionResult {
+	cr := CollisionResult{}
 
-	cr := CollisionResult{
-		newX: newX,
-		newY: newY,
+	stepUp, snap := 0.0, 0.0
+	if options.groundSnap {
+		// the middle of the box is half its width onto a slope before the box leaves the floor
+		stepUp = (box.w / 2) + collisionEpsilon
+		snap = stepUp + math.Abs(moveX)
 	}
-
-	isFalling := newY > oldY
-
-	horizontalTopLeftX, horizontalTopLeftY := newX, oldY
-	horizontalTopRightX, horizontalTopRightY := newX+w, oldY
-	horizontalBottomLeftX, horizontalBottomLeftY := newX, oldY+h
-	horizontalBottomRightX, horizontalBottomRightY := newX+w, oldY+h
-	verticalTopLeftX, verticalTopLeftY := oldX, newY
-	verticalTopRightX, verticalTopRightY := oldX+w, newY
-	verticalBottomLeftX, verticalBottomLeftY := oldX, newY+h
-	verticalBottomRightX, verticalBottomRightY := oldX+w, newY+h
-
-	var horizontalTopLeftCollider Collider = nil
-	var horizontalTopRightCollider Collider = nil
-	var horizontalBottomLeftCollider Collider = nil
-	var horizontalBottomRightCollider Collider = nil
-	var verticalTopLeftCollider Collider = nil
-	var verticalTopRightCollider Collider = nil
-	var verticalBottomLeftCollider Collider = nil
-	var verticalBottomRightCollider Collider = nil
-	for _, c := range level.GetColliders() {
-		cb := c.GetCollisionBox()
-		if common.Contains(cb.x, cb.y, cb.w, cb.h, horizontalTopLeftX, horizontalTopLeftY) {
-			horizontalTopLeftCollider = c
-		}
-		if common.Contains(cb.x, cb.y, cb.w, cb.h, horizontalTopRightX, horizontalTopRightY) {
-			horizontalTopRightCollider = c
-		}
-		if common.Contains(cb.x, cb.y, cb.w, cb.h, horizontalBottomLeftX, horizontalBottomLeftY) {
-			horizontalBottomLeftCollider = c
-		}
-		if common.Contains(cb.x, cb.y, cb.w, cb.h, horizontalBottomRightX, horizontalBottomRightY) {
-			horizontalBottomRightCollider = c
-		}
-		if common.Contains(cb.x, cb.y, cb.w, cb.h, verticalTopLeftX, verticalTopLeftY) {
-			verticalTopLeftCollider = c
-		}
-		if common.Contains(cb.x, cb.y, cb.w, cb.h, verticalTopRightX, verticalTopRightY) {
-			verticalTopRightCollider = c
-		}
-		if common.Contains(cb.x, cb.y, cb.w, cb.h, verticalBottomLeftX, verticalBottomLeftY) {
-			verticalBottomLeftCollider = c
+	newX, wall := sweepX(box, moveX, stepUp, level, options)
+	if wall != nil {
+		cr.hitWall = true
+		cr.contacts = append(cr.contacts, *wall)
+	}
+	moved := CollisionBox{x: newX, y: box.y, w: box.w, h: box.h}
+	newY, floor := sweepY(moved, moveY, level, options)
+	if moveY >= 0 {
+		bottom := newY + box.h
+		if floor == nil {
+			bottom = bottom + snap
 		}
-		if common.Contains(cb.x, cb.y, cb.w, cb.h, verticalBottomRightX, verticalBottomRightY) {
-			verticalBottomRightCollider = c
+		// a slope rises at most as far as the box moved sideways
+		rise := math.Max(stepUp, math.Abs(moveX)+collisionEpsilon)
+		surface, ground := slopeFloor(newX+(box.w/2), box.y+box.h-rise, bottom, level)
+		if ground != nil && (surface < newY+box.h || floor == nil) {
+			newY, floor = surface-box.h, ground
 		}
 	}
+	if floor != nil {
+		cr.hitFloor = floor.normalY < 0
+		cr.hitCeiling = floor.normalY > 0
+		cr.contacts = append(cr.contacts, *floor)
+	}
+	cr.newX, cr.newY = newX, newY
 
-	// horizontal
-	x, y := horizontalTopLeftX, horizontalTopLeftY
-	td := level.tiledGrid.GetTileData(int(x/common.TileSize), int(y/common.TileSize))
-	if td.Block || horizontalTopLeftCollider != nil {
-		cr.newX = oldX
-		cr.hitWall = true
+	left, right := spanTiles(newX, box.w)
+	top, _ := spanTiles(newY, box.h)
+	bottom := tileAt(newY + box.h + collisionEpsilon)
+	for ty := top; ty <= bottom && cr.damageTile == nil; ty++ {
+		for tx := left; tx <= right; tx++ {
+			if td := level.tiledGrid.GetTileData(tx, ty); td.Damage {
+				cr.damageTile, cr.damageTileX, cr.damageTileY = td, tx, ty
+				break
+			}
+		}
 	}
 
-	x, y = horizontalTopRightX, horizontalTopRightY
-	td = level.tiledGrid.GetTileData(int(x/common.TileSize), int(y/common.TileSize))
-	if td.Block || horizontalTopRightCollider != nil {
-		cr.newX = oldX
-		cr.hitWall = true
+	if level.debug.IsShown(debugProbes) {
+		level.debug.DrawBox(debugProbes, debugProbeColor, math.Min(box.x, newX), math.Min(box.y, newY), box.w+math.Abs(newX-box.x), box.h+math.Abs(newY-box.y))
+		for _, c := range cr.contacts {
+			x, y := newX+(box.w/2)-(c.normalX*box.w/2), newY+(box.h/2)-(c.normalY*box.h/2)
+			level.debug.DrawLine(debugProbes, debugProbeHitColor, x, y, x+(c.normalX*6), y+(c.normalY*6))
+		}
 	}
+	return cr
+}
 
-	x, y = horizontalBottomLeftX, horizontalBottomLeftY
-	td = level.tiledGrid.GetTileData(int(x/common.TileSize), int(y/common.TileSize))
-	if td.Block || horizontalBottomLeftCollider != nil {
-		cr.newX = oldX
-		cr.hitWall = true
+// floorTile is the tile the body is standing on, an empty tile when it is in the air or
+// standing on a collider.
+func (r CollisionResult) floorTile() *common.TileData {
+	for _, c := range r.contacts {
+		if c.normalY < 0 && c.tile != nil {
+			return c.tile
+		}
 	}
+	return common.EmptyTile
+}
 
-	x, y = horizontalBottomRightX, horizontalBottomRightY
-	td = level.tiledGrid.GetTileData(int(x/common.TileSize), int(y/common.TileSize))
-	if td.Block || horizontalBottomRightCollider != nil {
-		cr.newX = oldX
-		cr.hitWall = true
+// HasFloorBelow is true when something within distance under the box would stop it falling,
+// used to find ledges before walking off them.
+func HasFloorBelow(box CollisionBox, distance float64, level *Level, options CollisionOptions) bool {
+	_, floor := sweepY(box, distance, level, options)
+	if floor == nil {
+		bottom := box.y + box.h
+		_, floor = slopeFloor(box.x+(box.w/2), bottom, bottom+distance, level)
 	}
+	return floor != nil
+}
 
-	// vertical
-	x, y = verticalBottomLeftX, verticalBottomLeftY
-	td = level.tiledGrid.GetTileData(int(x/common.TileSize), int(y/common.TileSize))
-	if td.Block {
-		cr.newY = float64(int(y/common.TileSize)*common.TileSize) - h - fudge
-		cr.hitFloor = true
+// WallContact is the wall right next to the box on one side, direction is -1 for the left and
+// 1 for the right. It is nil when there is nothing within a pixel.
+func WallContact(box CollisionBox, direction float64, level *Level) *Contact {
+	_, wall := sweepX(box, direction*wallProbeDistance, 0, level, CollisionOptions{})
+	return wall
+}
+
+// slopeFloor finds the floor under x between two heights when it is a slope, or the top of a
+// tile stepped onto from one.
+func slopeFloor(x, from, to float64, level *Level) (float64, *Contact) {
+	tx := tileAt(x)
+	for ty := tileAt(from); ty <= tileAt(to); ty++ {
+		td := level.tiledGrid.GetTileData(tx, ty)
+		if td.Slope == nil && !td.Block {
+			continue
+		}
+		contact := &Contact{normalY: -1, tileX: tx, tileY: ty, tile: td}
+		surface := float64(ty) * common.TileSize
+		if td.Slope != nil {
+			surface = (float64(ty+1) * common.TileSize) - td.Slope.HeightAt(x-(float64(tx)*common.TileSize))
+			contact.normalX, contact.normalY = td.Slope.Normal()
+		}
+		if surface >= from && surface <= to {
+			return surface, contact
+		}
 	}
-	if verticalBottomLeftCollider != nil {
-		cr.newY = verticalBottomLeftCollider.GetCollisionBox().y - h - fudge
-		cr.hitFloor = true
+	return 0, nil
+}
+
+// sweepX moves the box sideways. Tiles with a top less than stepUp above the bottom of the box
+// are stepped onto rather than stopping it, for walking off the top of a slope.
+func sweepX(box CollisionBox, moveX, stepUp float64, level *Level, options CollisionOptions) (float64, *Contact) {
+	newX := box.x + moveX
+	if moveX == 0 {
+		return newX, nil
 	}
-	if !tryFall && td.Platform && isFalling {
-		distance := float64(int(y/common.TileSize)*common.TileSize) - (oldY + h)
-		if distance > -1 {
-			cr.newY = oldY + distance - fudge
-			cr.hitFloor = true
+	var contact *Contact
+	top, bottom := spanTiles(box.y, box.h)
+	blocks := func(td *common.TileData, ty int) bool {
+		if td.Block && float64(ty)*common.TileSize >= box.y+box.h-stepUp {
+			return false
 		}
+		return td.Block || (options.avoidHazards && td.Damage) || (options.platformWalls && td.Platform)
 	}
-
-	x, y = verticalTopLeftX, verticalTopLeftY
-	td = level.tiledGrid.GetTileData(int(x/common.TileSize), int(y/common.TileSize))
-	if td.Block {
-		cr.newY = float64(int(y/common.TileSize)*common.TileSize) + common.TileSize + fudge
-		if cr.newY > 0 {
-			cr.hitCeiling = true
+	if moveX > 0 {
+		edge := box.x + box.w
+		for tx := tileAt(edge-collisionEpsilon) + 1; contact == nil && tx <= tileBefore(edge+moveX); tx++ {
+			for ty := top; ty <= bottom; ty++ {
+				if td := level.tiledGrid.GetTileData(tx, ty); blocks(td, ty) {
+					newX = (float64(tx) * common.TileSize) - box.w
+					contact = &Contact{normalX: -1, tileX: tx, tileY: ty, tile: td}
+					break
+				}
+			}
+		}
+	} else {
+		for tx := tileAt(box.x+collisionEpsilon) - 1; contact == nil && tx >= tileAt(box.x+moveX); tx-- {
+			for ty := top; ty <= bottom; ty++ {
+				if td := level.tiledGrid.GetTileData(tx, ty); blocks(td, ty) {
+					newX = float64(tx+1) * common.TileSize
+					contact = &Contact{normalX: 1, tileX: tx, tileY: ty, tile: td}
+					break
+				}
+			}
 		}
 	}
-	if verticalTopLeftCollider != nil {
-		cb := verticalTopLeftCollider.GetCollisionBox()
-		cr.newY = cb.y + cb.h + fudge
-		cr.hitFloor = true
+	for _, c := range level.GetColliders() {
+		cb := c.GetCollisionBox()
+		if !spansOverlap(box.y, box.h, cb.y, cb.h) {
+			continue
+		}
+		if moveX > 0 && cb.x >= box.x+box.w-collisionEpsilon && cb.x-box.w < newX {
+			newX = cb.x - box.w
+			contact = &Contact{normalX: -1, collider: c}
+		}
+		if moveX < 0 && cb.x+cb.w <= box.x+collisionEpsilon && cb.x+cb.w > newX {
+			newX = cb.x + cb.w
+			contact = &Contact{normalX: 1, collider: c}
+		}
 	}
+	return newX, contact
+}
 
-	x, y = verticalBottomRightX, verticalBottomRightY
-	td = level.tiledGrid.GetTileData(int(x/common.TileSize), int(y/common.TileSize))
-	if td.Block {
-		cr.newY = float64(int(y/common.TileSize)*common.TileSize) - h - fudge
-		cr.hitFloor = true
-	}
-	if verticalBottomRightCollider != nil {
-		cr.newY = verticalBottomRightCollider.GetCollisionBox().y - h - fudge
-		cr.hitFloor = true
+func sweepY(box CollisionBox, moveY float64, level *Level, options CollisionOptions) (float64, *Contact) {
+	newY := box.y + moveY
+	if moveY == 0 {
+		return newY, nil
 	}
-	if !tryFall && td.Platform && isFalling {
-		distance := float64(int(y/common.TileSize)*common.TileSize) - (oldY + h)
-		if distance > -1 {
-			cr.newY = oldY + distance - fudge
-			cr.hitFloor = true
+	var contact *Contact
+	left, right := spanTiles(box.x, box.w)
+	if moveY > 0 {
+		edge := box.y + box.h
+		for ty := tileAt(edge-collisionEpsilon) + 1; contact == nil && ty <= tileBefore(edge+moveY); ty++ {
+			for tx := left; tx <= right; tx++ {
+				if td := level.tiledGrid.GetTileData(tx, ty); td.Block || (td.Platform && !options.dropThrough) {
+					newY = (float64(ty) * common.TileSize) - box.h
+					contact = &Contact{normalY: -1, tileX: tx, tileY: ty, tile: td}
+					break
+				}
+			}
 		}
-	}
-
-	x, y = verticalTopRightX, verticalTopRightY
-	td = level.tiledGrid.GetTileData(int(x/common.TileSize), int(y/common.TileSize))
-	if td.Block {
-		cr.newY = float64(int(y/common.TileSize)*common.TileSize) + common.TileSize + fudge
-		if cr.newY > 0 {
-			cr.hitCeiling = true
+	} else {
+		for ty := tileAt(box.y+collisionEpsilon) - 1; contact == nil && ty >= tileAt(box.y+moveY); ty-- {
+			for tx := left; tx <= right; tx++ {
+				if td := level.tiledGrid.GetTileData(tx, ty); td.Block || (options.avoidHazards && td.Damage) {
+					newY = float64(ty+1) * common.TileSize
+					contact = &Contact{normalY: 1, tileX: tx, tileY: ty, tile: td}
+					break
+				}
+			}
 		}
 	}
-	if verticalTopRightCollider != nil {
-		cb := verticalTopRightCollider.GetCollisionBox()
-		cr.newY = cb.y + cb.h + fudge
-		cr.hitFloor = true
+	for _, c := range level.GetColliders() {
+		cb := c.GetCollisionBox()
+		if !spansOverlap(box.x, box.w, cb.x, cb.w) {
+			continue
+		}
+		if moveY > 0 && cb.y >= box.y+box.h-collisionEpsilon && cb.y-box.h < newY {
+			newY = cb.y - box.h
+			contact = &Contact{normalY: -1, collider: c}
+		}
+		if moveY < 0 && cb.y+cb.h <= box.y+collisionEpsilon && cb.y+cb.h > newY {
+			newY = cb.y + cb.h
+			contact = &Contact{normalY: 1, collider: c}
+		}
 	}
+	return newY, contact
+}
 
-	return cr
+// tileAt is the tile a world position is in, rounding down so negative positions work.
+func tileAt(v float64) int {
+	return int(math.Floor(v / common.TileSize))
+}
+
+// tileBefore is the last tile an edge moving forward reaches, a tiny move onto a tile edge
+// still reaches the tile so bodies resting on the floor keep hitting it.
+func tileBefore(v float64) int {
+	return int(math.Ceil(v/common.TileSize)) - 1
+}
+
+// spanTiles is the first and last tile a span overlaps, not counting tiles it only touches.
+func spanTiles(start, size float64) (int, int) {
+	return tileAt(start + collisionEpsilon), tileAt(start + size - collisionEpsilon)
+}
+
+func spansOverlap(start1, size1, start2, size2 float64) bool {
+	return start1+collisionEpsilon < start2+size2 && start2 < start1+size1-collisionEpsilon
 }
@@ -0,0 +1,194 @@
+package core
+
+import (
+	"platformer/common"
+	"testing"
+)
+
+// textLevel builds a level from rows of text, # is a wall and = a platform.
+func textLevel(game *Game, rows ...string) *Level {
+	tiles := map[rune]int{'#': 1, '=': 2}
+	layer := &common.Layer{Width: len(rows[0]), Height: len(rows), Name: "Ground"}
+	for _, row := range rows {
+		for _, c := range row {
+			layer.Data = append(layer.Data, tiles[c])
+		}
+	}
+	grid := &common.TiledGrid{
+		TileSet: &common.TileSet{FirstGid: 1},
+		TileMap: map[int]*common.TileData{
+			0: {Block: true, Friction: 1},
+			1: {Platform: true, Friction: 1},
+		},
+		GroundLayer: layer,
+	}
+	return &Level{tiledGrid: grid, debug: game.debug}
+}
+
+// findSlope returns the first slope tile in the level that goes from left to right heights.
+func findSlope(t *testing.T, game *Game, left, right float64) (int, int) {
+	t.Helper()
+	return findTile(t, game, func(td *common.TileData) bool {
+		return td.Slope != nil && td.Slope.Left == left && td.Slope.Right == right
+	})
+}
+
+// walkBox moves a box the size of the player's along the floor a pixel at a time, the way a
+// body standing on the floor is moved. It returns the box and the height of its bottom after
+// each step, failing if the box ever leaves the floor.
+func walkBox(t *testing.T, level *Level, box CollisionBox, direction float64, steps int) (CollisionBox, []float64) {
+	t.Helper()
+	bottoms := []float64{}
+	for i := 0; i < steps; i++ {
+		cr := DoCollision(box, direction, 1, level, CollisionOptions{groundSnap: true})
+		if !cr.hitFloor || cr.hitWall {
+			t.Fatalf("left the floor at %v,%v after %v steps", cr.newX, cr.newY, i)
+		}
+		box.x, box.y = cr.newX, cr.newY
+		bottoms = append(bottoms, box.y+box.h)
+	}
+	return box, bottoms
+}
+
+// boxOn is a box standing on the floor with its middle at x.
+func boxOn(x, floorY float64) CollisionBox {
+	return CollisionBox{x: x - 4, y: floorY - 20, w: 8, h: 20}
+}
+
+func TestWalkingOverSlopes(t *testing.T) {
+	game, _ := newTestGame("level-gamma")
+	level := game.Level
+	for _, hill := range []struct {
+		name string
+		up   [2]float64
+		down [2]float64
+		// wide is how many tiles across the side of the hill is
+		wide int
+	}{
+		{name: "45", up: [2]float64{0, 16}, down: [2]float64{16, 0}, wide: 2},
+		{name: "22", up: [2]float64{0, 8}, down: [2]float64{16, 8}, wide: 4},
+	} {
+		// the first found is the upper tile of the side, the side starts one row down
+		tx, ty := findSlope(t, game, hill.up[0], hill.up[1])
+		tx, ty = tx-(hill.wide/2), ty+1
+		floorY := float64(ty+1) * common.TileSize
+		topY := floorY - (2 * common.TileSize)
+		box := boxOn((float64(tx)*common.TileSize)-8, floorY)
+
+		// up the side, the box climbs as far as the slope rises under its middle
+		steps := (hill.wide * common.TileSize) + 16
+		box, bottoms := walkBox(t, level, box, 1, steps)
+		for i := 1; i < len(bottoms); i++ {
+			if bottoms[i] > bottoms[i-1] {
+				t.Errorf("%v: went down from %v to %v walking up", hill.name, bottoms[i-1], bottoms[i])
+			}
+		}
+		half := (hill.wide * common.TileSize / 2) + 8
+		if want := floorY - common.TileSize; bottoms[half-1] != want {
+			t.Errorf("%v: halfway up the bottom is at %v, want %v", hill.name, bottoms[half-1], want)
+		}
+		if box.y+box.h != topY {
+			t.Errorf("%v: the top of the hill is at %v, want %v", hill.name, box.y+box.h, topY)
+		}
+
+		// back down the same side
+		box, bottoms = walkBox(t, level, box, -1, steps)
+		for i := 1; i < len(bottoms); i++ {
+			if bottoms[i] < bottoms[i-1] {
+				t.Errorf("%v: went up from %v to %v walking down", hill.name, bottoms[i-1], bottoms[i])
+			}
+		}
+		if box.y+box.h != floorY {
+			t.Errorf("%v: walked down to %v, want the floor at %v", hill.name, box.y+box.h, floorY)
+		}
+
+		// and down the far side of the hill
+		dx, dy := findTile(t, game, func(td *common.TileData) bool {
+			return td.Slope != nil && td.Slope.Left == hill.down[0] && td.Slope.Right == hill.down[1]
+		})
+		if dy != ty-1 {
+			t.Fatalf("%v: the far side starts at row %v, want %v", hill.name, dy, ty-1)
+		}
+		box = boxOn((float64(dx)*common.TileSize)-8, topY)
+		box, _ = walkBox(t, level, box, 1, steps)
+		if box.y+box.h != floorY {
+			t.Errorf("%v: walked down the far side to %v, want the floor at %v", hill.name, box.y+box.h, floorY)
+		}
+	}
+}
+
+func TestLandingOnSlope(t *testing.T) {
+	game, _ := newTestGame("level-gamma")
+	tx, ty := findSlope(t, game, 0, 16)
+	// the middle of the box over the middle of the tile, where the slope is half way up
+	box := boxOn((float64(tx)*common.TileSize)+8, float64(ty)*common.TileSize)
+	cr := DoCollision(box, 0, 12, game.Level, CollisionOptions{})
+	if !cr.hitFloor {
+		t.Fatal("falling onto the slope did not land")
+	}
+	if want := (float64(ty) * common.TileSize) + 8; cr.newY+box.h != want {
+		t.Errorf("landed at %v, want %v", cr.newY+box.h, want)
+	}
+	floor := cr.contacts[len(cr.contacts)-1]
+	if floor.normalX >= 0 || floor.normalY >= 0 {
+		t.Errorf("the floor normal is %v,%v, want it up and to the left", floor.normalX, floor.normalY)
+	}
+}
+
+func TestPlatformsAreOneWay(t *testing.T) {
+	game, _ := newTestGame("level-alpha")
+	level := textLevel(game,
+		"........",
+		"........",
+		"...==...",
+		"........",
+		"########",
+	)
+	// standing on the floor next to the platforms, the box is as tall as the gap under them
+	beside := CollisionBox{x: 28, y: 32, w: 8, h: 32}
+	player := DoCollision(beside, 16, 0, level, CollisionOptions{})
+	if player.hitWall || player.newX != 44 {
+		t.Errorf("the player went to %v through the side of the platform, want 44", player.newX)
+	}
+	enemy := DoCollision(beside, 16, 0, level, CollisionOptions{platformWalls: true})
+	if !enemy.hitWall || enemy.newX != 40 {
+		t.Errorf("an enemy went to %v, want it stopped by the platform at 40", enemy.newX)
+	}
+
+	// jumping up through from below
+	below := CollisionBox{x: 52, y: 50, w: 8, h: 14}
+	up := DoCollision(below, 0, -24, level, CollisionOptions{platformWalls: true})
+	if up.hitCeiling || up.newY != 26 {
+		t.Errorf("jumping up stopped at %v, want 26", up.newY)
+	}
+
+	// landing on top, or dropping through when asked
+	above := CollisionBox{x: 52, y: 10, w: 8, h: 14}
+	landed := DoCollision(above, 0, 12, level, CollisionOptions{})
+	if !landed.hitFloor || landed.newY != 18 {
+		t.Errorf("falling onto the platform stopped at %v, want 18", landed.newY)
+	}
+	dropped := DoCollision(above, 0, 12, level, CollisionOptions{dropThrough: true})
+	if dropped.hitFloor || dropped.newY != 22 {
+		t.Errorf("dropping through stopped at %v, want 22", dropped.newY)
+	}
+}
+
+func TestFastBodiesStopAtThinWalls(t *testing.T) {
+	game, _ := newTestGame("level-alpha")
+	level := textLevel(game,
+		"..........",
+		"....#.....",
+		"##########",
+	)
+	box := CollisionBox{x: 8, y: 16, w: 8, h: 16}
+	cr := DoCollision(box, 100, 0, level, CollisionOptions{})
+	if !cr.hitWall || cr.newX != 56 {
+		t.Errorf("moving fast went to %v, want stopped against the wall at 56", cr.newX)
+	}
+	falling := CollisionBox{x: 68, y: -100, w: 8, h: 16}
+	cr = DoCollision(falling, 0, 200, level, CollisionOptions{})
+	if !cr.hitFloor || cr.newY != 0 {
+		t.Errorf("falling fast stopped at %v, want on the wall at 0", cr.newY)
+	}
+}
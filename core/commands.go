@@ -0,0 +1,250 @@
+package core
+
+import (
+	"fmt"
+	"platformer/common"
+	"sort"
+	"strconv"
+)
+
+var knownSpells = []string{spellBullet, spellRay}
+var knownAbilities = []string{abilityWallJump, abilityDoubleJump}
+
+// registerCommands adds the console commands for cheating and moving around while testing.
+func (r *Game) registerCommands() {
+	r.RegisterCommand(&ConsoleCommand{
+		Name: "level",
+		Help: "level NAME loads a level",
+		Run: func(args []string, game *Game) string {
+			if len(args) != 1 {
+				return "usage: level NAME"
+			}
+			if !contains(common.GetLevelNames(), args[0]) {
+				return "no level called " + args[0]
+			}
+			game.LoadLevel(args[0])
+			return "loaded " + args[0]
+		},
+		Complete: func(args []string, game *Game) []string {
+			if len(args) > 0 {
+				return nil
+			}
+			return common.GetLevelNames()
+		},
+	})
+	r.RegisterCommand(&ConsoleCommand{
+		Name: "tp",
+		Help: "tp X Y moves the player to the tile",
+		Run: func(args []string, game *Game) string {
+			if len(args) != 2 {
+				return "usage: tp X Y"
+			}
+			x, errX := strconv.ParseFloat(args[0], 64)
+			y, errY := strconv.ParseFloat(args[1], 64)
+			if errX != nil || errY != nil {
+				return "the tile position must be numbers"
+			}
+			game.Player.x = x * common.TileSize
+			game.Player.y = y * common.TileSize
+			game.Player.velocityX, game.Player.velocityY = 0, 0
+			return fmt.Sprintf("moved to %v %v", game.Player.x, game.Player.y)
+		},
+	})
+	r.RegisterCommand(&ConsoleCommand{
+		Name: "give",
+		Help: "give spell NAME teaches the player a spell, give ability NAME unlocks an ability",
+		Run: func(args []string, game *Game) string {
+			if len(args) != 2 {
+				return "usage: give spell NAME or give ability NAME"
+			}
+			switch args[0] {
+			case "spell":
+				game.Player.AddSpell(args[1])
+				game.PlayerProgress.AddSpell(args[1])
+				return "learned " + args[1]
+			case "ability":
+				game.PlayerProgress.AddAbility(args[1])
+				return "unlocked " + args[1]
+			}
+			return "usage: give spell NAME or give ability NAME"
+		},
+		Complete: func(args []string, game *Game) []string {
+			switch len(args) {
+			case 0:
+				return []string{"spell", "ability"}
+			case 1:
+				if args[0] == "ability" {
+					return knownAbilities
+				}
+				return knownSpells
+			}
+			return nil
+		},
+	})
+	r.RegisterCommand(&ConsoleCommand{
+		Name: "heal",
+		Help: "fills the player's health",
+		Run: func(args []string, game *Game) string {
+			game.Player.Health = game.Player.MaxHealth
+			return "healed"
+		},
+	})
+	r.RegisterCommand(&ConsoleCommand{
+		Name: "god",
+		Help: "the player takes no damage",
+		Run: func(args []string, game *Game) string {
+			game.godMode = !game.godMode
+			return "god mode " + onOff(game.godMode)
+		},
+	})
+	r.RegisterCommand(&ConsoleCommand{
+		Name: "noclip",
+		Help: "the player flies through walls",
+		Run: func(args []string, game *Game) string {
+			game.noClip = !game.noClip
+			return "noclip " + onOff(game.noClip)
+		},
+	})
+	r.RegisterCommand(&ConsoleCommand{
+		Name: "kill",
+		Help: "kill all kills every enemy in the level",
+		Run: func(args []string, game *Game) string {
+			if len(args) != 1 || args[0] != "all" {
+				return "usage: kill all"
+			}
+			enemies := append([]Enemy{}, game.Level.enemies...)
+			for _, enemy := range enemies {
+				box := enemy.GetCollisionBox()
+				enemy.GetHurt(game, DamageInfo{
+					Amount:  1000,
+					Type:    damageTypeSpell,
+					SourceX: box.x + (box.w / 2),
+					SourceY: box.y + (box.h / 2),
+				})
+			}
+			return fmt.Sprintf("killed %v enemies", len(enemies))
+		},
+		Complete: func(args []string, game *Game) []string {
+			if len(args) > 0 {
+				return nil
+			}
+			return []string{"all"}
+		},
+	})
+	r.RegisterCommand(&ConsoleCommand{
+		Name: "spawn",
+		Help: "spawn TYPE puts an enemy in front of the player",
+		Run: func(args []string, game *Game) string {
+			if len(args) != 1 {
+				return "usage: spawn TYPE"
+			}
+			def, ok := game.enemyDefinitions[args[0]]
+			if !ok {
+				return "no enemy called " + args[0]
+			}
+			x := game.Player.x + (common.TileSize * 2)
+			if game.Player.isFlip {
+				x = game.Player.x - (common.TileSize * 2)
+			}
+			game.Level.AddEnemy(NewEnemy(x, game.Player.y-common.TileSize, def, game))
+			return "spawned " + args[0]
+		},
+		Complete: func(args []string, game *Game) []string {
+			if len(args) > 0 {
+				return nil
+			}
+			names := []string{}
+			for name := range game.enemyDefinitions {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return names
+		},
+	})
+	r.RegisterCommand(&ConsoleCommand{
+		Name: "reducemotion",
+		Help: "turns screen shake and hit-stops off",
+		Run: func(args []string, game *Game) string {
+			game.SetReduceMotion(!game.ReduceMotion())
+			return "reduce motion " + onOff(game.ReduceMotion())
+		},
+	})
+	r.RegisterCommand(&ConsoleCommand{
+		Name: "timescale",
+		Help: "timescale F runs the game at a different speed, 1 is normal",
+		Run: func(args []string, game *Game) string {
+			if len(args) == 0 {
+				return fmt.Sprintf("timescale %v", game.timeScale)
+			}
+			scale, err := strconv.ParseFloat(args[0], 64)
+			if err != nil || scale < 0 {
+				return "the timescale must be a number, 0 or more"
+			}
+			game.timeScale = scale
+			return fmt.Sprintf("timescale %v", scale)
+		},
+	})
+	r.RegisterCommand(&ConsoleCommand{
+		Name: "physics",
+		Help: "physics NAME gives the level another physics profile, physics save writes the current one",
+		Run: func(args []string, game *Game) string {
+			if len(args) == 0 {
+				return "physics " + game.Level.physics.Name
+			}
+			if args[0] == "save" {
+				if err := game.Level.physics.Save(); err != nil {
+					return "could not save " + err.Error()
+				}
+				game.hotReloader.skipChanges()
+				return "saved " + game.Level.physics.Name
+			}
+			profile, ok := game.physicsProfiles[args[0]]
+			if !ok {
+				return "no physics profile called " + args[0]
+			}
+			game.Level.physics = profile
+			return "physics " + profile.Name
+		},
+		Complete: func(args []string, game *Game) []string {
+			if len(args) > 0 {
+				return nil
+			}
+			names := []string{"save"}
+			for name := range game.physicsProfiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return names
+		},
+	})
+	r.RegisterCommand(&ConsoleCommand{
+		Name: "reload",
+		Help: "loads every image and the level again",
+		Run: func(args []string, game *Game) string {
+			game.ReloadAll()
+			game.hotReloader.skipChanges()
+			return "reloaded " + game.Level.name
+		},
+	})
+}
+
+// RegisterCommand adds a command to the developer console.
+func (r *Game) RegisterCommand(command *ConsoleCommand) {
+	r.console.Register(command)
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+	"platformer/common"
+	"sort"
+	"strings"
+)
+
+const (
+	consoleHeight     = 100.0
+	consoleOpenTime   = 0.15
+	consoleLineHeight = 7.0
+	consoleMaxLines   = 100
+	consolePrompt     = "> "
+	consoleToggleKey  = ebiten.KeyGraveAccent
+)
+
+var consoleColor = color.RGBA{R: 230, G: 225, B: 210, A: 235}
+
+// ConsoleCommand is something that can be typed into the console. Run gets the words after
+// the name and returns what to print. Complete is optional, it gets the words typed so far
+// after the name and returns the values the next word could be.
+type ConsoleCommand struct {
+	Name     string
+	Help     string
+	Run      func(args []string, game *Game) string
+	Complete func(args []string, game *Game) []string
+}
+
+// Console drops down from the top of the screen with the grave key in debug mode, the game
+// is paused while it is open.
+type Console struct {
+	isOpen       bool
+	openAmount   float64
+	input        string
+	lines        []string
+	history      []string
+	historyIndex int
+	commands     map[string]*ConsoleCommand
+	image        *ebiten.Image
+}
+
+func NewConsole(game *Game) *Console {
+	r := &Console{
+		lines:    []string{},
+		history:  []string{},
+		commands: map[string]*ConsoleCommand{},
+		image:    game.res.GetImage("debug-pixel"),
+	}
+	r.Register(&ConsoleCommand{
+		Name: "help",
+		Help: "lists the commands",
+		Run: func(args []string, game *Game) string {
+			lines := []string{}
+			for _, name := range r.commandNames() {
+				lines = append(lines, name+" - "+r.commands[name].Help)
+			}
+			return strings.Join(lines, "\n")
+		},
+	})
+	r.Register(&ConsoleCommand{
+		Name: "clear",
+		Help: "clears the console",
+		Run: func(args []string, game *Game) string {
+			r.lines = []string{}
+			return ""
+		},
+	})
+	return r
+}
+
+// Register adds a command, replacing any command with the same name.
+func (r *Console) Register(command *ConsoleCommand) {
+	r.commands[command.Name] = command
+}
+
+func (r *Console) Update(delta float64, game *Game) {
+	if r.isOpen {
+		r.openAmount = math.Min(1, r.openAmount+(delta/consoleOpenTime))
+	} else {
+		r.openAmount = math.Max(0, r.openAmount-(delta/consoleOpenTime))
+	}
+	if !game.debug.showDebug && !r.isOpen {
+		return
+	}
+	if game.input.IsKeyJustPressed(consoleToggleKey) && !game.editor.isTyping {
+		r.isOpen = !r.isOpen
+		return
+	}
+	if !r.isOpen {
+		return
+	}
+	for _, c := range game.input.AppendInputChars(nil) {
+		if c != '`' && c != '~' {
+			r.input = r.input + string(c)
+		}
+	}
+	if game.input.IsKeyJustPressed(ebiten.KeyBackspace) && len(r.input) > 0 {
+		runes := []rune(r.input)
+		r.input = string(runes[:len(runes)-1])
+	}
+	if game.input.IsKeyJustPressed(ebiten.KeyUp) && r.historyIndex > 0 {
+		r.historyIndex = r.historyIndex - 1
+		r.input = r.history[r.historyIndex]
+	}
+	if game.input.IsKeyJustPressed(ebiten.KeyDown) && r.historyIndex < len(r.history) {
+		r.historyIndex = r.historyIndex + 1
+		r.input = ""
+		if r.historyIndex < len(r.history) {
+			r.input = r.history[r.historyIndex]
+		}
+	}
+	if game.input.IsKeyJustPressed(ebiten.KeyTab) {
+		r.complete(game)
+	}
+	if game.input.IsKeyJustPressed(ebiten.KeyEnter) {
+		line := r.input
+		r.input = ""
+		r.Execute(line, game)
+	}
+}
+
+// Execute runs a line as if it was typed in, printing what the command returns.
+func (r *Console) Execute(line string, game *Game) {
+	r.Print(consolePrompt + line)
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return
+	}
+	if len(r.history) == 0 || r.history[len(r.history)-1] != line {
+		r.history = append(r.history, line)
+	}
+	r.historyIndex = len(r.history)
+	command, ok := r.commands[words[0]]
+	if !ok {
+		r.Print("unknown command " + words[0] + ", try help")
+		return
+	}
+	if out := command.Run(words[1:], game); out != "" {
+		r.Print(out)
+	}
+}
+
+// Print adds text to the console, each line of it on its own line.
+func (r *Console) Print(text string) {
+	r.lines = append(r.lines, strings.Split(text, "\n")...)
+	if len(r.lines) > consoleMaxLines {
+		r.lines = r.lines[len(r.lines)-consoleMaxLines:]
+	}
+}
+
+func (r *Console) close() {
+	r.isOpen = false
+}
+
+// complete finishes the word being typed, from the command names for the first word and from
+// the command after that. When more than one value fits they are printed.
+func (r *Console) complete(game *Game) {
+	words := strings.Fields(r.input)
+	if len(words) == 0 || strings.HasSuffix(r.input, " ") {
+		words = append(words, "")
+	}
+	last := words[len(words)-1]
+	options := []string{}
+	if len(words) == 1 {
+		options = r.commandNames()
+	} else if command, ok := r.commands[words[0]]; ok && command.Complete != nil {
+		options = command.Complete(words[1:len(words)-1], game)
+	}
+	matches := []string{}
+	for _, option := range options {
+		if strings.HasPrefix(option, last) {
+			matches = append(matches, option)
+		}
+	}
+	if len(matches) == 0 {
+		return
+	}
+	completed := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, completed) {
+			completed = completed[:len(completed)-1]
+		}
+	}
+	if len(matches) == 1 {
+		completed = completed + " "
+	} else {
+		r.Print(strings.Join(matches, "  "))
+	}
+	r.input = strings.Join(append(words[:len(words)-1], completed), " ")
+}
+
+func (r *Console) commandNames() []string {
+	names := []string{}
+	for name := range r.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Console) Draw(screen *ebiten.Image) {
+	if r.openAmount <= 0 {
+		return
+	}
+	top := -consoleHeight * (1 - r.openAmount)
+	drawRect(screen, r.image, consoleColor, 0, top, common.ScreenWidth, consoleHeight)
+	inputY := top + consoleHeight - consoleLineHeight - 2
+	common.DrawText(screen, consolePrompt+r.input+"_", 2, inputY)
+	y := inputY - consoleLineHeight
+	for i := len(r.lines) - 1; i >= 0 && y > top; i-- {
+		common.DrawText(screen, r.lines[i], 2, y)
+		y = y - consoleLineHeight
+	}
+}
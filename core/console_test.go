@@ -0,0 +1,18 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"testing"
+)
+
+func TestConsoleRunsTypedCommand(t *testing.T) {
+	game, input := newTestGame("level-alpha")
+	input.Press(ebiten.KeyBackspace).Press(consoleToggleKey).Type("give ability wall-jump").Press(ebiten.KeyEnter)
+	step(t, game, input.Frames())
+	if !game.console.isOpen {
+		t.Fatal("backspace then the toggle key did not open the console")
+	}
+	if !game.Player.HasAbility(abilityWallJump) {
+		t.Errorf("the typed command did not run, the console has %q", game.console.input)
+	}
+}
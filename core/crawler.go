@@ -2,119 +2,113 @@ package core
 
 import (
 	"github.com/hajimehoshi/ebiten/v2"
-	"image/color"
-	"math"
 	"platformer/common"
 )
 
-const hurtAmountTime = 0.4
-
 type CrawlerEnemy struct {
 	x                float64
 	y                float64
-	sizeX            float64
+	def              *EnemyDefinition
 	currentAnimation string
 	animations       map[string]*Animation
 	health           int
 	// ai
-	directionX int
-	targetX    float64
-	targetY    float64
-	moveSpeed  float64
-	hurtTimer  float64
+	directionX     int
+	moveSpeed      float64
+	velocityX      float64
+	velocityY      float64
+	touchingGround bool
+	hurtTimer      float64
+	standingOn     *common.TileData
 }
 
-func NewCrawlerEnemy(x float64, y float64, game *Game) *CrawlerEnemy {
+// crawlerLedgeDistance is how far under its feet a crawler looks for floor, it is pulled down
+// onto floor that is closer than this so it follows slopes.
+const crawlerLedgeDistance = common.TileSize / 2
+
+func NewCrawlerEnemy(x float64, y float64, def *EnemyDefinition, game *Game) *CrawlerEnemy {
 	return &CrawlerEnemy{
 		x:                x,
 		y:                y,
-		sizeX:            24,
+		def:              def,
 		currentAnimation: "run",
-		animations: map[string]*Animation{
-			"run": {
-				image:           game.res.GetImage("crawler-run"),
-				numFrames:       5,
-				size:            24,
-				frameTimeAmount: 0.1,
-				isLoop:          true,
-			},
-			"idle": {
-				image:           game.res.GetImage("crawler-idle"),
-				numFrames:       2,
-				size:            24,
-				frameTimeAmount: 0.4,
-				isLoop:          true,
-			},
-			"hurt": {
-				image:           game.res.GetImage("crawler-hurt"),
-				numFrames:       2,
-				size:            24,
-				frameTimeAmount: 0.1,
-				isLoop:          true,
-			},
-		},
-		health:     1,
-		directionX: 1,
-		moveSpeed:  48,
+		animations:       def.newAnimations(game),
+		health:           def.Health,
+		directionX:       1,
+		moveSpeed:        def.Speed,
+		standingOn:       common.EmptyTile,
 	}
 }
 
 func (r *CrawlerEnemy) Update(delta float64, game *Game) {
-	if common.Overlap(game.Player.x+4, game.Player.y+8, 8, 8, r.x+2, r.y+2, 12, 12) {
-		game.Player.TakeDamage(game)
-		r.GetHurt(game)
+	cb, pb := r.GetCollisionBox(), game.Player.GetCollisionBox()
+	if common.Overlap(pb.x, pb.y, pb.w, pb.h, cb.x, cb.y, cb.w, cb.h) {
+		game.Player.TakeDamage(r.def.contactDamage(cb), game)
+		r.GetHurt(game, DamageInfo{
+			Amount:  1,
+			Type:    damageTypeContact,
+			SourceX: game.Player.x + (game.Player.sizex / 2),
+			SourceY: game.Player.y + (game.Player.sizey / 2),
+		})
 	}
 	r.currentAnimation = "idle"
 	if r.hurtTimer > 0 {
 		r.currentAnimation = "hurt"
 		r.hurtTimer = r.hurtTimer - delta
 	} else {
-		if math.Abs(r.x-r.targetX) < (r.moveSpeed * delta) {
-			r.x = r.targetX
-			r.currentAnimation = "run"
-		}
-		if r.x < r.targetX {
-			r.x = r.x + (r.moveSpeed * delta)
-			r.currentAnimation = "run"
-		}
-		if r.x > r.targetX {
-			r.x = r.x - (r.moveSpeed * delta)
-			r.currentAnimation = "run"
-		}
+		r.crawl(delta, game)
 	}
 	r.animations[r.currentAnimation].Update(delta)
-	// thinking
-	r.think(game)
 }
 
-func (r *CrawlerEnemy) think(game *Game) {
-
-	cb := r.GetCollisionBox()
-
-	posX := cb.x
-	if r.directionX > 0 {
-		posX = cb.x + cb.w
+// crawl moves along the floor, turning back at walls, hazards and ledges. Crawlers slide on
+// ice, are carried by conveyors and are thrown up by springs the same as the player is.
+func (r *CrawlerEnemy) crawl(delta float64, game *Game) {
+	cb := r.feetBox()
+	options := CollisionOptions{avoidHazards: true, platformWalls: true, groundSnap: r.touchingGround}
+	acc := surfaceAcc(r.def.acceleration(r.moveSpeed, delta), r.standingOn, r.touchingGround)
+	r.velocityX = approach(r.velocityX, float64(r.directionX)*r.moveSpeed, acc)
+	moveX := surfaceMoveX(r.velocityX, r.standingOn, delta)
+	moveY := crawlerLedgeDistance
+	if r.touchingGround {
+		edge := CollisionBox{x: cb.x + moveX, y: cb.y, w: 1, h: cb.h}
+		if moveX > 0 {
+			edge.x = cb.x + cb.w + moveX - 1
+		}
+		if !HasFloorBelow(edge, crawlerLedgeDistance, game.Level, options) {
+			// hold on at the edge, a conveyor can be pushing it the other way
+			if (moveX > 0) == (r.directionX > 0) {
+				r.turn()
+			}
+			return
+		}
+	} else {
+		gravity := game.Level.physics.gravity(0)
+		moveY = -((r.velocityY * delta) + (0.5 * gravity * delta * delta))
+		r.velocityY = r.velocityY + (gravity * delta)
 	}
-
-	tx, ty := int((posX)/common.TileSize), int(r.y/common.TileSize)
-	game.debug.DrawBox(color.RGBA{R: 244, G: 12, B: 9, A: 244}, float64(tx*common.TileSize), float64(ty*common.TileSize), common.TileSize, common.TileSize)
-
-	td := game.Level.tiledGrid.GetTileData(tx, ty)
-	if td.Block || td.Damage || td.Platform {
-		r.directionX = r.directionX * -1
-		return
+	cr := DoCollision(cb, moveX, moveY, game.Level, options)
+	r.x = cr.newX - r.def.Hitbox.X
+	r.y = cr.newY - r.def.Hitbox.Y
+	r.touchingGround = cr.hitFloor
+	r.standingOn = cr.floorTile()
+	r.currentAnimation = "run"
+	if cr.hitFloor || cr.hitCeiling {
+		r.velocityY = 0
 	}
-
-	// check tile below
-	tx, ty = int((posX)/common.TileSize), int((r.y/common.TileSize)+1)
-	td = game.Level.tiledGrid.GetTileData(tx, ty)
-	game.debug.DrawBox(color.RGBA{R: 120, G: 12, B: 44, A: 244}, float64(tx*common.TileSize), float64(ty*common.TileSize), common.TileSize, common.TileSize)
-	if td.Block || td.Platform {
-		r.targetX = float64(tx*common.TileSize) + float64(r.directionX*common.TileSize)
-		return
+	if bounce, bounced := surfaceBounce(cr, r.velocityY); bounced {
+		r.velocityY = bounce
+		r.touchingGround = false
+	}
+	if cr.hitWall {
+		r.turn()
 	}
+}
 
+// turn goes back the way the crawler came, it stops dead rather than sliding off a ledge.
+func (r *CrawlerEnemy) turn() {
 	r.directionX = r.directionX * -1
+	r.velocityX = 0
 }
 
 func (r *CrawlerEnemy) Draw(camera common.Camera) {
@@ -122,30 +116,59 @@ func (r *CrawlerEnemy) Draw(camera common.Camera) {
 	op := &ebiten.DrawImageOptions{}
 	if r.directionX > 0 {
 		op.GeoM.Scale(-1, 1)
-		op.GeoM.Translate(r.sizeX, 0)
+		op.GeoM.Translate(r.def.DrawSize, 0)
 	}
-	op.GeoM.Translate(r.x-4, r.y-8)
+	op.GeoM.Translate(r.x+r.def.DrawOffsetX, r.y+r.def.DrawOffsetY)
 	op.GeoM.Scale(common.Scale, common.Scale)
+	r.def.applyTint(op)
 	camera.DrawImage(r.animations[r.currentAnimation].GetCurrentFrame(), op)
 }
 
-func (r *CrawlerEnemy) GetHurt(game *Game) {
-	r.health = r.health - 1
-	r.hurtTimer = hurtAmountTime
+// GetHurt ignores knockback, crawlers cling to whatever they are crawling along.
+func (r *CrawlerEnemy) GetHurt(game *Game, damage DamageInfo) {
+	if r.health <= 0 {
+		return
+	}
+	r.health = r.health - damage.Amount
+	r.hurtTimer = r.def.HurtTime
 	r.animations["hurt"].Play()
-	game.SpawnEffect(effectCrawlerSpray, r.x-8, r.y-8, r.directionX > 0, 0)
-	if r.health == 0 {
-		game.SpawnEffect(effectCrawlerDeath, r.x-8, r.y-8, r.directionX > 0, 0)
+	game.Feedback(hurtFeedback(damage))
+	if r.def.HurtEffect != "" {
+		game.SpawnEffect(r.def.HurtEffect, r.x-8, r.y-8, r.directionX > 0, 0)
+	}
+	if r.health <= 0 {
+		game.SpawnEffect(r.def.DeathEffect, r.x-8, r.y-8, r.directionX > 0, 0)
 		game.Level.RemoveEnemy(r)
 		// play effect
 	}
 }
 
+func (r *CrawlerEnemy) describeDebug(game *Game) debugInfo {
+	info := debugInfo{
+		state: "crawl",
+	}
+	if r.hurtTimer > 0 {
+		info.state = "hurt"
+	} else {
+		info.velocityX = r.velocityX
+		info.velocityY = -r.velocityY
+	}
+	return info
+}
+
+// feetBox is the hitbox stretched down to the bottom of the sprite, which is where the floor
+// the crawler clings to is.
+func (r *CrawlerEnemy) feetBox() CollisionBox {
+	cb := r.GetCollisionBox()
+	cb.h = r.y + r.def.DrawOffsetY + r.def.DrawSize - cb.y
+	return cb
+}
+
 func (r *CrawlerEnemy) GetCollisionBox() CollisionBox {
 	return CollisionBox{
-		x: r.x + 2,
-		y: r.y + 2,
-		w: 12,
-		h: 12,
+		x: r.x + r.def.Hitbox.X,
+		y: r.y + r.def.Hitbox.Y,
+		w: r.def.Hitbox.W,
+		h: r.def.Hitbox.H,
 	}
 }
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"platformer/common"
+)
+
+// crystalRespawnTime is how long a used crystal stays dim before it can refresh again.
+const crystalRespawnTime = 2.5
+
+// Crystal gives back the player's double jump when they touch it in the air, then goes dim
+// for a while.
+type Crystal struct {
+	x     float64
+	y     float64
+	image *ebiten.Image
+	timer float64
+}
+
+func NewCrystal(x, y float64, game *Game) *Crystal {
+	return &Crystal{
+		x:     x,
+		y:     y,
+		image: game.res.GetImage("refresh-crystal"),
+	}
+}
+
+func (r *Crystal) Update(delta float64, game *Game) {
+	if r.timer > 0 {
+		r.timer = r.timer - delta
+		return
+	}
+	pb := game.Player.GetCollisionBox()
+	if common.Overlap(pb.x, pb.y, pb.w, pb.h, r.x+2, r.y+2, 12, 12) && game.Player.RefreshDoubleJump() {
+		r.timer = crystalRespawnTime
+		game.SpawnEffect(effectDoubleJump, r.x+8, r.y+4, false, 0)
+	}
+}
+
+func (r *Crystal) Draw(camera common.Camera) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(r.x, r.y)
+	op.GeoM.Scale(common.Scale, common.Scale)
+	if r.timer > 0 {
+		op.ColorM.Scale(1, 1, 1, 0.3)
+	}
+	camera.DrawImage(r.image, op)
+}
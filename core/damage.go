@@ -0,0 +1,58 @@
+package core
+
+import (
+	"math"
+	"platformer/common"
+)
+
+const (
+	damageTypeContact    = "contact"
+	damageTypeTile       = "tile"
+	damageTypeSpell      = "spell"
+	damageTypeProjectile = "projectile"
+	damageTypeSlam       = "slam"
+	damageTypeDrown      = "drown"
+)
+
+const tileKnockback = 80.0
+const spellKnockback = 60.0
+const knockbackDrag = 400.0
+
+// DamageInfo describes a single hit, the knockback pushes whatever was hurt away from
+// the source position.
+type DamageInfo struct {
+	Amount    int
+	Type      string
+	SourceX   float64
+	SourceY   float64
+	Knockback float64
+}
+
+// directionFrom returns the direction pointing away from the source, hits that come from
+// exactly the same place push straight up.
+func (r DamageInfo) directionFrom(x, y float64) (float64, float64) {
+	dx, dy := x-r.SourceX, y-r.SourceY
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return 0, -1
+	}
+	return dx / length, dy / length
+}
+
+func newTileDamage(amount int, tx, ty int) DamageInfo {
+	return DamageInfo{
+		Amount:    amount,
+		Type:      damageTypeTile,
+		SourceX:   (float64(tx) + 0.5) * common.TileSize,
+		SourceY:   (float64(ty) + 0.5) * common.TileSize,
+		Knockback: tileKnockback,
+	}
+}
+
+// reduceKnockback slows a knockback velocity down towards zero.
+func reduceKnockback(velocity float64, delta float64) float64 {
+	if velocity > 0 {
+		return math.Max(velocity-(knockbackDrag*delta), 0)
+	}
+	return math.Min(velocity+(knockbackDrag*delta), 0)
+}
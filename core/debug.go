@@ -1,23 +1,98 @@
 package core
 
 import (
+	"fmt"
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"image/color"
+	"math"
 	"platformer/common"
+	"strconv"
+	"strings"
 )
 
+const (
+	debugBoxes    = "boxes"
+	debugProbes   = "probes"
+	debugTiles    = "tiles"
+	debugCamera   = "camera"
+	debugAI       = "ai"
+	debugVelocity = "velocity"
+	debugNav      = "nav"
+	debugStats    = "stats"
+	debugPhysics  = "physics"
+)
+
+// debugCategories are in the order of the number keys that toggle them.
+var debugCategories = []string{debugBoxes, debugProbes, debugTiles, debugCamera, debugAI, debugVelocity, debugNav, debugStats, debugPhysics}
+
+var debugCategoryKeys = []ebiten.Key{ebiten.Key1, ebiten.Key2, ebiten.Key3, ebiten.Key4, ebiten.Key5, ebiten.Key6, ebiten.Key7, ebiten.Key8, ebiten.Key9}
+
+// debugVelocityScale is how long a velocity line is, in seconds of movement.
+const debugVelocityScale = 0.25
+
+var (
+	debugPanelColor    = color.RGBA{R: 230, G: 225, B: 210, A: 200}
+	debugBoxColor      = color.RGBA{R: 40, G: 220, B: 240, A: 90}
+	debugPlayerColor   = color.RGBA{R: 20, G: 20, B: 20, A: 120}
+	debugColliderColor = color.RGBA{R: 240, G: 140, B: 20, A: 110}
+	debugProbeColor    = color.RGBA{R: 40, G: 240, B: 40, A: 255}
+	debugProbeHitColor = color.RGBA{R: 250, G: 30, B: 30, A: 255}
+	debugTargetColor   = color.RGBA{R: 250, G: 60, B: 200, A: 200}
+	debugVelocityColor = color.RGBA{R: 250, G: 250, B: 40, A: 230}
+	debugCameraColor   = color.RGBA{R: 60, G: 120, B: 250, A: 220}
+	debugRoomColor     = color.RGBA{R: 160, G: 90, B: 250, A: 220}
+	debugTileColors    = []struct {
+		matches func(td *common.TileData) bool
+		c       color.Color
+	}{
+		{func(td *common.TileData) bool { return td.Block }, color.RGBA{R: 70, G: 70, B: 160, A: 90}},
+		{func(td *common.TileData) bool { return td.Platform }, color.RGBA{G: 160, B: 60, A: 90}},
+		{func(td *common.TileData) bool { return td.Ladder }, color.RGBA{R: 180, G: 160, A: 90}},
+		{func(td *common.TileData) bool { return td.Damage }, color.RGBA{R: 200, A: 110}},
+		{func(td *common.TileData) bool { return td.Slope != nil }, color.RGBA{R: 60, G: 180, B: 200, A: 90}},
+	}
+)
+
+// DebugDrawer shows what the game is doing, toggled with backspace. What is drawn is split
+// into categories that the number keys turn on and off. Anything can add boxes, lines and
+// text every frame, in the world or on the screen, they are only kept while their category
+// is shown.
 type DebugDrawer struct {
-	boxes     []*debugBox
-	image     *ebiten.Image
-	showDebug bool
+	world      *debugLayer
+	screen     *debugLayer
+	image      *ebiten.Image
+	showDebug  bool
+	categories map[string]bool
 }
 
-func NewDebug() *DebugDrawer {
+type debugLayer struct {
+	boxes []*debugBox
+	lines []*debugLine
+	texts []*debugText
+}
+
+// NewDebug draws with the image, a 16 by 16 white square.
+func NewDebug(image *ebiten.Image) *DebugDrawer {
 	return &DebugDrawer{
-		boxes:     []*debugBox{},
+		world:     newDebugLayer(),
+		screen:    newDebugLayer(),
 		showDebug: false,
-		image:     common.LoadImage("debug-pixel.png"),
+		image:     image,
+		categories: map[string]bool{
+			debugBoxes:  true,
+			debugProbes: true,
+			debugAI:     true,
+			debugNav:    true,
+			debugStats:  true,
+		},
+	}
+}
+
+func newDebugLayer() *debugLayer {
+	return &debugLayer{
+		boxes: []*debugBox{},
+		lines: []*debugLine{},
+		texts: []*debugText{},
 	}
 }
 
@@ -29,35 +104,307 @@ type debugBox struct {
 	c color.Color
 }
 
+type debugLine struct {
+	x1 float64
+	y1 float64
+	x2 float64
+	y2 float64
+	c  color.Color
+}
+
+type debugText struct {
+	text string
+	x    float64
+	y    float64
+}
+
+// debugInfo is what an enemy is thinking, for the ai and velocity categories.
+type debugInfo struct {
+	state     string
+	hasTarget bool
+	targetX   float64
+	targetY   float64
+	velocityX float64
+	velocityY float64
+}
+
+// debugDescriber is implemented by enemies that can show their state, target and velocity.
+// Velocities are in pixels per second with y going down the screen.
+type debugDescriber interface {
+	describeDebug(game *Game) debugInfo
+}
+
 func (r *DebugDrawer) Update(delta float64, game *Game) {
-	if len(r.boxes) > 0 {
-		r.boxes = []*debugBox{}
+	r.world = newDebugLayer()
+	r.screen = newDebugLayer()
+	if game.IsTyping() {
+		return
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) {
+	if game.input.IsKeyJustPressed(ebiten.KeyBackspace) {
 		r.showDebug = !r.showDebug
 	}
+	if !r.showDebug {
+		return
+	}
+	for i, key := range debugCategoryKeys {
+		if game.input.IsKeyJustPressed(key) {
+			r.Toggle(debugCategories[i])
+		}
+	}
+}
+
+// IsShown is true when the category is being drawn, for skipping work that is only done for
+// the debug view.
+func (r *DebugDrawer) IsShown(category string) bool {
+	return r.showDebug && r.categories[category]
+}
+
+func (r *DebugDrawer) Toggle(category string) {
+	r.categories[category] = !r.categories[category]
 }
 
 func (r *DebugDrawer) Draw(camera common.Camera) {
 	if !r.showDebug {
 		return
 	}
+	r.world.draw(camera, r.image)
+}
+
+// DrawScreen draws what was added in screen space, over the top of the game.
+func (r *DebugDrawer) DrawScreen(screen *ebiten.Image) {
+	if !r.showDebug {
+		return
+	}
+	r.screen.draw(screen, r.image)
+}
+
+func (r *debugLayer) draw(drawable common.Drawable, img *ebiten.Image) {
 	for _, box := range r.boxes {
+		drawRect(drawable, img, box.c, box.x, box.y, box.w, box.h)
+	}
+	for _, line := range r.lines {
+		dx, dy := line.x2-line.x1, line.y2-line.y1
 		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(box.x, box.y)
+		op.GeoM.Scale(math.Hypot(dx, dy)/16.0, 1/16.0)
+		op.GeoM.Rotate(math.Atan2(dy, dx))
+		op.GeoM.Translate(line.x1, line.y1)
 		op.GeoM.Scale(common.Scale, common.Scale)
-		op.GeoM.Scale(box.w/16.0, box.h/16.0)
-		op.ColorM.ScaleWithColor(box.c)
-		camera.DrawImage(r.image, op)
+		op.ColorM.ScaleWithColor(line.c)
+		drawable.DrawImage(img, op)
+	}
+	for _, text := range r.texts {
+		lines := strings.Split(text.text, "\n")
+		w := 0
+		for _, line := range lines {
+			if lw := common.TextWidth(line); lw > w {
+				w = lw
+			}
+		}
+		drawRect(drawable, img, debugPanelColor, text.x-1, text.y-1, float64(w)+2, float64(len(lines)*6)+2)
+		common.DrawText(drawable, text.text, text.x, text.y)
+	}
+}
+
+func (r *DebugDrawer) DrawBox(category string, c color.Color, x, y, w, h float64) {
+	if r.IsShown(category) {
+		r.world.boxes = append(r.world.boxes, &debugBox{x: x, y: y, w: w, h: h, c: c})
+	}
+}
+
+func (r *DebugDrawer) DrawLine(category string, c color.Color, x1, y1, x2, y2 float64) {
+	if r.IsShown(category) {
+		r.world.lines = append(r.world.lines, &debugLine{x1: x1, y1: y1, x2: x2, y2: y2, c: c})
+	}
+}
+
+// DrawText puts a label in the world, with its top left at the position.
+func (r *DebugDrawer) DrawText(category string, text string, x, y float64) {
+	if r.IsShown(category) {
+		r.world.texts = append(r.world.texts, &debugText{text: text, x: x, y: y})
+	}
+}
+
+// DrawScreenBox is like DrawBox, in game pixels from the top left of the screen.
+func (r *DebugDrawer) DrawScreenBox(category string, c color.Color, x, y, w, h float64) {
+	if r.IsShown(category) {
+		r.screen.boxes = append(r.screen.boxes, &debugBox{x: x, y: y, w: w, h: h, c: c})
+	}
+}
+
+func (r *DebugDrawer) DrawScreenLine(category string, c color.Color, x1, y1, x2, y2 float64) {
+	if r.IsShown(category) {
+		r.screen.lines = append(r.screen.lines, &debugLine{x1: x1, y1: y1, x2: x2, y2: y2, c: c})
+	}
+}
+
+func (r *DebugDrawer) DrawScreenText(category string, text string, x, y float64) {
+	if r.IsShown(category) {
+		r.screen.texts = append(r.screen.texts, &debugText{text: text, x: x, y: y})
+	}
+}
+
+// drawRect fills a rectangle in game pixels with the 16 by 16 debug image, on the screen or in
+// the world.
+func drawRect(drawable common.Drawable, img *ebiten.Image, c color.Color, x, y, w, h float64) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(w/16.0, h/16.0)
+	op.GeoM.Translate(x, y)
+	op.GeoM.Scale(common.Scale, common.Scale)
+	op.ColorM.ScaleWithColor(c)
+	drawable.DrawImage(img, op)
+}
+
+// addOverlays adds everything the categories show about the game, once it has been updated.
+func (r *DebugDrawer) addOverlays(game *Game) {
+	if !r.showDebug {
+		return
+	}
+	if r.IsShown(debugTiles) {
+		r.addTileHeatmap(game)
+	}
+	if r.IsShown(debugBoxes) {
+		cb := game.Player.GetCollisionBox()
+		r.DrawBox(debugBoxes, debugPlayerColor, cb.x, cb.y, cb.w, cb.h)
+		for _, enemy := range game.Level.enemies {
+			cb := enemy.GetCollisionBox()
+			r.DrawBox(debugBoxes, debugBoxColor, cb.x, cb.y, cb.w, cb.h)
+		}
+		for _, c := range game.Level.GetColliders() {
+			cb := c.GetCollisionBox()
+			r.DrawBox(debugBoxes, debugColliderColor, cb.x, cb.y, cb.w, cb.h)
+		}
+		for _, p := range game.projectiles {
+			r.DrawBox(debugBoxes, debugBoxColor, p.x, p.y, p.w, p.h)
+		}
+		for _, p := range game.Level.pickups {
+			r.DrawBox(debugBoxes, debugBoxColor, p.x, p.y, common.TileSize, common.TileSize)
+		}
+		for _, c := range game.Level.crystals {
+			r.DrawBox(debugBoxes, debugBoxColor, c.x, c.y, common.TileSize, common.TileSize)
+		}
+	}
+	if r.IsShown(debugAI) || r.IsShown(debugVelocity) {
+		for _, enemy := range game.Level.enemies {
+			describer, ok := enemy.(debugDescriber)
+			if !ok {
+				continue
+			}
+			info := describer.describeDebug(game)
+			cb := enemy.GetCollisionBox()
+			cx, cy := cb.x+(cb.w/2), cb.y+(cb.h/2)
+			r.DrawText(debugAI, info.state, cb.x, cb.y-8)
+			if info.hasTarget {
+				r.DrawLine(debugAI, debugTargetColor, cx, cy, info.targetX, info.targetY)
+				r.DrawBox(debugAI, debugTargetColor, info.targetX-1, info.targetY-1, 3, 3)
+			}
+			r.drawVelocity(cx, cy, info.velocityX, info.velocityY)
+		}
+	}
+	if r.IsShown(debugVelocity) {
+		p := game.Player
+		r.drawVelocity(p.x+(p.sizex/2), p.y+(p.sizey/2), p.velocityX, -p.velocityY)
+		for _, s := range game.projectiles {
+			r.drawVelocity(s.x+(s.w/2), s.y+(s.h/2), s.moveX, s.moveY)
+		}
+	}
+	if r.IsShown(debugNav) {
+		for _, graph := range game.Level.navGraphs {
+			r.DrawNavGraph(graph)
+		}
+	}
+	if r.IsShown(debugCamera) {
+		game.Camera.drawDebug(r, game)
+	}
+	if r.IsShown(debugStats) {
+		r.addStats(game)
+	}
+	if r.IsShown(debugPhysics) {
+		game.physicsPanel.drawDebug(r, game)
+	}
+}
+
+func (r *DebugDrawer) drawVelocity(x, y, velocityX, velocityY float64) {
+	if velocityX == 0 && velocityY == 0 {
+		return
+	}
+	r.DrawLine(debugVelocity, debugVelocityColor, x, y, x+(velocityX*debugVelocityScale), y+(velocityY*debugVelocityScale))
+}
+
+// addTileHeatmap colours the tiles on screen by their properties.
+func (r *DebugDrawer) addTileHeatmap(game *Game) {
+	cx, cy := game.Camera.GetPos()
+	fromX, fromY := int(cx/common.TileSize), int(cy/common.TileSize)
+	toX, toY := int((cx+common.ScreenWidth)/common.TileSize), int((cy+common.ScreenHeight)/common.TileSize)
+	for ty := fromY; ty <= toY; ty++ {
+		for tx := fromX; tx <= toX; tx++ {
+			td := game.Level.tiledGrid.GetTileData(tx, ty)
+			for _, tc := range debugTileColors {
+				if tc.matches(td) {
+					r.DrawBox(debugTiles, tc.c, float64(tx*common.TileSize), float64(ty*common.TileSize), common.TileSize, common.TileSize)
+				}
+			}
+		}
+	}
+}
+
+// addStats puts the numbers panel in the top left of the screen, with the categories and the
+// keys that toggle them below it.
+func (r *DebugDrawer) addStats(game *Game) {
+	p := game.Player
+	stats := []string{
+		fmt.Sprintf("fps %.1f  tps %.1f", ebiten.CurrentFPS(), ebiten.CurrentTPS()),
+		fmt.Sprintf("enemies %v  projectiles %v", len(game.Level.enemies), len(game.projectiles)),
+		fmt.Sprintf("effects %v  rays %v  pickups %v", len(game.effectSprites), len(game.spellRays), len(game.Level.pickups)),
+		fmt.Sprintf("player %v %v", p.state, p.currentAnimation),
+		fmt.Sprintf("health %v/%v  air %.1f", p.Health, p.MaxHealth, p.airUsed),
+		fmt.Sprintf("pos %.0f %.0f  vel %.0f %.0f", p.x, p.y, p.velocityX, p.velocityY),
+	}
+	categories := []string{}
+	for i, category := range debugCategories {
+		state := "-"
+		if r.categories[category] {
+			state = "+"
+		}
+		categories = append(categories, strconv.Itoa(i+1)+" "+category+" "+state)
+	}
+	r.DrawScreenText(debugStats, strings.Join(stats, "\n"), 4, 4)
+	r.DrawScreenText(debugStats, strings.Join(categories, "\n"), 4, 48)
+}
+
+var navLinkColors = map[string]color.Color{
+	common.NavWalk: color.RGBA{G: 200, A: 200},
+	common.NavDrop: color.RGBA{B: 220, A: 200},
+	common.NavJump: color.RGBA{R: 230, G: 200, A: 200},
+}
+
+func (r *DebugDrawer) DrawNavGraph(graph *common.NavGraph) {
+	for _, n := range graph.Nodes {
+		x, y := float64(n.X*common.TileSize), float64(n.Y*common.TileSize)
+		r.DrawBox(debugNav, color.RGBA{G: 120, A: 120}, x+6, y+12, 4, 4)
+		for _, link := range n.Links {
+			r.DrawLine(debugNav, navLinkColors[link.Kind], x+8, y+14, float64(link.To.X*common.TileSize)+8, float64(link.To.Y*common.TileSize)+14)
+		}
 	}
 }
 
-func (r *DebugDrawer) DrawBox(c color.Color, x, y, w, h float64) {
-	r.boxes = append(r.boxes, &debugBox{
-		x: x,
-		y: y,
-		w: w,
-		h: h,
-		c: c,
+// registerDebugCommands lets the console turn categories on and off by name.
+func (r *DebugDrawer) registerDebugCommands(game *Game) {
+	game.RegisterCommand(&ConsoleCommand{
+		Name: "debug",
+		Help: "debug CATEGORY shows or hides part of the debug view",
+		Run: func(args []string, game *Game) string {
+			if len(args) != 1 || !contains(debugCategories, args[0]) {
+				return "categories: " + strings.Join(debugCategories, " ")
+			}
+			r.showDebug = true
+			r.Toggle(args[0])
+			return args[0] + " " + onOff(r.categories[args[0]])
+		},
+		Complete: func(args []string, game *Game) []string {
+			if len(args) > 0 {
+				return nil
+			}
+			return debugCategories
+		},
 	})
 }
@@ -0,0 +1,882 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+	"platformer/common"
+	"strconv"
+)
+
+const (
+	editorTileMode     = "tiles"
+	editorObjectMode   = "objects"
+	editorCameraSpeed  = 200.0
+	editorSnap         = 8
+	editorPaletteX     = 8.0
+	editorPaletteY     = 8.0
+	editorPaletteTile  = 8.0
+	editorPanelWidth   = 100.0
+	editorLineHeight   = 8.0
+	editorMessageTime  = 2.0
+	editorStatusHeight = 10.0
+)
+
+// editorObjectKinds are the objects that can be placed, in the order they are cycled through.
+var editorObjectKinds = []string{spawnObject, exitObject, "crawler", "blob", healthPickup, bookPickup, abilityPickup, signObject, flimsyObject, waterObject, crystalObject, cameraRoomObject}
+
+// editorProperties are offered for editing on each kind of object, even before they are set.
+var editorProperties = map[string][]string{
+	exitObject:       {"next-level"},
+	healthPickup:     {"amount"},
+	bookPickup:       {"title", "spell"},
+	abilityPickup:    {"ability"},
+	signObject:       {"text"},
+	cameraRoomObject: {"transition"},
+	"crawler":        {"health", "speed", "contact-damage", "knockback"},
+	"blob":           {"health", "speed", "ground-speed", "contact-damage", "knockback", "jump-height"},
+}
+
+// editorPropertyTypes are the Tiled types the level loader reads each property as, what is
+// typed in has to fit the type.
+var editorPropertyTypes = map[string]string{
+	"next-level":         "string",
+	"amount":             "int",
+	"title":              "string",
+	"spell":              "string",
+	"ability":            "string",
+	"text":               "string",
+	"transition":         "string",
+	"health":             "int",
+	"speed":              "float",
+	"ground-speed":       "float",
+	"contact-damage":     "int",
+	"knockback":          "float",
+	"view-distance-x":    "float",
+	"view-distance-y":    "float",
+	"jump-height":        "float",
+	"jump-time":          "float",
+	"time-between-jumps": "float",
+	"hurt-time":          "float",
+	"windup-time":        "float",
+	"fire-cooldown":      "float",
+	"projectile-speed":   "float",
+	"projectile-gravity": "float",
+	"projectile-damage":  "int",
+}
+
+// editorFields are the fields every object has, its custom properties are listed after them.
+var editorFields = []string{"x", "y", "width", "height"}
+
+var (
+	editorStatusColor   = color.RGBA{R: 230, G: 225, B: 210, A: 230}
+	editorHighlight     = color.RGBA{R: 250, G: 200, B: 80, A: 230}
+	editorTypingColor   = color.RGBA{R: 120, G: 200, B: 250, A: 230}
+	editorObjectColor   = color.RGBA{R: 80, G: 160, B: 250, A: 255}
+	editorSelectedColor = color.RGBA{R: 250, G: 200, B: 80, A: 255}
+	editorCursorColor   = color.RGBA{R: 255, G: 255, B: 255, A: 200}
+)
+
+// Editor changes the current level while the game is paused, toggled with F2 in debug mode.
+// Tiles in the ground layer are painted from the tileset, objects are placed, moved and their
+// properties edited, and the map is saved back to the level file it came from.
+type Editor struct {
+	isActive       bool
+	mode           string
+	tile           int
+	kind           int
+	showPalette    bool
+	waitForRelease bool
+	selected       *common.TiledObject
+	field          int
+	isTyping       bool
+	input          string
+	history        []editorEdit
+	future         []editorEdit
+	stroke         *tileEdit
+	isDragging     bool
+	dragOffsetX    int
+	dragOffsetY    int
+	dragFromX      int
+	dragFromY      int
+	message        string
+	messageTimer   float64
+	image          *ebiten.Image
+	// cursorX and cursorY are where the mouse was on the screen at the last update
+	cursorX float64
+	cursorY float64
+	// refs
+	grid *common.TiledGrid
+}
+
+func NewEditor(game *Game) *Editor {
+	r := &Editor{
+		mode:    editorTileMode,
+		history: []editorEdit{},
+		future:  []editorEdit{},
+		image:   game.res.GetImage("debug-pixel"),
+	}
+	game.RegisterCommand(&ConsoleCommand{
+		Name: "edit",
+		Help: "opens the level editor",
+		Run: func(args []string, game *Game) string {
+			game.console.close()
+			if !r.isActive {
+				r.open(game)
+			}
+			return ""
+		},
+	})
+	return r
+}
+
+func (r *Editor) Update(delta float64, game *Game) {
+	if r.messageTimer > 0 {
+		r.messageTimer = r.messageTimer - delta
+	}
+	if !game.debug.showDebug {
+		if r.isActive {
+			r.close(game)
+		}
+		return
+	}
+	if !r.isTyping && game.input.IsKeyJustPressed(ebiten.KeyF2) {
+		if r.isActive {
+			r.close(game)
+		} else {
+			r.open(game)
+		}
+	}
+	if !r.isActive {
+		return
+	}
+	r.cursorX, r.cursorY = screenCursor(game.input)
+	grid := game.Level.tiledGrid
+	r.grid = grid
+	if r.tile == 0 {
+		r.tile = grid.TileSet.FirstGid
+	}
+	if r.selected != nil && indexOfObject(grid, r.selected) < 0 {
+		r.deselect()
+	}
+	if r.isTyping {
+		r.updateTyping(game)
+		return
+	}
+	r.updateKeys(delta, game)
+	if r.waitForRelease {
+		if game.input.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+			return
+		}
+		r.waitForRelease = false
+	}
+	if r.mode == editorTileMode {
+		r.updateTiles(game)
+	} else {
+		r.updateObjects(game)
+	}
+}
+
+func (r *Editor) open(game *Game) {
+	r.isActive = true
+	game.Camera.Target(nil)
+	r.showMessage("editing " + game.Level.name)
+}
+
+func (r *Editor) close(game *Game) {
+	r.isActive = false
+	r.isTyping = false
+	r.isDragging = false
+	r.showPalette = false
+	r.finishStroke()
+	game.Camera.Target(game.Player)
+}
+
+func (r *Editor) updateKeys(delta float64, game *Game) {
+	isControl := game.input.IsKeyPressed(ebiten.KeyControl) || game.input.IsKeyPressed(ebiten.KeyMeta)
+	if isControl {
+		if game.input.IsKeyJustPressed(ebiten.KeyZ) {
+			if game.input.IsKeyPressed(ebiten.KeyShift) {
+				r.redo(game)
+			} else {
+				r.undo(game)
+			}
+		}
+		if game.input.IsKeyJustPressed(ebiten.KeyY) {
+			r.redo(game)
+		}
+		if game.input.IsKeyJustPressed(ebiten.KeyS) {
+			r.save(game)
+		}
+		return
+	}
+	if game.input.IsKeyPressed(ebiten.KeyA) {
+		game.Camera.x = game.Camera.x - (editorCameraSpeed * delta)
+	}
+	if game.input.IsKeyPressed(ebiten.KeyD) {
+		game.Camera.x = game.Camera.x + (editorCameraSpeed * delta)
+	}
+	if game.input.IsKeyPressed(ebiten.KeyW) {
+		game.Camera.y = game.Camera.y - (editorCameraSpeed * delta)
+	}
+	if game.input.IsKeyPressed(ebiten.KeyS) {
+		game.Camera.y = game.Camera.y + (editorCameraSpeed * delta)
+	}
+	if game.input.IsKeyJustPressed(ebiten.KeyTab) {
+		r.finishStroke()
+		r.isDragging = false
+		r.showPalette = false
+		if r.mode == editorTileMode {
+			r.mode = editorObjectMode
+		} else {
+			r.mode = editorTileMode
+		}
+	}
+}
+
+func (r *Editor) updateTiles(game *Game) {
+	grid := game.Level.tiledGrid
+	numTilesX, numTilesY := grid.GetTileSetSize()
+	numTiles := numTilesX * numTilesY
+	if game.input.IsKeyJustPressed(ebiten.KeyP) {
+		r.showPalette = !r.showPalette
+	}
+	if _, wheel := game.input.Wheel(); wheel != 0 && numTiles > 0 {
+		index := r.tile - grid.TileSet.FirstGid - int(math.Copysign(1, wheel))
+		r.tile = grid.TileSet.FirstGid + ((index + numTiles) % numTiles)
+	}
+	if r.showPalette {
+		if game.input.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+			sx, sy := screenCursor(game.input)
+			col := int(math.Floor((sx - editorPaletteX) / editorPaletteTile))
+			row := int(math.Floor((sy - editorPaletteY) / editorPaletteTile))
+			if col >= 0 && row >= 0 && col < numTilesX && row < numTilesY {
+				r.tile = grid.TileSet.FirstGid + (row * numTilesX) + col
+			}
+			r.showPalette = false
+			r.waitForRelease = true
+		}
+		return
+	}
+	mx, my := r.worldCursorAt(game.Camera)
+	tx, ty := int(math.Floor(mx/common.TileSize)), int(math.Floor(my/common.TileSize))
+	if game.input.IsMouseButtonJustPressed(ebiten.MouseButtonMiddle) {
+		if tileIndex := grid.GetTile(tx, ty); tileIndex != 0 {
+			r.tile = tileIndex
+		}
+	}
+	isPaint := game.input.IsMouseButtonPressed(ebiten.MouseButtonLeft)
+	isErase := game.input.IsMouseButtonPressed(ebiten.MouseButtonRight)
+	if !isPaint && !isErase {
+		r.finishStroke()
+		return
+	}
+	if r.stroke == nil {
+		r.stroke = &tileEdit{
+			changes: []*tileChange{},
+		}
+	}
+	tileIndex := r.tile
+	if isErase {
+		tileIndex = 0
+	}
+	if r.stroke.paint(grid, tx, ty, tileIndex) {
+		game.Level.tilesChanged()
+	}
+}
+
+// finishStroke adds the tiles painted since the mouse was pressed to the history as one edit.
+func (r *Editor) finishStroke() {
+	if r.stroke != nil && len(r.stroke.changes) > 0 {
+		r.push(r.stroke)
+	}
+	r.stroke = nil
+}
+
+func (r *Editor) updateObjects(game *Game) {
+	grid := game.Level.tiledGrid
+	_, wheel := game.input.Wheel()
+	if game.input.IsKeyJustPressed(ebiten.KeyE) || wheel < 0 {
+		r.kind = (r.kind + 1) % len(editorObjectKinds)
+	}
+	if game.input.IsKeyJustPressed(ebiten.KeyQ) || wheel > 0 {
+		r.kind = (r.kind + len(editorObjectKinds) - 1) % len(editorObjectKinds)
+	}
+	if r.selected != nil {
+		fields := getEditorFields(r.selected)
+		if game.input.IsKeyJustPressed(ebiten.KeyDown) {
+			r.field = (r.field + 1) % len(fields)
+		}
+		if game.input.IsKeyJustPressed(ebiten.KeyUp) {
+			r.field = (r.field + len(fields) - 1) % len(fields)
+		}
+		if r.field >= len(fields) {
+			r.field = 0
+		}
+		if game.input.IsKeyJustPressed(ebiten.KeyEnter) {
+			r.isTyping = true
+			r.input = getEditorField(r.selected, fields[r.field])
+			return
+		}
+		if game.input.IsKeyJustPressed(ebiten.KeyDelete) {
+			r.removeObject(game, r.selected)
+			return
+		}
+	}
+	mx, my := r.worldCursorAt(game.Camera)
+	if r.isDragging && r.selected != nil {
+		r.selected.X = snap(mx - float64(r.dragOffsetX))
+		r.selected.Y = snap(my - float64(r.dragOffsetY))
+		if !game.input.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+			r.isDragging = false
+			if r.selected.X != r.dragFromX || r.selected.Y != r.dragFromY {
+				r.push(&moveEdit{
+					object: r.selected,
+					fromX:  r.dragFromX,
+					fromY:  r.dragFromY,
+					toX:    r.selected.X,
+					toY:    r.selected.Y,
+				})
+				game.rebuildLevel(grid)
+			}
+		}
+		return
+	}
+	if game.input.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+		if obj := objectAt(grid, mx, my); obj != nil {
+			r.removeObject(game, obj)
+		}
+		return
+	}
+	if !game.input.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		return
+	}
+	obj := objectAt(grid, mx, my)
+	if obj == nil {
+		name := editorObjectKinds[r.kind]
+		w, h := int(common.TileSize), int(common.TileSize)
+		if name == exitObject {
+			h = h * 2
+		}
+		obj = grid.NewObject(name, snap(mx-float64(w/2)), snap(my-float64(h/2)), w, h)
+		edit := &objectEdit{
+			object: obj,
+			index:  len(grid.GetObjects()),
+		}
+		edit.redo(game)
+		r.push(edit)
+	}
+	if r.selected != obj {
+		r.field = 0
+	}
+	r.selected = obj
+	r.isDragging = true
+	r.dragFromX, r.dragFromY = obj.X, obj.Y
+	r.dragOffsetX, r.dragOffsetY = int(mx)-obj.X, int(my)-obj.Y
+}
+
+func (r *Editor) removeObject(game *Game, obj *common.TiledObject) {
+	if obj.Name == spawnObject && countObjects(game.Level.tiledGrid, spawnObject) == 1 {
+		r.showMessage("the level needs a spawn")
+		return
+	}
+	edit := &objectEdit{
+		object:   obj,
+		index:    indexOfObject(game.Level.tiledGrid, obj),
+		isRemove: true,
+	}
+	edit.redo(game)
+	r.push(edit)
+	if r.selected == obj {
+		r.deselect()
+	}
+}
+
+// deselect lets go of the selected object, along with any drag of it.
+func (r *Editor) deselect() {
+	r.selected = nil
+	r.isDragging = false
+}
+
+func (r *Editor) updateTyping(game *Game) {
+	r.input = string(game.input.AppendInputChars([]rune(r.input)))
+	if game.input.IsKeyJustPressed(ebiten.KeyBackspace) && len(r.input) > 0 {
+		runes := []rune(r.input)
+		r.input = string(runes[:len(runes)-1])
+	}
+	if game.input.IsKeyJustPressed(ebiten.KeyEscape) {
+		r.isTyping = false
+		return
+	}
+	if !game.input.IsKeyJustPressed(ebiten.KeyEnter) {
+		return
+	}
+	r.isTyping = false
+	fields := getEditorFields(r.selected)
+	if r.field >= len(fields) {
+		return
+	}
+	name := fields[r.field]
+	from := getEditorField(r.selected, name)
+	if r.input == from {
+		return
+	}
+	edit := &fieldEdit{
+		object: r.selected,
+		name:   name,
+		from:   from,
+		to:     r.input,
+	}
+	if err := setEditorField(r.selected, name, r.input); err != nil {
+		r.showMessage(err.Error())
+		return
+	}
+	r.push(edit)
+	game.rebuildLevel(game.Level.tiledGrid)
+}
+
+// push adds an edit that has already been made to the history, anything that was undone
+// can no longer be redone.
+func (r *Editor) push(edit editorEdit) {
+	r.history = append(r.history, edit)
+	r.future = []editorEdit{}
+}
+
+func (r *Editor) undo(game *Game) {
+	r.finishStroke()
+	if len(r.history) == 0 {
+		r.showMessage("nothing to undo")
+		return
+	}
+	edit := r.history[len(r.history)-1]
+	r.history = r.history[:len(r.history)-1]
+	edit.undo(game)
+	r.future = append(r.future, edit)
+}
+
+func (r *Editor) redo(game *Game) {
+	if len(r.future) == 0 {
+		r.showMessage("nothing to redo")
+		return
+	}
+	edit := r.future[len(r.future)-1]
+	r.future = r.future[:len(r.future)-1]
+	edit.redo(game)
+	r.history = append(r.history, edit)
+}
+
+func (r *Editor) save(game *Game) {
+	r.finishStroke()
+	if err := game.Level.tiledGrid.Save(); err != nil {
+		r.showMessage("could not save " + err.Error())
+		fmt.Println("saving level", err)
+		return
+	}
+	// the level already has the changes, there is no need to load it again
+	game.hotReloader.skipChanges()
+	r.showMessage("saved " + game.Level.name)
+	fmt.Println("saved Level ", game.Level.name)
+}
+
+func (r *Editor) showMessage(message string) {
+	r.message = message
+	r.messageTimer = editorMessageTime
+}
+
+// Draw shows the objects and the tile under the cursor in the world.
+func (r *Editor) Draw(camera common.Camera) {
+	if !r.isActive {
+		return
+	}
+	mx, my := r.worldCursorAt(camera)
+	if r.mode == editorTileMode {
+		if r.showPalette {
+			return
+		}
+		tx, ty := math.Floor(mx/common.TileSize)*common.TileSize, math.Floor(my/common.TileSize)*common.TileSize
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(tx, ty)
+		op.GeoM.Scale(common.Scale, common.Scale)
+		op.ColorM.Scale(1, 1, 1, 0.6)
+		camera.DrawImage(r.grid.GetTileImage(r.tile), op)
+		r.strokeRect(camera, editorCursorColor, tx, ty, common.TileSize, common.TileSize)
+		return
+	}
+	for _, obj := range r.grid.GetObjects() {
+		c := editorObjectColor
+		if obj == r.selected {
+			c = editorSelectedColor
+		}
+		x, y := float64(obj.X), float64(obj.Y)
+		r.strokeRect(camera, c, x, y, float64(obj.Width), float64(obj.Height))
+		common.DrawText(camera, obj.Name, x, y-7)
+	}
+}
+
+// DrawUI shows the status line, the tile palette and the fields of the selected object.
+func (r *Editor) DrawUI(screen *ebiten.Image) {
+	if !r.isActive {
+		return
+	}
+	status := r.mode
+	if r.mode == editorTileMode {
+		status = status + "  tile " + strconv.Itoa(r.tile) + "  p palette"
+	} else {
+		status = status + "  " + editorObjectKinds[r.kind] + "  q e kind  enter edit"
+	}
+	status = status + "  tab mode  ctrl z undo  ctrl s save"
+	if r.messageTimer > 0 {
+		status = r.message
+	}
+	statusY := common.ScreenHeight - editorStatusHeight
+	r.fillRect(screen, editorStatusColor, 0, statusY, common.ScreenWidth, editorStatusHeight)
+	common.DrawText(screen, status, 2, statusY+2)
+
+	if r.mode == editorTileMode && r.showPalette {
+		r.drawPalette(screen)
+	}
+	if r.mode == editorObjectMode && r.selected != nil {
+		r.drawFields(screen)
+	}
+}
+
+func (r *Editor) drawPalette(screen *ebiten.Image) {
+	tileSetImage := r.grid.GetTileSetImage()
+	w, h := float64(tileSetImage.Bounds().Dx()), float64(tileSetImage.Bounds().Dy())
+	scale := editorPaletteTile / common.TileSize
+	r.fillRect(screen, editorStatusColor, editorPaletteX-2, editorPaletteY-2, (w*scale)+4, (h*scale)+4)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(editorPaletteX, editorPaletteY)
+	op.GeoM.Scale(common.Scale, common.Scale)
+	screen.DrawImage(tileSetImage, op)
+	numTilesX := int(w / common.TileSize)
+	if numTilesX == 0 {
+		return
+	}
+	index := r.tile - r.grid.TileSet.FirstGid
+	x := editorPaletteX + (float64(index%numTilesX) * editorPaletteTile)
+	y := editorPaletteY + (float64(index/numTilesX) * editorPaletteTile)
+	r.strokeRect(screen, editorHighlight, x, y, editorPaletteTile, editorPaletteTile)
+}
+
+func (r *Editor) drawFields(screen *ebiten.Image) {
+	fields := getEditorFields(r.selected)
+	x := common.ScreenWidth - editorPanelWidth - 4
+	y := 4.0
+	r.fillRect(screen, editorStatusColor, x, y, editorPanelWidth, (float64(len(fields)+1)*editorLineHeight)+4)
+	common.DrawText(screen, r.selected.Name, x+2, y+2)
+	for i, name := range fields {
+		lineY := y + 2 + (float64(i+1) * editorLineHeight)
+		value := getEditorField(r.selected, name)
+		if i == r.field {
+			c := editorHighlight
+			if r.isTyping {
+				c = editorTypingColor
+				value = r.input
+			}
+			r.fillRect(screen, c, x, lineY-1, editorPanelWidth, editorLineHeight)
+		}
+		common.DrawText(screen, name+"  "+value, x+2, lineY)
+	}
+}
+
+func (r *Editor) fillRect(drawable common.Drawable, c color.Color, x, y, w, h float64) {
+	drawRect(drawable, r.image, c, x, y, w, h)
+}
+
+func (r *Editor) strokeRect(drawable common.Drawable, c color.Color, x, y, w, h float64) {
+	r.fillRect(drawable, c, x, y, w, 1)
+	r.fillRect(drawable, c, x, y+h-1, w, 1)
+	r.fillRect(drawable, c, x, y, 1, h)
+	r.fillRect(drawable, c, x+w-1, y, 1, h)
+}
+
+// screenCursor is the mouse position in game pixels on the screen.
+func screenCursor(input Input) (float64, float64) {
+	cx, cy := input.CursorPosition()
+	return float64(cx) / common.Scale, float64(cy) / common.Scale
+}
+
+// worldCursorAt is where the mouse was in the world at the last update, seen through the camera.
+func (r *Editor) worldCursorAt(camera common.Camera) (float64, float64) {
+	cx, cy := camera.GetPos()
+	return r.cursorX + cx, r.cursorY + cy
+}
+
+func snap(v float64) int {
+	return int(math.Floor(v/editorSnap)) * editorSnap
+}
+
+// objectAt returns the top most object under the point.
+func objectAt(grid *common.TiledGrid, x, y float64) *common.TiledObject {
+	objects := grid.GetObjects()
+	for i := len(objects) - 1; i >= 0; i-- {
+		obj := objects[i]
+		if common.Contains(float64(obj.X), float64(obj.Y), float64(obj.Width), float64(obj.Height), x, y) {
+			return obj
+		}
+	}
+	return nil
+}
+
+func indexOfObject(grid *common.TiledGrid, obj *common.TiledObject) int {
+	for i, o := range grid.GetObjects() {
+		if o == obj {
+			return i
+		}
+	}
+	return -1
+}
+
+func countObjects(grid *common.TiledGrid, name string) int {
+	count := 0
+	for _, o := range grid.GetObjects() {
+		if o.Name == name {
+			count++
+		}
+	}
+	return count
+}
+
+func getEditorFields(obj *common.TiledObject) []string {
+	fields := append([]string{}, editorFields...)
+	for _, prop := range obj.Properties {
+		fields = append(fields, prop.Name)
+	}
+	for _, name := range editorProperties[obj.Name] {
+		if getProperty(obj, name) == nil {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+func getProperty(obj *common.TiledObject, name string) *common.TileConfigProp {
+	for _, prop := range obj.Properties {
+		if prop.Name == name {
+			return prop
+		}
+	}
+	return nil
+}
+
+func getEditorField(obj *common.TiledObject, name string) string {
+	switch name {
+	case "x":
+		return strconv.Itoa(obj.X)
+	case "y":
+		return strconv.Itoa(obj.Y)
+	case "width":
+		return strconv.Itoa(obj.Width)
+	case "height":
+		return strconv.Itoa(obj.Height)
+	}
+	if prop := getProperty(obj, name); prop != nil && prop.Value != nil {
+		return fmt.Sprint(prop.Value)
+	}
+	return ""
+}
+
+// setEditorField changes a field of the object from what was typed, an empty value removes
+// the property. Properties keep the type the level loader reads them as, values that do not
+// fit it are refused.
+func setEditorField(obj *common.TiledObject, name string, value string) error {
+	var field *int
+	switch name {
+	case "x":
+		field = &obj.X
+	case "y":
+		field = &obj.Y
+	case "width":
+		field = &obj.Width
+	case "height":
+		field = &obj.Height
+	}
+	if field != nil {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New(name + " must be a whole number")
+		}
+		*field = n
+		return nil
+	}
+	prop := getProperty(obj, name)
+	if value == "" {
+		if prop != nil {
+			properties := []*common.TileConfigProp{}
+			for _, p := range obj.Properties {
+				if p != prop {
+					properties = append(properties, p)
+				}
+			}
+			obj.Properties = properties
+		}
+		return nil
+	}
+	propType, ok := editorPropertyTypes[name]
+	if !ok && prop != nil {
+		propType = prop.Type
+	}
+	propValue, err := parsePropertyValue(propType, value)
+	if err != nil {
+		return errors.New(name + " must be " + describePropertyType(propType))
+	}
+	if prop == nil {
+		prop = &common.TileConfigProp{
+			Name: name,
+		}
+		obj.Properties = append(obj.Properties, prop)
+	}
+	prop.Type = propType
+	prop.Value = propValue
+	return nil
+}
+
+// parsePropertyValue reads the value as the Tiled property type, numbers are float64 as
+// they are when the level is loaded.
+func parsePropertyValue(propType string, value string) (interface{}, error) {
+	switch propType {
+	case "string":
+		return value, nil
+	case "bool":
+		return strconv.ParseBool(value)
+	case "int":
+		n, err := strconv.Atoi(value)
+		return float64(n), err
+	case "float":
+		return strconv.ParseFloat(value, 64)
+	}
+	return nil, errors.New("unknown property type " + propType)
+}
+
+func describePropertyType(propType string) string {
+	switch propType {
+	case "int":
+		return "a whole number"
+	case "float":
+		return "a number"
+	case "bool":
+		return "true or false"
+	case "string":
+		return "text"
+	}
+	return "a known property"
+}
+
+// editorEdit is one change to the level that can be undone and redone.
+type editorEdit interface {
+	undo(game *Game)
+	redo(game *Game)
+}
+
+type tileChange struct {
+	x    int
+	y    int
+	from int
+	to   int
+}
+
+// tileEdit is every tile painted in one stroke of the mouse.
+type tileEdit struct {
+	changes []*tileChange
+}
+
+// paint sets the tile and remembers what was there before, it returns false when nothing changed.
+func (r *tileEdit) paint(grid *common.TiledGrid, x, y int, tileIndex int) bool {
+	from := grid.GetTile(x, y)
+	if from == tileIndex || !grid.SetTile(x, y, tileIndex) {
+		return false
+	}
+	for _, change := range r.changes {
+		if change.x == x && change.y == y {
+			change.to = tileIndex
+			return true
+		}
+	}
+	r.changes = append(r.changes, &tileChange{
+		x:    x,
+		y:    y,
+		from: from,
+		to:   tileIndex,
+	})
+	return true
+}
+
+func (r *tileEdit) undo(game *Game) {
+	for i := len(r.changes) - 1; i >= 0; i-- {
+		change := r.changes[i]
+		game.Level.tiledGrid.SetTile(change.x, change.y, change.from)
+	}
+	game.Level.tilesChanged()
+}
+
+func (r *tileEdit) redo(game *Game) {
+	for _, change := range r.changes {
+		game.Level.tiledGrid.SetTile(change.x, change.y, change.to)
+	}
+	game.Level.tilesChanged()
+}
+
+// objectEdit adds an object, or removes it when isRemove is set. Undoing a removal puts the
+// object back where it was in the layer.
+type objectEdit struct {
+	object   *common.TiledObject
+	index    int
+	isRemove bool
+}
+
+func (r *objectEdit) undo(game *Game) {
+	r.apply(game, !r.isRemove)
+}
+
+func (r *objectEdit) redo(game *Game) {
+	r.apply(game, r.isRemove)
+}
+
+func (r *objectEdit) apply(game *Game, isRemove bool) {
+	if isRemove {
+		game.Level.tiledGrid.RemoveObject(r.object)
+	} else {
+		game.Level.tiledGrid.InsertObject(r.index, r.object)
+	}
+	game.rebuildLevel(game.Level.tiledGrid)
+}
+
+type moveEdit struct {
+	object *common.TiledObject
+	fromX  int
+	fromY  int
+	toX    int
+	toY    int
+}
+
+func (r *moveEdit) undo(game *Game) {
+	r.object.X, r.object.Y = r.fromX, r.fromY
+	game.rebuildLevel(game.Level.tiledGrid)
+}
+
+func (r *moveEdit) redo(game *Game) {
+	r.object.X, r.object.Y = r.toX, r.toY
+	game.rebuildLevel(game.Level.tiledGrid)
+}
+
+// fieldEdit changes one field or property, the values are as they were typed.
+type fieldEdit struct {
+	object *common.TiledObject
+	name   string
+	from   string
+	to     string
+}
+
+func (r *fieldEdit) undo(game *Game) {
+	setEditorField(r.object, r.name, r.from)
+	game.rebuildLevel(game.Level.tiledGrid)
+}
+
+func (r *fieldEdit) redo(game *Game) {
+	setEditorField(r.object, r.name, r.to)
+	game.rebuildLevel(game.Level.tiledGrid)
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"platformer/common"
+	"testing"
+)
+
+func TestSetEditorFieldKeepsLoaderTypes(t *testing.T) {
+	obj := &common.TiledObject{Name: signObject}
+	if err := setEditorField(obj, "text", "42"); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := getProperty(obj, "text").Value.(string); !ok || value != "42" {
+		t.Errorf("sign text is %#v, want the string 42", getProperty(obj, "text").Value)
+	}
+
+	obj = &common.TiledObject{Name: healthPickup}
+	if err := setEditorField(obj, "amount", "lots"); err == nil {
+		t.Errorf("a health amount of lots was accepted")
+	}
+	if getProperty(obj, "amount") != nil {
+		t.Errorf("a refused value was stored")
+	}
+	if err := setEditorField(obj, "amount", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := getProperty(obj, "amount").Value.(float64); !ok || value != 2 {
+		t.Errorf("amount is %#v, want 2 as a float64", getProperty(obj, "amount").Value)
+	}
+
+	if err := setEditorField(obj, "made-up", "1"); err == nil {
+		t.Errorf("a property with no known type was accepted")
+	}
+}
+
+func TestEditorKeepsLastSpawn(t *testing.T) {
+	game, _ := newTestGame("level-alpha")
+	grid := game.Level.tiledGrid
+	var spawn *common.TiledObject
+	for _, obj := range grid.GetObjects() {
+		if obj.Name == spawnObject {
+			spawn = obj
+		}
+	}
+	game.editor.selected = spawn
+	game.editor.isDragging = true
+
+	game.editor.removeObject(game, spawn)
+	if indexOfObject(grid, spawn) < 0 {
+		t.Errorf("the last spawn was removed")
+	}
+
+	// a second spawn can go, and whatever is being dragged is let go of
+	second := grid.NewObject(spawnObject, 0, 0, common.TileSize, common.TileSize)
+	grid.InsertObject(-1, second)
+	game.editor.selected = second
+	game.editor.removeObject(game, second)
+	if indexOfObject(grid, second) >= 0 {
+		t.Errorf("the second spawn was not removed")
+	}
+	if game.editor.selected != nil || game.editor.isDragging {
+		t.Errorf("the removed object is still selected or dragged")
+	}
+}
+
+func TestRebuildWithoutSpawnKeepsOldSpawn(t *testing.T) {
+	game, _ := newTestGame("level-alpha")
+	grid := game.Level.tiledGrid
+	spawn := game.Level.spawn
+	for _, obj := range grid.GetObjects() {
+		if obj.Name == spawnObject {
+			grid.RemoveObject(obj)
+		}
+	}
+
+	game.rebuildLevel(grid)
+	if game.Level.spawn != spawn {
+		t.Errorf("spawn is %v, want the old one %v", game.Level.spawn, spawn)
+	}
+}
+
+func TestEditorReadsKeysFromGameInput(t *testing.T) {
+	game, input := newTestGame("level-alpha")
+	input.Press(ebiten.KeyBackspace).Press(ebiten.KeyF2).Press(ebiten.KeyTab)
+	step(t, game, input.Frames())
+	if !game.editor.isActive || game.editor.mode != editorObjectMode {
+		t.Fatal("backspace, F2 and tab did not open the editor on objects")
+	}
+
+	sign := game.Level.tiledGrid.NewObject(signObject, 0, 0, common.TileSize, common.TileSize)
+	game.Level.tiledGrid.InsertObject(-1, sign)
+	game.editor.selected = sign
+	// the text comes after the fields every object has
+	game.editor.field = len(editorFields)
+	input.Press(ebiten.KeyEnter).Type("hello").Press(ebiten.KeyEnter)
+	step(t, game, 3)
+	prop := getProperty(sign, "text")
+	if prop == nil {
+		t.Fatal("typing hello did not give the sign any text")
+	}
+	if value, _ := prop.Value.(string); value != "hello" {
+		t.Errorf("sign text is %#v after typing hello", prop.Value)
+	}
+}
@@ -0,0 +1,223 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"log"
+	"os"
+	"path/filepath"
+	"platformer/common"
+	"strings"
+)
+
+const enemyDefinitionsDirectory = "res/enemies/"
+
+// enemyAccelerationTime is how long a ground enemy takes to get up to speed on a normal floor,
+// about as long as the player takes.
+const enemyAccelerationTime = 0.08
+
+const (
+	crawlerBehavior = "crawler"
+	blobBehavior    = "blob"
+	spitterBehavior = "spitter"
+)
+
+type EnemyDefinition struct {
+	Name              string                          `json:"name"`
+	Behavior          string                          `json:"behavior"`
+	Health            int                             `json:"health"`
+	Speed             float64                         `json:"speed"`
+	GroundSpeed       float64                         `json:"ground-speed"`
+	ContactDamage     int                             `json:"contact-damage"`
+	Knockback         float64                         `json:"knockback"`
+	ViewDistanceX     float64                         `json:"view-distance-x"`
+	ViewDistanceY     float64                         `json:"view-distance-y"`
+	JumpHeight        float64                         `json:"jump-height"`
+	JumpTime          float64                         `json:"jump-time"`
+	TimeBetweenJumps  float64                         `json:"time-between-jumps"`
+	HurtTime          float64                         `json:"hurt-time"`
+	WindupTime        float64                         `json:"windup-time"`
+	FireCooldown      float64                         `json:"fire-cooldown"`
+	ProjectileSpeed   float64                         `json:"projectile-speed"`
+	ProjectileGravity float64                         `json:"projectile-gravity"`
+	ProjectileDamage  int                             `json:"projectile-damage"`
+	DrawSize          float64                         `json:"draw-size"`
+	DrawOffsetX       float64                         `json:"draw-offset-x"`
+	DrawOffsetY       float64                         `json:"draw-offset-y"`
+	Hitbox            HitboxDefinition                `json:"hitbox"`
+	Tint              []float64                       `json:"tint"`
+	DeathEffect       string                          `json:"death-effect"`
+	HurtEffect        string                          `json:"hurt-effect"`
+	Animations        map[string]*AnimationDefinition `json:"animations"`
+}
+
+type HitboxDefinition struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+}
+
+// AnimationDefinition plays the image's sprite sheet, or one tag of it, frame-time is only
+// set to play the frames at a different speed than the sheet.
+type AnimationDefinition struct {
+	Image     string  `json:"image"`
+	Tag       string  `json:"tag"`
+	FrameTime float64 `json:"frame-time"`
+	Loop      bool    `json:"loop"`
+}
+
+func LoadEnemyDefinitions() map[string]*EnemyDefinition {
+	definitions, err := loadEnemyDefinitions()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return definitions
+}
+
+// loadEnemyDefinitions reads every definition, returning what went wrong instead of stopping
+// the game so a half written file can be reloaded.
+func loadEnemyDefinitions() (map[string]*EnemyDefinition, error) {
+	definitions := map[string]*EnemyDefinition{}
+	files, err := filepath.Glob(filepath.Join(enemyDefinitionsDirectory, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("listing enemy definitions: %w", err)
+	}
+	for _, fileName := range files {
+		def, err := loadEnemyDefinition(fileName)
+		if err != nil {
+			return nil, err
+		}
+		definitions[def.Name] = def
+	}
+	return definitions, nil
+}
+
+func loadEnemyDefinition(fileName string) (*EnemyDefinition, error) {
+	definitionFile, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("opening enemy definition: %w", err)
+	}
+	defer definitionFile.Close()
+	def := &EnemyDefinition{}
+	jsonParser := json.NewDecoder(definitionFile)
+	if err = jsonParser.Decode(def); err != nil {
+		return nil, fmt.Errorf("parsing enemy definition %v: %w", fileName, err)
+	}
+	if def.Name == "" {
+		def.Name = strings.TrimSuffix(filepath.Base(fileName), ".json")
+	}
+	switch def.Behavior {
+	case crawlerBehavior, blobBehavior, spitterBehavior:
+	default:
+		return nil, fmt.Errorf("unknown enemy behavior %v in %v", def.Behavior, fileName)
+	}
+	return def, nil
+}
+
+// withOverrides returns a copy of the definition with any stats set on the Tiled object replacing the defaults.
+func (r *EnemyDefinition) withOverrides(properties []*common.ObjectProperty) *EnemyDefinition {
+	def := *r
+	for _, prop := range properties {
+		value, ok := prop.Value.(float64)
+		if !ok {
+			continue
+		}
+		switch prop.Name {
+		case "health":
+			def.Health = int(value)
+		case "speed":
+			def.Speed = value
+		case "ground-speed":
+			def.GroundSpeed = value
+		case "contact-damage":
+			def.ContactDamage = int(value)
+		case "knockback":
+			def.Knockback = value
+		case "view-distance-x":
+			def.ViewDistanceX = value
+		case "view-distance-y":
+			def.ViewDistanceY = value
+		case "jump-height":
+			def.JumpHeight = value
+		case "jump-time":
+			def.JumpTime = value
+		case "time-between-jumps":
+			def.TimeBetweenJumps = value
+		case "hurt-time":
+			def.HurtTime = value
+		case "windup-time":
+			def.WindupTime = value
+		case "fire-cooldown":
+			def.FireCooldown = value
+		case "projectile-speed":
+			def.ProjectileSpeed = value
+		case "projectile-gravity":
+			def.ProjectileGravity = value
+		case "projectile-damage":
+			def.ProjectileDamage = int(value)
+		}
+	}
+	return &def
+}
+
+func (r *EnemyDefinition) newAnimations(game *Game) map[string]*Animation {
+	animations := map[string]*Animation{}
+	for name, a := range r.Animations {
+		animations[name] = newTagAnimation(game, a.Image, a.Tag, a.Loop)
+		if a.FrameTime > 0 {
+			animations[name].withFrameTime(a.FrameTime)
+		}
+	}
+	return animations
+}
+
+// contactDamage is the damage done to the player for touching an enemy with the given collision box.
+func (r *EnemyDefinition) contactDamage(cb CollisionBox) DamageInfo {
+	return DamageInfo{
+		Amount:    r.ContactDamage,
+		Type:      damageTypeContact,
+		SourceX:   cb.x + (cb.w / 2),
+		SourceY:   cb.y + (cb.h / 2),
+		Knockback: r.Knockback,
+	}
+}
+
+func (r *EnemyDefinition) navProfile() common.NavProfile {
+	return common.NavProfile{
+		JumpHeight: r.JumpHeight,
+		JumpTime:   r.JumpTime,
+		Speed:      r.Speed,
+		Height:     r.Hitbox.H,
+	}
+}
+
+// followsPaths is true for enemies that chase the player through a nav graph.
+func (r *EnemyDefinition) followsPaths() bool {
+	return r.Behavior == blobBehavior
+}
+
+// acceleration is how much speed the enemy gains in an update on a normal floor.
+func (r *EnemyDefinition) acceleration(speed, delta float64) float64 {
+	return (speed / enemyAccelerationTime) * delta
+}
+
+func (r *EnemyDefinition) applyTint(op *ebiten.DrawImageOptions) {
+	if len(r.Tint) == 4 {
+		op.ColorM.Scale(r.Tint[0], r.Tint[1], r.Tint[2], r.Tint[3])
+	}
+}
+
+func NewEnemy(x float64, y float64, def *EnemyDefinition, game *Game) Enemy {
+	switch def.Behavior {
+	case crawlerBehavior:
+		return NewCrawlerEnemy(x, y, def, game)
+	case blobBehavior:
+		return NewBlobEnemy(x, y, def, game)
+	case spitterBehavior:
+		return NewSpitterEnemy(x, y, def, game)
+	}
+	log.Fatal("unknown enemy behavior ", def.Behavior, " for ", def.Name)
+	return nil
+}
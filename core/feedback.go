@@ -0,0 +1,79 @@
+package core
+
+import "math"
+
+const (
+	// traumaDecay is how much trauma wears off every second
+	traumaDecay = 1.2
+	// shakeDistance is how far the camera shakes at the most trauma, in pixels
+	shakeDistance = 6.0
+	// shakeSpeed is how quickly the shake changes direction
+	shakeSpeed = 40.0
+	// hitStopScale is how fast the game runs during a hit-stop
+	hitStopScale = 0.05
+)
+
+// Feedback is how hard something hit, asked for with Game.Feedback. Trauma shakes the camera,
+// it adds up over hits to at most 1 and wears off. HitStop slows the game almost to a stop
+// for that many seconds.
+type Feedback struct {
+	Trauma  float64
+	HitStop float64
+}
+
+var (
+	feedbackPlayerHurt = Feedback{Trauma: 0.5, HitStop: 0.08}
+	feedbackSpellHit   = Feedback{HitStop: 0.05}
+	feedbackEnemyDeath = Feedback{Trauma: 0.3}
+	feedbackBossDeath  = Feedback{Trauma: 1, HitStop: 0.3}
+	feedbackSlam       = Feedback{Trauma: 0.6}
+)
+
+// effectFeedback is felt whenever the effect is spawned, so every way an enemy can die shakes
+// the camera the same.
+var effectFeedback = map[string]Feedback{
+	effectCrawlerDeath: feedbackEnemyDeath,
+	effectBlobDeath:    feedbackEnemyDeath,
+}
+
+// Feedback shakes the camera and stops the game for a moment. With reduced motion on neither
+// happens.
+func (r *Game) Feedback(feedback Feedback) {
+	if r.reduceMotion {
+		return
+	}
+	r.Camera.AddTrauma(feedback.Trauma)
+	r.hitStopTimer = math.Max(r.hitStopTimer, feedback.HitStop)
+}
+
+// hitStop slows the update down while a hit-stop lasts, the hit-stop itself runs out at the
+// normal speed.
+func (r *Game) hitStop(delta float64) float64 {
+	if r.hitStopTimer <= 0 {
+		return delta
+	}
+	r.hitStopTimer = r.hitStopTimer - delta
+	return delta * hitStopScale
+}
+
+// SetReduceMotion turns screen shake and hit-stops off, for players they make uncomfortable.
+func (r *Game) SetReduceMotion(reduceMotion bool) {
+	r.reduceMotion = reduceMotion
+	if reduceMotion {
+		r.Camera.trauma = 0
+		r.hitStopTimer = 0
+	}
+}
+
+func (r *Game) ReduceMotion() bool {
+	return r.reduceMotion
+}
+
+// hurtFeedback is how an enemy being hurt feels, spells stop the game for a moment as they
+// connect. Deaths shake the camera through their effect.
+func hurtFeedback(damage DamageInfo) Feedback {
+	if damage.Type == damageTypeSpell {
+		return feedbackSpellHit
+	}
+	return Feedback{}
+}
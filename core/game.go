@@ -3,34 +3,70 @@ package core
 import (
 	"github.com/hajimehoshi/ebiten/v2"
 	"platformer/actions"
+	"platformer/common"
 	"platformer/res"
 )
 
 type Game struct {
-	Enabled        bool
-	Player         *Player
-	PlayerProgress *PlayerProgress
-	Camera         *Camera
-	Level          *Level
-	debug          *DebugDrawer
-	spellObjects   []*SpellObject
-	effectSprites  []*EffectSprite
+	Enabled          bool
+	Player           *Player
+	PlayerProgress   *PlayerProgress
+	Camera           *Camera
+	Level            *Level
+	debug            *DebugDrawer
+	hotReloader      *HotReloader
+	editor           *Editor
+	console          *Console
+	projectiles      []*Projectile
+	effectSprites    []*EffectSprite
+	spellRays        []*SpellRay
+	sounds           *common.SoundManager
+	enemyDefinitions map[string]*EnemyDefinition
+	physicsProfiles  map[string]*PhysicsProfile
+	physicsPanel     *PhysicsPanel
+	timeScale        float64
+	hitStopTimer     float64
+	reduceMotion     bool
+	godMode          bool
+	noClip           bool
+	input            Input
+	renderer         Renderer
 	// refs
 	res     *res.Resources
 	Actions actions.Actions
 }
 
 func NewGame(resources *res.Resources, actions actions.Actions) *Game {
+	r := newGame(resources, actions, keyboardInput{})
+	r.renderer = screenRenderer{}
+	r.LoadLevel("level-alpha")
+	return r
+}
+
+// newGame sets up everything but the level, without anything to draw it with.
+func newGame(resources *res.Resources, actions actions.Actions, input Input) *Game {
 	r := &Game{
-		debug:   NewDebug(),
-		res:     resources,
-		Enabled: true,
-		Actions: actions,
+		debug:            NewDebug(resources.GetImage("debug-pixel")),
+		hotReloader:      NewHotReloader(),
+		res:              resources,
+		Enabled:          true,
+		Actions:          actions,
+		sounds:           resources.GetSoundManager(),
+		enemyDefinitions: LoadEnemyDefinitions(),
+		physicsProfiles:  LoadPhysicsProfiles(),
+		physicsPanel:     NewPhysicsPanel(),
+		timeScale:        1,
+		input:            input,
 		PlayerProgress: &PlayerProgress{
-			spells: map[string]bool{},
+			spells:         map[string]bool{},
+			abilities:      map[string]bool{},
+			defeatedBosses: map[string]bool{},
 		},
 	}
-	r.LoadLevel("level-alpha")
+	r.console = NewConsole(r)
+	r.registerCommands()
+	r.debug.registerDebugCommands(r)
+	r.editor = NewEditor(r)
 	return r
 }
 
@@ -38,45 +74,70 @@ func (r *Game) Update(delta float64) error {
 	if !r.Enabled {
 		return nil
 	}
+	r.input.Update()
 	r.debug.Update(delta, r)
+	r.console.Update(delta, r)
+	if !r.console.isOpen {
+		r.editor.Update(delta, r)
+	}
+	if r.console.isOpen || r.editor.isActive {
+		// the level is paused while commands are typed or it is being edited
+		r.Camera.Update(delta, r)
+		r.debug.addOverlays(r)
+		return nil
+	}
+	r.hotReloader.Update(delta, r)
+	r.physicsPanel.Update(r)
+	delta = r.hitStop(delta * r.timeScale)
+	if r.Camera.IsScrolling() {
+		// everything waits while the camera scrolls into the next room
+		r.Camera.Update(delta, r)
+		r.debug.addOverlays(r)
+		return nil
+	}
 	r.Player.Update(delta, r)
 	r.Level.Update(delta, r)
 	r.Camera.Update(delta, r)
-	for _, s := range r.spellObjects {
+	for _, s := range r.projectiles {
 		s.Update(delta, r)
 	}
 	for _, e := range r.effectSprites {
 		e.Update(delta, r)
 	}
+	for _, s := range r.spellRays {
+		s.Update(delta, r)
+	}
+	r.debug.addOverlays(r)
 
 	return nil
 }
 
+// Draw draws the game with its renderer, a headless game has none and draws nothing.
 func (r *Game) Draw(screen *ebiten.Image) {
-	r.Level.Draw(r.Camera)
-	r.Player.Draw(r.Camera)
-	for _, s := range r.spellObjects {
-		s.Draw(r.Camera)
-	}
-	for _, e := range r.effectSprites {
-		e.Draw(r.Camera)
+	if r.renderer == nil {
+		return
 	}
-	r.debug.Draw(r.Camera)
-	r.Camera.DrawBuffer(screen)
+	r.renderer.Draw(screen, r)
+}
+
+// IsTyping is true while text is being typed into the game, so keys should not be used
+// for anything else.
+func (r *Game) IsTyping() bool {
+	return r.editor.isTyping || r.console.isOpen
 }
 
-func (r *Game) RemoveSpellObject(spellObject *SpellObject) {
-	newSpellObjs := []*SpellObject{}
-	for _, so := range r.spellObjects {
-		if so != spellObject {
-			newSpellObjs = append(newSpellObjs, so)
+func (r *Game) RemoveProjectile(projectile *Projectile) {
+	newProjectiles := []*Projectile{}
+	for _, p := range r.projectiles {
+		if p != projectile {
+			newProjectiles = append(newProjectiles, p)
 		}
 	}
-	r.spellObjects = newSpellObjs
+	r.projectiles = newProjectiles
 }
 
-func (r *Game) AddSpellObject(spellObject *SpellObject) {
-	r.spellObjects = append(r.spellObjects, spellObject)
+func (r *Game) AddProjectile(projectile *Projectile) {
+	r.projectiles = append(r.projectiles, projectile)
 }
 
 func (r *Game) RemoveEffectSprite(effectSprite *EffectSprite) {
@@ -93,96 +154,118 @@ func (r *Game) AddEffectSprite(effectSprite *EffectSprite) {
 	r.effectSprites = append(r.effectSprites, effectSprite)
 }
 
+func (r *Game) RemoveSpellRay(spellRay *SpellRay) {
+	newSpellRays := []*SpellRay{}
+	for _, s := range r.spellRays {
+		if s != spellRay {
+			newSpellRays = append(newSpellRays, s)
+		}
+	}
+	r.spellRays = newSpellRays
+}
+
+func (r *Game) AddSpellRay(spellRay *SpellRay) {
+	r.spellRays = append(r.spellRays, spellRay)
+}
+
 const (
 	effectSpellHit     = "effect-spell-hit"
 	effectCastSpell    = "effect-cast-spell"
 	effectCrawlerDeath = "effect-crawler-death"
 	effectCrawlerSpray = "effect-crawler-spray"
 	effectBlobDeath    = "effect-blob-death"
+	effectSplash       = "effect-splash"
+	effectDust         = "effect-dust"
+	effectDoubleJump   = "effect-double-jump"
 )
 
 func (r *Game) SpawnEffect(name string, x, y float64, isFlip bool, rot float64) {
+	r.PlaySoundAt(name, x, y)
+	if feedback, ok := effectFeedback[name]; ok {
+		r.Feedback(feedback)
+	}
 	switch name {
 	case effectCrawlerDeath:
 		r.AddEffectSprite(&EffectSprite{
-			x: x,
-			y: y,
-			w: 24,
-			h: 24,
-			animation: &Animation{
-				image:           r.res.GetImage("crawler-die"),
-				numFrames:       2,
-				size:            24,
-				frameTimeAmount: 0.2,
-				isLoop:          false,
-			},
-			isFlipX: isFlip,
+			x:         x,
+			y:         y,
+			w:         24,
+			h:         24,
+			animation: newAnimation(r, "crawler-die", false).withFrameTime(0.2),
+			isFlipX:   isFlip,
 		})
 	case effectCrawlerSpray:
 		r.AddEffectSprite(&EffectSprite{
-			x: x,
-			y: y,
-			w: 24,
-			h: 24,
-			animation: &Animation{
-				image:           r.res.GetImage("effect-crawler-spray"),
-				numFrames:       6,
-				size:            24,
-				frameTimeAmount: 0.1,
-				isLoop:          false,
-			},
+			x:           x,
+			y:           y,
+			w:           24,
+			h:           24,
+			animation:   newAnimation(r, "effect-crawler-spray", false),
 			isTemporary: true,
 			ttl:         0.6,
 			isFlipX:     isFlip,
 		})
 	case effectBlobDeath:
 		r.AddEffectSprite(&EffectSprite{
-			x: x,
-			y: y,
-			w: 32,
-			h: 32,
-			animation: &Animation{
-				image:           r.res.GetImage("blob-die"),
-				numFrames:       6,
-				size:            32,
-				frameTimeAmount: 0.1,
-				isLoop:          false,
-			},
+			x:           x,
+			y:           y,
+			w:           32,
+			h:           32,
+			animation:   newAnimation(r, "blob-die", false),
 			isTemporary: true,
 			ttl:         0.6,
 			isFlipX:     isFlip,
 		})
 	case effectSpellHit:
 		r.AddEffectSprite(&EffectSprite{
-			x: x - 4,
-			y: y - 4,
-			w: 24,
-			h: 24,
-			animation: &Animation{
-				image:           r.res.GetImage("effect-spell-hit"),
-				numFrames:       5,
-				size:            24,
-				frameTimeAmount: 0.1,
-				isLoop:          false,
-			},
+			x:           x - 4,
+			y:           y - 4,
+			w:           24,
+			h:           24,
+			animation:   newAnimation(r, "effect-spell-hit", false),
 			isTemporary: true,
 			ttl:         0.4,
 			isFlipX:     isFlip,
 		})
+	case effectSplash:
+		r.AddEffectSprite(&EffectSprite{
+			x:           x - 8,
+			y:           y - 14,
+			w:           16,
+			h:           16,
+			animation:   newAnimation(r, "effect-splash", false),
+			isTemporary: true,
+			ttl:         0.4,
+		})
+	case effectDust:
+		r.AddEffectSprite(&EffectSprite{
+			x:           x - 4,
+			y:           y - 4,
+			w:           8,
+			h:           8,
+			animation:   newAnimation(r, "effect-dust", false),
+			isTemporary: true,
+			ttl:         0.3,
+			isFlipX:     isFlip,
+		})
+	case effectDoubleJump:
+		r.AddEffectSprite(&EffectSprite{
+			x:           x - 8,
+			y:           y - 8,
+			w:           16,
+			h:           16,
+			animation:   newAnimation(r, "effect-double-jump", false),
+			isTemporary: true,
+			ttl:         0.3,
+		})
 	case effectCastSpell:
 		r.AddEffectSprite(&EffectSprite{
-			x:   x - 4,
-			y:   y - 4,
-			w:   24,
-			h:   24,
-			rot: rot,
-			animation: &Animation{
-				image:           r.res.GetImage("effect-cast-spell"),
-				numFrames:       9,
-				size:            24,
-				frameTimeAmount: 0.05,
-				isLoop:          false,
-			},
+			x:           x - 4,
+			y:           y - 4,
+			w:           24,
+			h:           24,
+			rot:         rot,
+			animation:   newAnimation(r, "effect-cast-spell", false).withFrameTime(0.05),
 			isTemporary: true,
 			ttl:         0.5,
 			isFlipX:     isFlip,
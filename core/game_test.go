@@ -0,0 +1,425 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"math"
+	"os"
+	"platformer/common"
+	"platformer/res"
+	"testing"
+)
+
+// testDelta is one update at the speed the game runs.
+const testDelta = 1.0 / 60
+
+// the spawn point of level-alpha and the floor under it
+const (
+	alphaSpawnX = 288.0
+	alphaFloorY = 296.0
+)
+
+// startingHealth is the health of a new game.
+const startingHealth = 6
+
+func TestMain(m *testing.M) {
+	// resources are loaded relative to the root of the repository
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestGame loads the level with no window, the player is controlled by the script.
+func newTestGame(level string) (*Game, *ScriptedInput) {
+	input := NewScriptedInput()
+	return NewHeadlessGame(res.NewHeadlessResources(), input, level), input
+}
+
+// step runs the game for a number of updates.
+func step(t *testing.T, game *Game, updates int) {
+	t.Helper()
+	for i := 0; i < updates; i++ {
+		if err := game.Update(testDelta); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// recordStates keeps every state the player changes to.
+func recordStates(game *Game) *[]string {
+	states := []string{}
+	game.Player.AddStateListener(func(from, to string) {
+		states = append(states, to)
+	})
+	return &states
+}
+
+func TestPlayerLandsOnFloor(t *testing.T) {
+	game, _ := newTestGame("level-alpha")
+	step(t, game, 60)
+
+	if game.Player.State() != playerIdle {
+		t.Errorf("state is %v, want %v", game.Player.State(), playerIdle)
+	}
+	if game.Player.x != alphaSpawnX || game.Player.y != alphaFloorY {
+		t.Errorf("player is at %v,%v, want %v,%v", game.Player.x, game.Player.y, alphaSpawnX, alphaFloorY)
+	}
+	if game.Player.Health != startingHealth {
+		t.Errorf("health is %v, want %v", game.Player.Health, startingHealth)
+	}
+}
+
+func TestPlayerRuns(t *testing.T) {
+	game, input := newTestGame("level-alpha")
+	input.Hold(30).Hold(30, ebiten.KeyRight).Hold(30).Hold(30, ebiten.KeyLeft)
+	states := recordStates(game)
+
+	step(t, game, 60)
+	right := game.Player.x
+	if right <= alphaSpawnX+16 {
+		t.Errorf("running right reached %v, want past %v", right, alphaSpawnX+16)
+	}
+	if game.Player.State() != playerRun {
+		t.Errorf("state is %v, want %v", game.Player.State(), playerRun)
+	}
+	step(t, game, 60)
+	if game.Player.x >= right {
+		t.Errorf("running left reached %v, want before %v", game.Player.x, right)
+	}
+	if game.Player.y != alphaFloorY {
+		t.Errorf("player left the floor, y is %v", game.Player.y)
+	}
+	if !containsInOrder(*states, playerRun, playerIdle, playerRun) {
+		t.Errorf("states were %v", *states)
+	}
+}
+
+func TestPlayerCanLeaveEverySpawn(t *testing.T) {
+	for _, level := range []string{"level-alpha", "level-beta", "level-gamma", "level-delta", "level-epsilon"} {
+		game, input := newTestGame(level)
+		input.Hold(30).Hold(30, ebiten.KeyRight).Hold(60, ebiten.KeyLeft)
+		step(t, game, 30)
+		spawnX, furthest := game.Player.x, 0.0
+		for i := 0; i < 90; i++ {
+			step(t, game, 1)
+			furthest = math.Max(furthest, math.Abs(game.Player.x-spawnX))
+		}
+		if furthest < common.TileSize {
+			t.Errorf("in %v the player only got %v from the spawn, it may be in the floor", level, furthest)
+		}
+	}
+}
+
+func TestPlayerJump(t *testing.T) {
+	profile := LoadPhysicsProfiles()[defaultPhysicsProfile]
+	jump := func(frames int) float64 {
+		game, input := newTestGame("level-alpha")
+		input.Hold(30).Hold(frames, ebiten.KeySpace)
+		states := recordStates(game)
+		step(t, game, 30)
+		top := game.Player.y
+		for i := 0; i < 90; i++ {
+			step(t, game, 1)
+			if game.Player.y < top {
+				top = game.Player.y
+			}
+		}
+		if game.Player.y != alphaFloorY || game.Player.State() != playerIdle {
+			t.Errorf("player did not land, at %v in %v", game.Player.y, game.Player.State())
+		}
+		if !containsInOrder(*states, playerJump, playerFall, playerIdle) {
+			t.Errorf("states were %v", *states)
+		}
+		return alphaFloorY - top
+	}
+
+	full := jump(40)
+	if full < profile.JumpHeight-4 || full > profile.JumpHeight+4 {
+		t.Errorf("holding jump reached %v, want about %v", full, profile.JumpHeight)
+	}
+	tapped := jump(2)
+	if tapped >= full || tapped < profile.MinimumJumpHeight-4 {
+		t.Errorf("tapping jump reached %v, want between %v and %v", tapped, profile.MinimumJumpHeight, full)
+	}
+}
+
+func TestCrawlerHurtsPlayer(t *testing.T) {
+	game, _ := newTestGame("level-alpha")
+	step(t, game, 30)
+	states := recordStates(game)
+	enemies := len(game.Level.enemies)
+	crawler := NewEnemy(game.Player.x+8, game.Player.y, game.enemyDefinitions["crawler"], game)
+	game.Level.AddEnemy(crawler)
+
+	step(t, game, 1)
+	if game.Player.Health != startingHealth-1 {
+		t.Errorf("health is %v, want %v", game.Player.Health, startingHealth-1)
+	}
+	if game.Player.State() != playerHurt {
+		t.Errorf("state is %v, want %v", game.Player.State(), playerHurt)
+	}
+	// touching the player is enough to kill a crawler
+	if len(game.Level.enemies) != enemies {
+		t.Errorf("there are %v enemies, want %v", len(game.Level.enemies), enemies)
+	}
+
+	step(t, game, 120)
+	if game.Player.State() != playerIdle {
+		t.Errorf("state is %v, want %v", game.Player.State(), playerIdle)
+	}
+	if !containsInOrder(*states, playerHurt, playerIdle) {
+		t.Errorf("states were %v", *states)
+	}
+}
+
+func TestPlayerStateTransitions(t *testing.T) {
+	for name, state := range playerStates {
+		for _, next := range state.Transitions {
+			if _, ok := playerStates[next]; !ok || next == name {
+				t.Errorf("%v can change to %v", name, next)
+			}
+		}
+	}
+
+	// holding right while hurt, the player only runs again once they have landed
+	game, input := newTestGame("level-alpha")
+	step(t, game, 30)
+	input.Hold(150, ebiten.KeyRight)
+	states := recordStates(game)
+	game.Player.TakeDamage(DamageInfo{Amount: 1, Knockback: 100, SourceX: game.Player.x + 16, SourceY: game.Player.y}, game)
+	game.Player.changeState(playerRun, game)
+	if game.Player.State() != playerHurt {
+		t.Errorf("state is %v, a hurt player cannot run", game.Player.State())
+	}
+	step(t, game, 120)
+	if len(*states) < 3 || (*states)[0] != playerHurt || (*states)[1] == playerRun || game.Player.State() != playerRun {
+		t.Errorf("states were %v", *states)
+	}
+}
+
+func TestSpellKillsCrawler(t *testing.T) {
+	game, input := newTestGame("level-alpha")
+	game.Player.AddSpell(spellBullet)
+	// crawlers are too low to hit standing up
+	input.Hold(30).Hold(10, ebiten.KeyArrowDown).Press(ebiten.KeyArrowDown, ebiten.KeyD).Hold(60)
+	enemies := len(game.Level.enemies)
+	crawler := NewEnemy(alphaSpawnX+64, alphaFloorY, game.enemyDefinitions["crawler"], game)
+	game.Level.AddEnemy(crawler)
+	start := crawler.GetCollisionBox()
+
+	step(t, game, 10)
+	if len(game.Level.enemies) != enemies+1 {
+		t.Fatalf("there are %v enemies, want %v", len(game.Level.enemies), enemies+1)
+	}
+	if crawler.GetCollisionBox().x == start.x {
+		t.Errorf("crawler did not move")
+	}
+	step(t, game, input.Frames()-10)
+	for _, enemy := range game.Level.enemies {
+		if enemy == crawler {
+			t.Fatal("the spell did not kill the crawler")
+		}
+	}
+	if game.Player.Health != startingHealth {
+		t.Errorf("health is %v, want %v", game.Player.Health, startingHealth)
+	}
+}
+
+// containsInOrder is whether the wanted states all happened, in order, with any in between.
+func containsInOrder(states []string, want ...string) bool {
+	for _, state := range states {
+		if len(want) > 0 && state == want[0] {
+			want = want[1:]
+		}
+	}
+	return len(want) == 0
+}
+
+func TestCameraHoldsStillWhileJumping(t *testing.T) {
+	game, input := newTestGame("level-alpha")
+	input.Hold(60).Hold(40, ebiten.KeySpace)
+	step(t, game, 60)
+	_, settled := game.Camera.GetPos()
+
+	for i := 0; i < 90; i++ {
+		step(t, game, 1)
+		if _, y := game.Camera.GetPos(); math.Abs(y-settled) > 0.5 {
+			t.Fatalf("camera moved to %v while jumping, want %v", y, settled)
+		}
+	}
+}
+
+func TestCameraLooksAhead(t *testing.T) {
+	game, input := newTestGame("level-alpha")
+	input.Hold(30).Hold(90, ebiten.KeyRight)
+	step(t, game, input.Frames())
+
+	x, _ := game.Camera.GetPos()
+	if centre := x + (common.ScreenWidth / 2); centre <= game.Player.x+(common.TileSize/2) {
+		t.Errorf("camera centred on %v, want ahead of the player at %v", centre, game.Player.x)
+	}
+}
+
+func TestCameraLooksUp(t *testing.T) {
+	game, input := newTestGame("level-alpha")
+	input.Hold(60).Hold(60, ebiten.KeyArrowUp)
+	step(t, game, 60)
+	_, settled := game.Camera.GetPos()
+
+	step(t, game, 10)
+	if _, y := game.Camera.GetPos(); math.Abs(y-settled) > 0.5 {
+		t.Errorf("camera moved to %v before the look delay, want %v", y, settled)
+	}
+	step(t, game, 50)
+	if _, y := game.Camera.GetPos(); y > settled-game.Level.camera.LookDistance/2 {
+		t.Errorf("camera is at %v looking up, want above %v", y, settled-game.Level.camera.LookDistance/2)
+	}
+	step(t, game, 120)
+	if _, y := game.Camera.GetPos(); math.Abs(y-settled) > 0.5 {
+		t.Errorf("camera is at %v after looking up, want back at %v", y, settled)
+	}
+}
+
+func TestHurtFeedback(t *testing.T) {
+	for _, reduceMotion := range []bool{false, true} {
+		game, _ := newTestGame("level-alpha")
+		game.SetReduceMotion(reduceMotion)
+		step(t, game, 30)
+		game.Level.AddEnemy(NewEnemy(game.Player.x+8, game.Player.y, game.enemyDefinitions["crawler"], game))
+
+		step(t, game, 1)
+		shaking, stopped := game.Camera.trauma > 0, game.hitStopTimer > 0
+		if shaking == reduceMotion || stopped == reduceMotion {
+			t.Errorf("with reduce motion %v the camera shaking is %v and hit-stop is %v", reduceMotion, shaking, stopped)
+		}
+		step(t, game, 120)
+		if game.Camera.trauma != 0 || game.hitStopTimer > 0 {
+			t.Errorf("trauma %v and hit-stop %v did not wear off", game.Camera.trauma, game.hitStopTimer)
+		}
+	}
+}
+
+func TestDeathEffectsShake(t *testing.T) {
+	for _, effect := range []string{effectCrawlerDeath, effectBlobDeath, effectSpellHit} {
+		game, _ := newTestGame("level-alpha")
+		step(t, game, 30)
+		game.SpawnEffect(effect, game.Player.x, game.Player.y, false, 0)
+		if shaking := game.Camera.trauma > 0; shaking != (effect != effectSpellHit) {
+			t.Errorf("after %v the camera shaking is %v", effect, shaking)
+		}
+	}
+
+	// spells kill crawlers in one hit, so the shake comes from the death effect
+	game, _ := newTestGame("level-alpha")
+	step(t, game, 30)
+	crawler := NewEnemy(alphaSpawnX+64, alphaFloorY, game.enemyDefinitions["crawler"], game)
+	game.Level.AddEnemy(crawler)
+	crawler.GetHurt(game, DamageInfo{Amount: 1, Type: damageTypeSpell})
+	if game.Camera.trauma != feedbackEnemyDeath.Trauma || game.hitStopTimer != feedbackSpellHit.HitStop {
+		t.Errorf("killing a crawler left trauma %v and hit-stop %v", game.Camera.trauma, game.hitStopTimer)
+	}
+}
+
+// findRooms returns the first pair of camera rooms in the level that are side by side, with the
+// second scrolling in or not.
+func findRooms(t *testing.T, game *Game, scroll bool) (*cameraRoom, *cameraRoom) {
+	t.Helper()
+	for _, first := range game.Level.cameraRooms {
+		for _, second := range game.Level.cameraRooms {
+			if second.bounds.x == first.bounds.x+first.bounds.w && second.scroll == scroll {
+				return first, second
+			}
+		}
+	}
+	t.Fatalf("there are no rooms side by side with scrolling %v", scroll)
+	return nil, nil
+}
+
+func TestCameraCentresSmallRoom(t *testing.T) {
+	game, _ := newTestGame("level-gamma")
+	var room *cameraRoom
+	for _, r := range game.Level.cameraRooms {
+		if r.bounds.w < common.ScreenWidth && r.bounds.h < common.ScreenHeight {
+			room = r
+		}
+	}
+	if room == nil {
+		t.Fatal("level-gamma has no camera rooms smaller than the screen")
+	}
+	// the floor of every part of level-gamma is level with the spawn
+	game.Player.x = room.bounds.x + ((room.bounds.w - game.Player.sizex) / 2)
+	game.Player.y = game.Level.spawn.y
+	step(t, game, 60)
+
+	x, y := game.Camera.GetPos()
+	wantX := room.bounds.x + ((room.bounds.w - common.ScreenWidth) / 2)
+	wantY := room.bounds.y + ((room.bounds.h - common.ScreenHeight) / 2)
+	if x != wantX || y != wantY {
+		t.Errorf("camera is at %v,%v, want centred on the room at %v,%v", x, y, wantX, wantY)
+	}
+}
+
+func TestCameraUsesRoomsFromRebuiltLevel(t *testing.T) {
+	game, _ := newTestGame("level-gamma")
+	var room *cameraRoom
+	for _, r := range game.Level.cameraRooms {
+		if r.bounds.w < common.ScreenWidth && r.bounds.h < common.ScreenHeight {
+			room = r
+		}
+	}
+	if room == nil {
+		t.Fatal("level-gamma has no camera rooms smaller than the screen")
+	}
+	game.Player.x = room.bounds.x + ((room.bounds.w - game.Player.sizex) / 2)
+	game.Player.y = game.Level.spawn.y
+	step(t, game, 60)
+
+	// widen the room the way the editor does and build the level again
+	for _, obj := range game.Level.tiledGrid.GetObjects() {
+		if obj.Name == cameraRoomObject && float64(obj.X) == room.bounds.x && float64(obj.Y) == room.bounds.y {
+			obj.Width = obj.Width + 32
+		}
+	}
+	game.rebuildLevel(game.Level.tiledGrid)
+	step(t, game, 60)
+	x, _ := game.Camera.GetPos()
+	wantX := room.bounds.x + ((room.bounds.w + 32 - common.ScreenWidth) / 2)
+	if x != wantX {
+		t.Errorf("camera is at %v, want centred on the wider room at %v", x, wantX)
+	}
+}
+
+func TestCameraMovesBetweenRooms(t *testing.T) {
+	for _, scroll := range []bool{false, true} {
+		game, input := newTestGame("level-gamma")
+		first, second := findRooms(t, game, scroll)
+		game.Player.x = first.bounds.x + first.bounds.w - 112
+		game.Player.y = game.Level.spawn.y
+		input.Hold(30).Hold(120, ebiten.KeyRight)
+
+		moved, transitions := false, 0
+		for i := 0; i < input.Frames(); i++ {
+			x, transitioning := game.Player.x, game.Camera.transitionTimer > 0
+			step(t, game, 1)
+			if x, _ := game.Camera.GetPos(); game.Camera.room == first && (x < first.bounds.x || x > first.bounds.x+first.bounds.w-common.ScreenWidth) {
+				t.Fatalf("camera left the first room, it is at %v", x)
+			}
+			if transitioning {
+				transitions++
+				moved = moved || game.Player.x != x
+			}
+		}
+		if game.Camera.room != second {
+			t.Fatalf("camera did not move into the second room, the player is at %v", game.Player.x)
+		}
+		if transitions == 0 {
+			t.Errorf("camera jumped into the second room")
+		}
+		if moved == scroll {
+			t.Errorf("scrolling %v, the player moving during the transition is %v", scroll, moved)
+		}
+		if x, _ := game.Camera.GetPos(); x < second.bounds.x {
+			t.Errorf("camera is at %v, want inside the second room from %v", x, second.bounds.x)
+		}
+	}
+}
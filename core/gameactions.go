@@ -1,11 +1,21 @@
 package core
 
-import "fmt"
+import (
+	"fmt"
+	"log"
+	"platformer/common"
+)
 
 func (r *Game) LoadLevel(name string) {
-	r.spellObjects = []*SpellObject{}
+	r.projectiles = []*Projectile{}
 	r.effectSprites = []*EffectSprite{}
+	r.spellRays = []*SpellRay{}
+	oldLevel := r.Level
 	r.Level = NewLevel(name, r)
+	if oldLevel != nil {
+		// after loading the new level so anything both levels use stays loaded
+		oldLevel.unloadResources(r)
+	}
 	r.Player = NewPlayer(r)
 	r.Player.x = r.Level.spawn.x
 	r.Player.y = r.Level.spawn.y
@@ -15,9 +25,42 @@ func (r *Game) LoadLevel(name string) {
 	fmt.Println("load Level ", name)
 }
 
+// ReloadLevel loads the current level from its file again, the player keeps their position,
+// health and spells, and the camera stays where it is. A file that cannot be read leaves the
+// level as it was.
+func (r *Game) ReloadLevel() {
+	tiledGrid, err := common.LoadTileGrid(r.Level.name)
+	if err != nil {
+		log.Println("could not reload Level", r.Level.name, err)
+		return
+	}
+	r.rebuildLevel(tiledGrid)
+	fmt.Println("reloaded Level ", r.Level.name)
+}
+
+// rebuildLevel replaces the current level with one built from the map, keeping the player
+// and camera as they are. The camera lets go of its room, which belonged to the old level.
+func (r *Game) rebuildLevel(tiledGrid *common.TiledGrid) {
+	r.projectiles = []*Projectile{}
+	r.effectSprites = []*EffectSprite{}
+	r.spellRays = []*SpellRay{}
+	oldLevel := r.Level
+	r.Level = newLevelFromGrid(oldLevel.name, tiledGrid, r)
+	if r.Level.spawn == nil {
+		log.Println("no spawn for Level", oldLevel.name, "keeping the old one")
+		r.Level.spawn = oldLevel.spawn
+	}
+	r.Camera.leaveRoom()
+	oldLevel.unloadResources(r)
+}
+
 func (r *Game) PlayerDeath() {
-	r.spellObjects = []*SpellObject{}
+	r.projectiles = []*Projectile{}
 	r.effectSprites = []*EffectSprite{}
+	r.spellRays = []*SpellRay{}
+	if r.Level.arena != nil {
+		r.Level.arena.Reset(r)
+	}
 	r.Player = NewPlayer(r)
 	r.Player.x = r.Level.spawn.x
 	r.Player.y = r.Level.spawn.y
@@ -0,0 +1,19 @@
+package core
+
+import "platformer/res"
+
+// NewHeadlessGame loads the level with nothing to draw it and the controls read from input,
+// for running the game from tests and tools. Use it with NewHeadlessResources so no images
+// are made.
+func NewHeadlessGame(resources *res.Resources, input Input, level string) *Game {
+	r := newGame(resources, headlessActions{}, input)
+	r.LoadLevel(level)
+	return r
+}
+
+// headlessActions has no book to open, the game keeps running.
+type headlessActions struct{}
+
+func (r headlessActions) OpenBook(title, text string) {}
+
+func (r headlessActions) CloseBook() {}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"log"
+	"platformer/common"
+	"strings"
+)
+
+const hotReloadPollTime = 0.5
+const hotReloadDirectory = "res"
+
+// HotReloader watches res/ while the game runs in debug mode, changed levels, tilesets and
+// images are loaded again without restarting. Files that cannot be read are logged and the
+// game keeps what it had.
+type HotReloader struct {
+	watcher *common.FileWatcher
+	timer   float64
+}
+
+func NewHotReloader() *HotReloader {
+	return &HotReloader{
+		watcher: common.NewFileWatcher(hotReloadDirectory),
+		timer:   hotReloadPollTime,
+	}
+}
+
+func (r *HotReloader) Update(delta float64, game *Game) {
+	// only while developing, players do not pay for watching the files
+	if !game.debug.showDebug {
+		return
+	}
+	if game.input.IsKeyJustPressed(ebiten.KeyF5) {
+		game.ReloadAll()
+		// the manual reload already picked up anything that changed
+		r.skipChanges()
+		return
+	}
+	r.timer = r.timer - delta
+	if r.timer > 0 {
+		return
+	}
+	r.timer = hotReloadPollTime
+	changes := r.watcher.GetChanges()
+	if len(changes) > 0 {
+		game.reloadFiles(changes)
+	}
+}
+
+// skipChanges forgets the changes made so far, for files the game has written itself.
+func (r *HotReloader) skipChanges() {
+	r.watcher.GetChanges()
+}
+
+// reloadFiles works out what needs loading again for the changed files, relative to res/.
+func (r *Game) reloadFiles(files []string) {
+	reloadLevel := false
+	for _, file := range files {
+		switch {
+		case file == "levels/"+r.Level.name+".json":
+			reloadLevel = true
+		case strings.HasPrefix(file, enemyDefinitionsDirectory[len(hotReloadDirectory)+1:]):
+			if r.reloadEnemyDefinitions() {
+				reloadLevel = true
+			}
+		case strings.HasPrefix(file, physicsProfilesDirectory[len(hotReloadDirectory)+1:]):
+			if r.reloadPhysicsProfiles() {
+				reloadLevel = true
+			}
+		case r.Level.tiledGrid.UsesFile(file):
+			if err := r.Level.reloadTileSet(); err != nil {
+				log.Println("could not reload tileset", file, err)
+				continue
+			}
+			fmt.Println("reloaded tileset", file)
+		default:
+			if names := r.res.ReloadFile(file); len(names) > 0 {
+				fmt.Println("reloaded", file, names)
+			}
+		}
+	}
+	if reloadLevel {
+		r.ReloadLevel()
+	}
+}
+
+// reloadEnemyDefinitions reads the enemy definitions again, keeping the ones already loaded
+// when any of them cannot be read.
+func (r *Game) reloadEnemyDefinitions() bool {
+	definitions, err := loadEnemyDefinitions()
+	if err != nil {
+		log.Println("could not reload enemy definitions", err)
+		return false
+	}
+	r.enemyDefinitions = definitions
+	return true
+}
+
+// reloadPhysicsProfiles reads the physics profiles again, keeping the ones already loaded when
+// any of them cannot be read.
+func (r *Game) reloadPhysicsProfiles() bool {
+	profiles, err := loadPhysicsProfiles()
+	if err != nil {
+		log.Println("could not reload physics profiles", err)
+		return false
+	}
+	r.physicsProfiles = profiles
+	return true
+}
+
+// ReloadAll loads every image and the current level again.
+func (r *Game) ReloadAll() {
+	r.res.ReloadAll()
+	r.reloadEnemyDefinitions()
+	r.reloadPhysicsProfiles()
+	r.ReloadLevel()
+}
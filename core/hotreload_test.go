@@ -0,0 +1,94 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestFile writes a file under res/ for the length of the test.
+func writeTestFile(t *testing.T, fileName string, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Remove(fileName)
+	})
+}
+
+func TestReloadKeepsEnemiesWhenDefinitionIsBroken(t *testing.T) {
+	game, _ := newTestGame("level-alpha")
+	definitions := game.enemyDefinitions
+	level := game.Level
+	fileName := filepath.Join(enemyDefinitionsDirectory, "half-written.json")
+	writeTestFile(t, fileName, `{"name": "half-written", "behavior": "cra`)
+
+	game.reloadFiles([]string{"enemies/half-written.json"})
+	if len(game.enemyDefinitions) != len(definitions) || game.enemyDefinitions["crawler"] != definitions["crawler"] {
+		t.Errorf("the enemy definitions changed after a broken file")
+	}
+	if game.Level != level {
+		t.Errorf("the level was rebuilt after a broken file")
+	}
+
+	writeTestFile(t, fileName, `{"name": "half-written", "behavior": "crawler"}`)
+	game.reloadFiles([]string{"enemies/half-written.json"})
+	if _, ok := game.enemyDefinitions["half-written"]; !ok {
+		t.Errorf("the fixed definition was not loaded")
+	}
+}
+
+func TestReloadKeepsPhysicsWhenProfileIsBroken(t *testing.T) {
+	game, _ := newTestGame("level-alpha")
+	profiles := game.physicsProfiles
+	level := game.Level
+	fileName := filepath.Join(physicsProfilesDirectory, "half-written.json")
+	writeTestFile(t, fileName, `{"jump-height": 6`)
+
+	game.reloadFiles([]string{"physics/half-written.json"})
+	if len(game.physicsProfiles) != len(profiles) || game.physicsProfiles[defaultPhysicsProfile] != profiles[defaultPhysicsProfile] {
+		t.Errorf("the physics profiles changed after a broken file")
+	}
+	if game.Level != level {
+		t.Errorf("the level was rebuilt after a broken file")
+	}
+
+	writeTestFile(t, fileName, `{"jump-height": 60}`)
+	game.reloadFiles([]string{"physics/half-written.json"})
+	if profile, ok := game.physicsProfiles["half-written"]; !ok || profile.JumpHeight != 60 {
+		t.Errorf("the fixed profile was not loaded")
+	}
+}
+
+func TestReloadKeepsLevelWhenMapIsBroken(t *testing.T) {
+	game, _ := newTestGame("level-alpha")
+	level := game.Level
+	game.Level.name = "half-written"
+	writeTestFile(t, filepath.Join("res", "levels", "half-written.json"), `{"layers": [`)
+
+	game.reloadFiles([]string{"levels/half-written.json"})
+	if game.Level != level {
+		t.Errorf("the level was replaced by a broken map")
+	}
+}
+
+func TestReloadedSheetReachesAnimations(t *testing.T) {
+	game, _ := newTestGame("level-alpha")
+	animation := newAnimation(game, "player-run", true)
+	sheet := game.res.GetSpriteSheet("player-run")
+	duration := sheet.Frames[0].Duration
+
+	// as the resources do when the file changes
+	sheet.Frames[0].Duration = duration * 2
+	sheet.Version = sheet.Version + 1
+	defer func() {
+		sheet.Frames[0].Duration = duration
+	}()
+
+	animation.Update(0)
+	if animation.frames[0].duration != duration*2 {
+		t.Errorf("frame duration is %v, want the reloaded %v", animation.frames[0].duration, duration*2)
+	}
+}
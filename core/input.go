@@ -0,0 +1,140 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Input is where the player's controls are read from, the keyboard when playing and a script
+// when the game is run by a test. The developer tools read the mouse and typed text from it
+// too. Update is called at the start of every game update.
+type Input interface {
+	Update()
+	IsKeyPressed(key ebiten.Key) bool
+	IsKeyJustPressed(key ebiten.Key) bool
+	IsMouseButtonPressed(button ebiten.MouseButton) bool
+	IsMouseButtonJustPressed(button ebiten.MouseButton) bool
+	// CursorPosition is where the mouse is in the window, in screen pixels
+	CursorPosition() (int, int)
+	Wheel() (float64, float64)
+	// AppendInputChars adds the characters typed since the last update to the runes
+	AppendInputChars(runes []rune) []rune
+}
+
+type keyboardInput struct{}
+
+func (r keyboardInput) Update() {}
+
+func (r keyboardInput) IsKeyPressed(key ebiten.Key) bool {
+	return ebiten.IsKeyPressed(key)
+}
+
+func (r keyboardInput) IsKeyJustPressed(key ebiten.Key) bool {
+	return inpututil.IsKeyJustPressed(key)
+}
+
+func (r keyboardInput) IsMouseButtonPressed(button ebiten.MouseButton) bool {
+	return ebiten.IsMouseButtonPressed(button)
+}
+
+func (r keyboardInput) IsMouseButtonJustPressed(button ebiten.MouseButton) bool {
+	return inpututil.IsMouseButtonJustPressed(button)
+}
+
+func (r keyboardInput) CursorPosition() (int, int) {
+	return ebiten.CursorPosition()
+}
+
+func (r keyboardInput) Wheel() (float64, float64) {
+	return ebiten.Wheel()
+}
+
+func (r keyboardInput) AppendInputChars(runes []rune) []rune {
+	return ebiten.AppendInputChars(runes)
+}
+
+// ScriptedInput plays back frames of held keys, one frame each update. A key held in a frame
+// that was not held in the frame before is just pressed. Once the frames run out nothing is
+// held. The mouse is never pressed and stays in the top left corner.
+type ScriptedInput struct {
+	frames   [][]ebiten.Key
+	frame    int
+	held     map[ebiten.Key]bool
+	previous map[ebiten.Key]bool
+	// typed is the text typed in each frame that has any
+	typed map[int]string
+	chars string
+}
+
+func NewScriptedInput() *ScriptedInput {
+	return &ScriptedInput{
+		frames:   [][]ebiten.Key{},
+		held:     map[ebiten.Key]bool{},
+		previous: map[ebiten.Key]bool{},
+		typed:    map[int]string{},
+	}
+}
+
+// Hold adds frames with the keys held down, with no keys it waits.
+func (r *ScriptedInput) Hold(frames int, keys ...ebiten.Key) *ScriptedInput {
+	for i := 0; i < frames; i++ {
+		r.frames = append(r.frames, keys)
+	}
+	return r
+}
+
+// Press holds the keys for a single frame.
+func (r *ScriptedInput) Press(keys ...ebiten.Key) *ScriptedInput {
+	return r.Hold(1, keys...)
+}
+
+// Type adds a frame where the text is typed all at once, for the console and the editor.
+func (r *ScriptedInput) Type(text string) *ScriptedInput {
+	r.typed[len(r.frames)] = text
+	return r.Hold(1)
+}
+
+// Frames is how many frames have been added, for running the game until the script ends.
+func (r *ScriptedInput) Frames() int {
+	return len(r.frames)
+}
+
+func (r *ScriptedInput) Update() {
+	r.previous = r.held
+	r.held = map[ebiten.Key]bool{}
+	if r.frame < len(r.frames) {
+		for _, key := range r.frames[r.frame] {
+			r.held[key] = true
+		}
+	}
+	r.chars = r.typed[r.frame]
+	r.frame = r.frame + 1
+}
+
+func (r *ScriptedInput) IsKeyPressed(key ebiten.Key) bool {
+	return r.held[key]
+}
+
+func (r *ScriptedInput) IsKeyJustPressed(key ebiten.Key) bool {
+	return r.held[key] && !r.previous[key]
+}
+
+func (r *ScriptedInput) IsMouseButtonPressed(button ebiten.MouseButton) bool {
+	return false
+}
+
+func (r *ScriptedInput) IsMouseButtonJustPressed(button ebiten.MouseButton) bool {
+	return false
+}
+
+func (r *ScriptedInput) CursorPosition() (int, int) {
+	return 0, 0
+}
+
+func (r *ScriptedInput) Wheel() (float64, float64) {
+	return 0, 0
+}
+
+func (r *ScriptedInput) AppendInputChars(runes []rune) []rune {
+	return append(runes, []rune(r.chars)...)
+}
@@ -2,20 +2,29 @@ package core
 
 import (
 	"github.com/hajimehoshi/ebiten/v2"
+	"log"
+	"path/filepath"
 	"platformer/common"
+	"strings"
 )
 
 const (
-	healthPickup = "health"
-	bookPickup   = "book"
-	spawnObject  = "spawn"
-	exitObject   = "exit"
-	crawlerEnemy = "crawler"
-	blobEnemy    = "blob"
-	flimsyObject = "flimsy"
-	signObject   = "sign"
+	healthPickup  = "health"
+	bookPickup    = "book"
+	abilityPickup = "ability"
+	spawnObject   = "spawn"
+	exitObject    = "exit"
+	flimsyObject  = "flimsy"
+	signObject    = "sign"
+	bossObject    = "boss"
+	bossTrigger   = "boss-trigger"
+	bossBarrier   = "barrier"
+	waterObject   = "water"
+	crystalObject = "crystal"
 )
 
+const defaultBossHealth = 20
+
 type Level struct {
 	name             string
 	tiledGrid        *common.TiledGrid
@@ -27,18 +36,66 @@ type Level struct {
 	enemies          []Enemy
 	flimsy           []*Flimsy
 	signs            []*Sign
+	crystals         []*Crystal
+	physics          *PhysicsProfile
+	camera           *CameraSettings
+	cameraRooms      []*cameraRoom
+	arena            *BossArena
+	navGraphs        map[common.NavProfile]*common.NavGraph
+	resourceGroups   []string
+	waterObjects     []CollisionBox
+	waterTiles       []CollisionBox
+	waterImage       *ebiten.Image
+	// airTime is how long the player can stay under water, 0 is forever
+	airTime float64
+	// refs
+	debug *DebugDrawer
 }
 
 func NewLevel(name string, game *Game) *Level {
+	l := newLevelFromGrid(name, common.NewTileGrid(name), game)
+	if l.spawn == nil {
+		panic("no spawn for Level: " + name)
+	}
+	return l
+}
+
+// newLevelFromGrid builds the level from a map that is already loaded, so the editor can
+// rebuild the level from its changes without saving them first. The spawn is left nil when
+// the map has none.
+func newLevelFromGrid(name string, tiledGrid *common.TiledGrid, game *Game) *Level {
 	l := &Level{
+		name:             name,
 		backgroundOffset: 60,
 		enemies:          []Enemy{},
 		flimsy:           []*Flimsy{},
+		navGraphs:        map[common.NavProfile]*common.NavGraph{},
+		waterObjects:     []CollisionBox{},
+		waterImage:       game.res.GetImage("water"),
+		debug:            game.debug,
+	}
+	l.tiledGrid = tiledGrid
+	if airTime, ok := l.tiledGrid.GetProperty("air-time").(float64); ok {
+		l.airTime = airTime
+	}
+	l.physics = game.physicsProfile(defaultPhysicsProfile)
+	if profile, ok := l.tiledGrid.GetProperty("physics-profile").(string); ok && profile != "" {
+		l.physics = game.physicsProfile(profile)
+	}
+	l.camera = loadCameraSettings(l.tiledGrid)
+	l.findWaterTiles()
+	if groups, ok := l.tiledGrid.GetProperty("resource-groups").(string); ok && groups != "" {
+		l.resourceGroups = strings.Split(groups, ",")
 	}
-	l.tiledGrid = common.NewTileGrid(name)
-	l.background = common.LoadImage("levels/" + l.tiledGrid.BackgroundImage)
+	for _, group := range l.resourceGroups {
+		game.res.PreloadGroup(group)
+	}
+	l.background = game.res.GetImage(strings.TrimSuffix(filepath.Base(l.tiledGrid.BackgroundImage), ".png"))
 	objects := l.tiledGrid.GetObjectData()
 	l.pickups = []*Pickup{}
+	var bossData *common.ObjectData
+	var triggerData *common.ObjectData
+	var barrierData []*common.ObjectData
 	for _, object := range objects {
 		if object.Name == spawnObject {
 			l.spawn = &Spawn{
@@ -67,13 +124,11 @@ func NewLevel(name string, game *Game) *Level {
 				}
 			}
 		}
-		if object.Name == crawlerEnemy {
-			newEnemy := NewCrawlerEnemy(float64(object.X), float64(object.Y), game)
-			l.enemies = append(l.enemies, newEnemy)
-		}
-		if object.Name == blobEnemy {
-			newEnemy := NewBlobEnemy(float64(object.X), float64(object.Y), game)
+		if def, ok := game.enemyDefinitions[object.Name]; ok {
+			def = def.withOverrides(object.Properties)
+			newEnemy := NewEnemy(float64(object.X), float64(object.Y), def, game)
 			l.enemies = append(l.enemies, newEnemy)
+			l.prepareNavGraph(def)
 		}
 		if object.Name == healthPickup {
 			newPickup := &Pickup{
@@ -86,7 +141,7 @@ func NewLevel(name string, game *Game) *Level {
 			}
 			for _, prop := range object.Properties {
 				if prop.Name == "amount" && prop.Value != nil {
-					effect.amount = (prop.Value).(int)
+					effect.amount = int((prop.Value).(float64))
 				}
 			}
 			newPickup.effect = effect
@@ -112,6 +167,24 @@ func NewLevel(name string, game *Game) *Level {
 			newPickup.effect = effect
 			l.pickups = append(l.pickups, newPickup)
 		}
+		if object.Name == abilityPickup {
+			newPickup := &Pickup{
+				x:     float64(object.X),
+				y:     float64(object.Y),
+				image: game.res.GetImage("ability-pickup"),
+			}
+			effect := &AbilityEffect{}
+			for _, prop := range object.Properties {
+				if prop.Name == "ability" && prop.Value != nil {
+					effect.ability = (prop.Value).(string)
+				}
+			}
+			newPickup.effect = effect
+			l.pickups = append(l.pickups, newPickup)
+		}
+		if object.Name == crystalObject {
+			l.crystals = append(l.crystals, NewCrystal(float64(object.X), float64(object.Y), game))
+		}
 		if object.Name == signObject {
 			x, y := float64(object.X), float64(object.Y)
 			text := "no text found"
@@ -122,10 +195,35 @@ func NewLevel(name string, game *Game) *Level {
 			}
 			l.signs = append(l.signs, NewSign(x, y, text, game))
 		}
+		if object.Name == bossObject {
+			bossData = object
+		}
+		if object.Name == bossTrigger {
+			triggerData = object
+		}
+		if object.Name == bossBarrier {
+			barrierData = append(barrierData, object)
+		}
+		if object.Name == cameraRoomObject {
+			l.cameraRooms = append(l.cameraRooms, newCameraRoom(object))
+		}
+		if object.Name == waterObject {
+			l.waterObjects = append(l.waterObjects, CollisionBox{
+				x: float64(object.X),
+				y: float64(object.Y),
+				w: float64(object.W),
+				h: float64(object.H),
+			})
+		}
 	}
-	// validate Level
-	if l.spawn == nil {
-		panic("no spawn for Level: " + name)
+	if bossData != nil && triggerData != nil {
+		l.arena = newBossArena(name, bossData, triggerData, barrierData, game)
+		if l.arena != nil {
+			l.enemies = append(l.enemies, l.arena.boss)
+			if def, ok := game.enemyDefinitions[l.arena.boss.minion]; ok {
+				l.prepareNavGraph(def)
+			}
+		}
 	}
 	return l
 }
@@ -146,6 +244,12 @@ func (r *Level) Update(delta float64, game *Game) {
 	for _, sign := range r.signs {
 		sign.Update(delta, game)
 	}
+	for _, crystal := range r.crystals {
+		crystal.Update(delta, game)
+	}
+	if r.arena != nil {
+		r.arena.Update(delta, game)
+	}
 }
 
 func (r *Level) Draw(camera common.Camera) {
@@ -170,6 +274,12 @@ func (r *Level) Draw(camera common.Camera) {
 	for _, sign := range r.signs {
 		sign.Draw(camera)
 	}
+	for _, crystal := range r.crystals {
+		crystal.Draw(camera)
+	}
+	if r.arena != nil {
+		r.arena.Draw(camera)
+	}
 }
 
 type Spawn struct {
@@ -193,6 +303,14 @@ func (r *Level) RemovePickup(pickup *Pickup) {
 	r.pickups = newListOfPickups
 }
 
+func (r *Level) AddPickup(pickup *Pickup) {
+	r.pickups = append(r.pickups, pickup)
+}
+
+func (r *Level) AddEnemy(enemy Enemy) {
+	r.enemies = append(r.enemies, enemy)
+}
+
 func (r *Level) RemoveEnemy(enemy Enemy) {
 	newEnemies := []Enemy{}
 	for _, e := range r.enemies {
@@ -218,13 +336,132 @@ func (r *Level) GetColliders() []Collider {
 	for _, flimsy := range r.flimsy {
 		colliders = append(colliders, flimsy)
 	}
+	if r.arena != nil {
+		colliders = append(colliders, r.arena.GetColliders()...)
+	}
 	return colliders
 }
 
+// GetNavGraph returns the navigation graph for bodies that move with the given profile,
+// graphs are built the first time they are asked for.
+func (r *Level) GetNavGraph(profile common.NavProfile) *common.NavGraph {
+	graph, ok := r.navGraphs[profile]
+	if !ok {
+		graph = common.NewNavGraph(r.tiledGrid, profile)
+		r.navGraphs[profile] = graph
+	}
+	return graph
+}
+
+// prepareNavGraph builds the graph for an enemy that follows paths while the level loads,
+// every jump arc is simulated so building it mid chase would drop frames.
+func (r *Level) prepareNavGraph(def *EnemyDefinition) {
+	if def.followsPaths() {
+		r.GetNavGraph(def.navProfile())
+	}
+}
+
+// reloadTileSet picks up changes to the tileset, the nav graphs are built again as the
+// tile properties may have changed.
+func (r *Level) reloadTileSet() error {
+	if err := r.tiledGrid.ReloadTileSet(); err != nil {
+		return err
+	}
+	r.tilesChanged()
+	return nil
+}
+
+// tilesChanged is called when the ground tiles are edited, the nav graphs are built again
+// and the water is found again.
+func (r *Level) tilesChanged() {
+	for profile := range r.navGraphs {
+		r.navGraphs[profile] = common.NewNavGraph(r.tiledGrid, profile)
+	}
+	r.findWaterTiles()
+}
+
+// unloadResources lets go of the resource groups the level preloaded, call it once nothing
+// from the level is being drawn any more.
+func (r *Level) unloadResources(game *Game) {
+	for _, group := range r.resourceGroups {
+		game.res.UnloadGroup(group)
+	}
+}
+
+// GetActiveBoss returns the boss the player is currently locked in with, or nil.
+func (r *Level) GetActiveBoss() *BossEnemy {
+	if r.arena == nil || !r.arena.isLocked {
+		return nil
+	}
+	return r.arena.boss
+}
+
+func newBossArena(levelName string, bossData, triggerData *common.ObjectData, barrierData []*common.ObjectData, game *Game) *BossArena {
+	arena := &BossArena{
+		id: levelName,
+		trigger: CollisionBox{
+			x: float64(triggerData.X),
+			y: float64(triggerData.Y),
+			w: float64(triggerData.W),
+			h: float64(triggerData.H),
+		},
+		barriers:    []*Barrier{},
+		music:       bossMusic,
+		rewardTitle: "untitled",
+	}
+	name := "Blob King"
+	health := defaultBossHealth
+	minion := bossMinion
+	for _, prop := range bossData.Properties {
+		if prop.Name == "id" && prop.Value != nil {
+			arena.id = (prop.Value).(string)
+		}
+		if prop.Name == "title" && prop.Value != nil {
+			name = (prop.Value).(string)
+		}
+		if prop.Name == "health" && prop.Value != nil {
+			health = int((prop.Value).(float64))
+		}
+		if prop.Name == "minion" && prop.Value != nil {
+			minion = (prop.Value).(string)
+		}
+		if prop.Name == "reward-spell" && prop.Value != nil {
+			arena.rewardSpell = (prop.Value).(string)
+		}
+		if prop.Name == "reward-title" && prop.Value != nil {
+			arena.rewardTitle = (prop.Value).(string)
+		}
+	}
+	// music names a sound in the resource manifest
+	for _, prop := range triggerData.Properties {
+		if prop.Name == "music" && prop.Value != nil {
+			arena.music = (prop.Value).(string)
+		}
+	}
+	if game.PlayerProgress.IsBossDefeated(arena.id) {
+		return nil
+	}
+	for _, b := range barrierData {
+		arena.barriers = append(arena.barriers, &Barrier{
+			x:     float64(b.X),
+			y:     float64(b.Y),
+			w:     float64(b.W),
+			h:     float64(b.H),
+			image: game.res.GetImage("flimsy"),
+		})
+	}
+	arena.boss = NewBossEnemy(float64(bossData.X), float64(bossData.Y), name, health, minion, game)
+	arena.boss.arena = arena
+	if !game.sounds.HasSound(arena.music) {
+		log.Println("warning: boss music", arena.music, "is not in the resource manifest")
+	}
+	return arena
+}
+
 type Enemy interface {
 	Update(delta float64, game *Game)
 	Draw(camera common.Camera)
-	GetHurt(game *Game)
+	GetHurt(game *Game, damage DamageInfo)
 	GetCollisionBox() CollisionBox
 }
 
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"image/color"
+	"math"
+	"platformer/common"
+)
+
+const (
+	maxHearingDistance = common.TileSize * 20
+	occludedVolume     = 0.35
+)
+
+func isOpaqueTile(td *common.TileData) bool {
+	return td.Block
+}
+
+// Raycast returns where a segment first hits a block tile or a collider like a flimsy,
+// along with the collider if that was what stopped it.
+func (r *Game) Raycast(x1, y1, x2, y2 float64) (common.RayHit, Collider) {
+	hit := r.Level.tiledGrid.Raycast(x1, y1, x2, y2, isOpaqueTile)
+	var hitCollider Collider
+	length := math.Hypot(x2-x1, y2-y1)
+	for _, c := range r.Level.GetColliders() {
+		cb := c.GetCollisionBox()
+		ok, t := common.SegmentIntersectsBox(x1, y1, x2, y2, cb.x, cb.y, cb.w, cb.h)
+		if ok && t*length < hit.Distance {
+			hit.Hit = true
+			hit.Distance = t * length
+			hit.X = x1 + ((x2 - x1) * t)
+			hit.Y = y1 + ((y2 - y1) * t)
+			hit.TileX = int(hit.X / common.TileSize)
+			hit.TileY = int(hit.Y / common.TileSize)
+			hitCollider = c
+		}
+	}
+	if r.debug.IsShown(debugAI) {
+		c := color.RGBA{G: 220, B: 120, A: 200}
+		if hit.Hit {
+			c = color.RGBA{R: 220, B: 120, A: 200}
+		}
+		r.debug.DrawLine(debugAI, c, x1, y1, hit.X, hit.Y)
+	}
+	return hit, hitCollider
+}
+
+func (r *Game) HasLineOfSight(x1, y1, x2, y2 float64) bool {
+	hit, _ := r.Raycast(x1, y1, x2, y2)
+	return !hit.Hit
+}
+
+// PlaySoundAt plays a sound that comes from somewhere in the level, quieter the further it is
+// from the player and muffled when there is a wall in the way.
+func (r *Game) PlaySoundAt(name string, x, y float64) {
+	if !r.sounds.HasSound(name) {
+		return
+	}
+	volume := r.hearingVolume(x, y)
+	if volume <= 0 {
+		return
+	}
+	r.sounds.PlaySoundWithVolume(name, volume)
+}
+
+// hearingVolume is how loud something at x, y is to the player, 0 when it is too far away.
+func (r *Game) hearingVolume(x, y float64) float64 {
+	px, py := r.Player.x+(r.Player.sizex/2), r.Player.y+(r.Player.sizey/2)
+	distance := math.Hypot(x-px, y-py)
+	if distance > maxHearingDistance {
+		return 0
+	}
+	volume := 1 - (distance / maxHearingDistance)
+	if !r.HasLineOfSight(x, y, px, py) {
+		volume = volume * occludedVolume
+	}
+	return volume
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSoundsFadeAndAreMuffledByWalls(t *testing.T) {
+	game, _ := newTestGame("level-alpha")
+	step(t, game, 30)
+	px, py := game.Player.x+(game.Player.sizex/2), game.Player.y+(game.Player.sizey/2)
+
+	for _, effect := range []string{effectSpellHit, effectCrawlerDeath, effectSplash} {
+		if !game.sounds.HasSound(effect) {
+			t.Errorf("%v has no sound", effect)
+		}
+	}
+
+	// along the floor to the right nothing is in the way
+	openX, openY := 488.0, 312.0
+	want := 1 - (math.Hypot(openX-px, openY-py) / maxHearingDistance)
+	if volume := game.hearingVolume(openX, openY); math.Abs(volume-want) > 0.001 {
+		t.Errorf("volume in the open is %v, want %v", volume, want)
+	}
+	// up to the right is behind the rock above the floor
+	wallX, wallY := 552.0, 216.0
+	want = (1 - (math.Hypot(wallX-px, wallY-py) / maxHearingDistance)) * occludedVolume
+	if volume := game.hearingVolume(wallX, wallY); math.Abs(volume-want) > 0.001 {
+		t.Errorf("volume behind a wall is %v, want %v", volume, want)
+	}
+	if volume := game.hearingVolume(px+maxHearingDistance+1, py); volume != 0 {
+		t.Errorf("volume out of hearing is %v, want 0", volume)
+	}
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+	"platformer/common"
+)
+
+const (
+	physicsPanelWidth  = 116.0
+	physicsPanelX      = common.ScreenWidth - physicsPanelWidth - 4
+	physicsPanelY      = 4.0
+	physicsHeaderSize  = 8.0
+	physicsRowHeight   = 10.0
+	physicsSliderWidth = physicsPanelWidth - 4
+)
+
+var (
+	physicsPanelColor  = color.RGBA{R: 20, G: 20, B: 30, A: 160}
+	physicsSliderColor = color.RGBA{R: 230, G: 225, B: 210, A: 200}
+	physicsKnobColor   = color.RGBA{R: 250, G: 200, B: 80, A: 255}
+)
+
+// physicsField is one slider, between the smallest and largest value it can be dragged to.
+type physicsField struct {
+	name  string
+	min   float64
+	max   float64
+	value func(p *PhysicsProfile) *float64
+}
+
+var physicsFields = []physicsField{
+	{"jump-height", 8, 128, func(p *PhysicsProfile) *float64 { return &p.JumpHeight }},
+	{"minimum-jump-height", 0, 64, func(p *PhysicsProfile) *float64 { return &p.MinimumJumpHeight }},
+	{"forced-jump-height", 0, 64, func(p *PhysicsProfile) *float64 { return &p.ForcedJumpHeight }},
+	{"jump-time", 0.1, 1.5, func(p *PhysicsProfile) *float64 { return &p.JumpTime }},
+	{"fall-time", 0.1, 1.5, func(p *PhysicsProfile) *float64 { return &p.FallTime }},
+	{"coyote-time", 0, 0.5, func(p *PhysicsProfile) *float64 { return &p.CoyoteTime }},
+	{"late-jump-time", 0, 0.5, func(p *PhysicsProfile) *float64 { return &p.LateJumpTime }},
+	{"run-acc", 1, 100, func(p *PhysicsProfile) *float64 { return &p.RunAcc }},
+	{"max-run-velocity", 20, 300, func(p *PhysicsProfile) *float64 { return &p.MaxRunVelocity }},
+	{"ladder-velocity", 10, 200, func(p *PhysicsProfile) *float64 { return &p.LadderVelocity }},
+	{"wall-slide-max-velocity", 0, 200, func(p *PhysicsProfile) *float64 { return &p.WallSlideMaxVelocity }},
+	{"wall-jump-lock-time", 0, 0.5, func(p *PhysicsProfile) *float64 { return &p.WallJumpLockTime }},
+	{"take-damage-time", 0, 1, func(p *PhysicsProfile) *float64 { return &p.TakeDamageTime }},
+	{"post-damage-time", 0, 3, func(p *PhysicsProfile) *float64 { return &p.PostDamageTime }},
+}
+
+// PhysicsPanel has a slider for each value of the level's physics profile, shown with the
+// physics debug category. Dragging a slider changes the value while the game runs, and the
+// save button under them writes the profile back to its file.
+type PhysicsPanel struct {
+	dragging int
+	message  string
+}
+
+func NewPhysicsPanel() *PhysicsPanel {
+	return &PhysicsPanel{
+		dragging: -1,
+	}
+}
+
+func (r *PhysicsPanel) Update(game *Game) {
+	if !game.debug.IsShown(debugPhysics) || !game.input.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		r.dragging = -1
+		return
+	}
+	mx, my := screenCursor(game.input)
+	if game.input.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		r.dragging = r.rowAt(mx, my)
+		if r.dragging == len(physicsFields) {
+			r.dragging = -1
+			r.save(game)
+		}
+	}
+	if r.dragging < 0 {
+		return
+	}
+	field := physicsFields[r.dragging]
+	amount := math.Max(0, math.Min(1, (mx-physicsPanelX-2)/physicsSliderWidth))
+	value := field.min + (amount * (field.max - field.min))
+	*field.value(game.Level.physics) = math.Round(value*100) / 100
+}
+
+// rowAt is the slider under the cursor, the save button is the row after the last slider.
+func (r *PhysicsPanel) rowAt(x, y float64) int {
+	if x < physicsPanelX || x > physicsPanelX+physicsPanelWidth {
+		return -1
+	}
+	row := int(math.Floor((y - physicsPanelY - physicsHeaderSize) / physicsRowHeight))
+	if y < physicsPanelY+physicsHeaderSize || row > len(physicsFields) {
+		return -1
+	}
+	return row
+}
+
+func (r *PhysicsPanel) save(game *Game) {
+	profile := game.Level.physics
+	if err := profile.Save(); err != nil {
+		r.message = "could not save"
+		fmt.Println("saving physics profile", err)
+		return
+	}
+	// the game already has the values, there is no need to load them again
+	game.hotReloader.skipChanges()
+	r.message = "saved"
+	fmt.Println("saved physics profile", profile.Name)
+}
+
+func (r *PhysicsPanel) drawDebug(debug *DebugDrawer, game *Game) {
+	profile := game.Level.physics
+	x, y := physicsPanelX, physicsPanelY
+	height := physicsHeaderSize + (float64(len(physicsFields)+1) * physicsRowHeight)
+	debug.DrawScreenBox(debugPhysics, physicsPanelColor, x, y, physicsPanelWidth, height)
+	debug.DrawScreenText(debugPhysics, "physics "+profile.Name+"  "+r.message, x+2, y+1)
+	for i, field := range physicsFields {
+		rowY := y + physicsHeaderSize + (float64(i) * physicsRowHeight)
+		value := *field.value(profile)
+		amount := math.Max(0, math.Min(1, (value-field.min)/(field.max-field.min)))
+		debug.DrawScreenText(debugPhysics, fmt.Sprintf("%v %v", field.name, value), x+2, rowY)
+		debug.DrawScreenBox(debugPhysics, physicsSliderColor, x+2, rowY+6, physicsSliderWidth, 2)
+		debug.DrawScreenBox(debugPhysics, physicsKnobColor, x+1+(amount*physicsSliderWidth), rowY+5, 2, 4)
+	}
+	debug.DrawScreenText(debugPhysics, "save", x+2, y+physicsHeaderSize+(float64(len(physicsFields))*physicsRowHeight))
+}
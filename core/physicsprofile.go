@@ -0,0 +1,164 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const physicsProfilesDirectory = "res/physics/"
+const defaultPhysicsProfile = "default"
+
+// PhysicsProfile is how the player moves, loaded from res/physics so it can be tuned without
+// building the game again. Levels pick one with their physics-profile property. Heights are
+// in pixels, times in seconds and velocities in pixels per second.
+type PhysicsProfile struct {
+	Name                 string  `json:"name"`
+	JumpHeight           float64 `json:"jump-height"`
+	MinimumJumpHeight    float64 `json:"minimum-jump-height"`
+	ForcedJumpHeight     float64 `json:"forced-jump-height"`
+	JumpTime             float64 `json:"jump-time"`
+	FallTime             float64 `json:"fall-time"`
+	CoyoteTime           float64 `json:"coyote-time"`
+	LateJumpTime         float64 `json:"late-jump-time"`
+	RunAcc               float64 `json:"run-acc"`
+	MaxRunVelocity       float64 `json:"max-run-velocity"`
+	LadderVelocity       float64 `json:"ladder-velocity"`
+	WallSlideMaxVelocity float64 `json:"wall-slide-max-velocity"`
+	WallJumpLockTime     float64 `json:"wall-jump-lock-time"`
+	TakeDamageTime       float64 `json:"take-damage-time"`
+	PostDamageTime       float64 `json:"post-damage-time"`
+	// defaults is the profile this one was read over, nil for the default profile
+	defaults *PhysicsProfile
+}
+
+func LoadPhysicsProfiles() map[string]*PhysicsProfile {
+	profiles, err := loadPhysicsProfiles()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return profiles
+}
+
+// loadPhysicsProfiles reads every profile, returning what went wrong instead of stopping the
+// game so a half written file can be reloaded. default.json is read first and the other
+// profiles start from it, so they only need to list what they change.
+func loadPhysicsProfiles() (map[string]*PhysicsProfile, error) {
+	defaults, err := loadPhysicsProfile(filepath.Join(physicsProfilesDirectory, defaultPhysicsProfile+".json"), nil)
+	if err != nil {
+		return nil, err
+	}
+	profiles := map[string]*PhysicsProfile{
+		defaultPhysicsProfile: defaults,
+	}
+	files, err := filepath.Glob(filepath.Join(physicsProfilesDirectory, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("listing physics profiles: %w", err)
+	}
+	for _, fileName := range files {
+		if strings.TrimSuffix(filepath.Base(fileName), ".json") == defaultPhysicsProfile {
+			continue
+		}
+		profile, err := loadPhysicsProfile(fileName, defaults)
+		if err != nil {
+			return nil, err
+		}
+		profiles[profile.Name] = profile
+	}
+	return profiles, nil
+}
+
+// loadPhysicsProfile reads the file over a copy of the defaults, which can be nil for the
+// default profile itself.
+func loadPhysicsProfile(fileName string, defaults *PhysicsProfile) (*PhysicsProfile, error) {
+	profileFile, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("opening physics profile: %w", err)
+	}
+	defer profileFile.Close()
+	profile := &PhysicsProfile{}
+	if defaults != nil {
+		*profile = *defaults
+		profile.defaults = defaults
+	}
+	jsonParser := json.NewDecoder(profileFile)
+	if err = jsonParser.Decode(profile); err != nil {
+		return nil, fmt.Errorf("parsing physics profile %v: %w", fileName, err)
+	}
+	// the file name is the name, so saving writes back to the same file
+	profile.Name = strings.TrimSuffix(filepath.Base(fileName), ".json")
+	return profile, nil
+}
+
+// Save writes the profile back to its file in res/physics. Profiles other than the default
+// only write the values that differ from it, so they keep following it for the rest.
+func (r *PhysicsProfile) Save() error {
+	var values interface{} = r
+	if r.defaults != nil {
+		changed, err := r.changedValues()
+		if err != nil {
+			return err
+		}
+		values = changed
+	}
+	out, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(physicsProfilesDirectory, r.Name+".json"), append(out, '\n'), 0644)
+}
+
+// changedValues is the profile's json values that are not the same as the defaults, and its name.
+func (r *PhysicsProfile) changedValues() (map[string]interface{}, error) {
+	values, err := r.jsonValues()
+	if err != nil {
+		return nil, err
+	}
+	defaults, err := r.defaults.jsonValues()
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range values {
+		if key != "name" && defaults[key] == value {
+			delete(values, key)
+		}
+	}
+	return values, nil
+}
+
+// jsonValues is the profile as it is written to its file.
+func (r *PhysicsProfile) jsonValues() (map[string]interface{}, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	return values, json.Unmarshal(b, &values)
+}
+
+// gravity is how fast the player speeds up falling, it is faster on the way down than up.
+func (r *PhysicsProfile) gravity(jumpTimer float64) float64 {
+	time := r.JumpTime
+	if jumpTimer > r.JumpTime {
+		time = r.FallTime
+	}
+	return (r.JumpHeight * -2) / (time * time)
+}
+
+// jumpVelocity is the speed upwards that reaches the height, with the gravity of a jump.
+func (r *PhysicsProfile) jumpVelocity(height float64) float64 {
+	return (2 * height) / r.JumpTime
+}
+
+// physicsProfile finds a profile by name, falling back to the default one.
+func (r *Game) physicsProfile(name string) *PhysicsProfile {
+	if profile, ok := r.physicsProfiles[name]; ok {
+		return profile
+	}
+	log.Println("no physics profile called", name, "using", defaultPhysicsProfile)
+	return r.physicsProfiles[defaultPhysicsProfile]
+}
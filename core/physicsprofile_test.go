@@ -0,0 +1,43 @@
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestPhysicsProfileFollowsDefaults(t *testing.T) {
+	defaults := &PhysicsProfile{Name: defaultPhysicsProfile, JumpHeight: 40, RunAcc: 99}
+	fileName := filepath.Join(physicsProfilesDirectory, "test-profile.json")
+	writeTestFile(t, fileName, `{"jump-height": 60}`)
+	profile, err := loadPhysicsProfile(fileName, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.JumpHeight != 60 || profile.RunAcc != 99 {
+		t.Errorf("jump height is %v and run acc %v, want 60 from the file and 99 from the defaults", profile.JumpHeight, profile.RunAcc)
+	}
+
+	profile.MaxRunVelocity = 150
+	if err := profile.Save(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := map[string]interface{}{}
+	if err := json.Unmarshal(b, &saved); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"name": "test-profile", "jump-height": 60.0, "max-run-velocity": 150.0}
+	if len(saved) != len(want) {
+		t.Errorf("saved %v, want only %v", saved, want)
+	}
+	for key, value := range want {
+		if saved[key] != value {
+			t.Errorf("saved %v as %v, want %v", key, saved[key], value)
+		}
+	}
+}
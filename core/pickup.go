@@ -15,7 +15,8 @@ type Pickup struct {
 }
 
 func (r *Pickup) Update(delta float64, game *Game) {
-	if common.Overlap(game.Player.x+4, game.Player.y+8, 8, 8, r.x+2, r.y+2, 12, 12) {
+	pb := game.Player.GetCollisionBox()
+	if common.Overlap(pb.x, pb.y, pb.w, pb.h, r.x+2, r.y+2, 12, 12) {
 		r.effect.GetPickedUp(game)
 		game.Level.RemovePickup(r)
 	}
@@ -52,3 +53,13 @@ func (r *BookEffect) GetPickedUp(game *Game) {
 	game.Player.AddSpell(r.spell)
 	game.PlayerProgress.AddSpell(r.spell)
 }
+
+// AbilityEffect unlocks a movement ability, like wall jumping.
+type AbilityEffect struct {
+	ability string
+}
+
+func (r *AbilityEffect) GetPickedUp(game *Game) {
+	fmt.Println("picked up the ability: ", r.ability)
+	game.PlayerProgress.AddAbility(r.ability)
+}
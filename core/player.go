@@ -2,32 +2,27 @@ package core
 
 import (
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
-	"image/color"
 	"math"
 	"platformer/common"
+	"sort"
 )
 
-const playingState = "playing"
-const dyingState = "dying"
+const spellBullet = "spell-bullet"
+const spellRay = "spell-ray"
+const noClipVelocity = 150
+
+const abilityWallJump = "wall-jump"
+const abilityDoubleJump = "double-jump"
 
-const standardJumpHeight = 16 * 3.2
-const forcedJumpHeight = 16 * 2
-const standardJumpTime = 0.44
-const standardFallTime = 0.4
-const minimumJumpHeight = 16
-const coyoteTimeAmount = 0.16
 const fudge = 0.001
-const runAcc = 20.0
-const maxRunVelocity = 100
-const ladderVelocity = 70
-const lateJumpMarginTime = 0.14
 const ladderGrabAllowance = 8.0
-const takeDamageTime = 0.3
-const postDamageTime = 0.9
 const playerDeathTime = 2.0
 const castSpellCoolDownTime = 0.2
 const castSpellTimeTotal = 0.3
+const wallSlideDustTime = 0.12
+
+// airRefillSpeed is how many times faster air comes back than it is used up.
+const airRefillSpeed = 4.0
 
 type Player struct {
 	x                  float64
@@ -50,6 +45,11 @@ type Player struct {
 	velocityY          float64
 	velocityX          float64
 	coyoteTimer        float64
+	touchingGround     bool
+	standingOn         *common.TileData
+	inWater            bool
+	splashTimer        float64
+	airUsed            float64
 	jumpTimer          float64
 	wasPressingJump    bool
 	alreadyAbortedJump bool
@@ -58,22 +58,32 @@ type Player struct {
 	currentAnimation   string
 	animations         map[string]*Animation
 	lateJumpTimer      float64
-	lockedToLadder     bool
 	takeDamageTimer    float64
+	knockbackVelocityX float64
 	postDamageTimer    float64
 	Health             int
 	deathTimer         float64
 	MaxHealth          int
 	castSpellTimer     float64
-	isCrouch           bool
 	currentSpell       string
 	spells             map[string]bool
+	abilities          map[string]bool
 	castTimer          float64
+	wallDirection      float64
+	wallJumpTimer      float64
+	wallJumpDirection  float64
+	dustTimer          float64
+	input              playerInput
+	aimY               float64
+	stateListeners     []PlayerStateListener
+	physics            *PhysicsProfile
+	doubleJumpUsed     bool
+	doubleJumping      bool
 }
 
 func NewPlayer(game *Game) *Player {
 	p := &Player{
-		state:            playingState,
+		state:            playerIdle,
 		Health:           6,
 		MaxHealth:        9,
 		x:                19 * common.TileSize,
@@ -86,362 +96,345 @@ func NewPlayer(game *Game) *Player {
 		drawSizey:        32,
 		currentAnimation: "idle",
 		spells:           map[string]bool{},
+		abilities:        map[string]bool{},
 		currentSpell:     "", //"spell-bullet"
 		animations: map[string]*Animation{
-			"run": {
-				image:           game.res.GetImage("player-run"),
-				numFrames:       6,
-				size:            32,
-				frameTimeAmount: 0.1,
-				isLoop:          true,
-			},
-			"run-cast": {
-				image:           game.res.GetImage("player-run-cast"),
-				numFrames:       6,
-				size:            32,
-				frameTimeAmount: 0.1,
-				isLoop:          true,
-			},
-			"idle": {
-				image:           game.res.GetImage("player-idle"),
-				numFrames:       1,
-				size:            32,
-				frameTimeAmount: 1,
-				isLoop:          true,
-			},
-			"crouch": {
-				image:           game.res.GetImage("player-crouch"),
-				numFrames:       1,
-				size:            32,
-				frameTimeAmount: 1,
-				isLoop:          true,
-			},
-			"jump": {
-				image:           game.res.GetImage("player-jump"),
-				numFrames:       1,
-				size:            32,
-				frameTimeAmount: 1,
-				isLoop:          true,
-			},
-			"jump-cast": {
-				image:           game.res.GetImage("player-jump-cast"),
-				numFrames:       1,
-				size:            32,
-				frameTimeAmount: 1,
-				isLoop:          true,
-			},
-			"death": {
-				image:           game.res.GetImage("player-death"),
-				numFrames:       3,
-				size:            32,
-				frameTimeAmount: 0.4,
-				isLoop:          false,
-			},
-			"fall": {
-				image:           game.res.GetImage("player-fall"),
-				numFrames:       1,
-				size:            32,
-				frameTimeAmount: 1,
-				isLoop:          true,
-			},
-			"fall-cast": {
-				image:           game.res.GetImage("player-fall-cast"),
-				numFrames:       1,
-				size:            32,
-				frameTimeAmount: 1,
-				isLoop:          true,
-			},
-			"climb": {
-				image:           game.res.GetImage("player-climb"),
-				numFrames:       2,
-				size:            32,
-				frameTimeAmount: 0.2,
-				isLoop:          true,
-			},
-			"hurt": {
-				image:           game.res.GetImage("player-hurt"),
-				numFrames:       1,
-				size:            32,
-				frameTimeAmount: 1,
-				isLoop:          true,
-			},
-			"cast": {
-				image:           game.res.GetImage("player-cast"),
-				numFrames:       3,
-				size:            32,
-				frameTimeAmount: 0.1,
-				isLoop:          false,
-			},
+			"run":         newAnimation(game, "player-run", true),
+			"run-cast":    newAnimation(game, "player-run-cast", true),
+			"idle":        newAnimation(game, "player-idle", true),
+			"crouch":      newAnimation(game, "player-crouch", true),
+			"jump":        newAnimation(game, "player-jump", true),
+			"jump-cast":   newAnimation(game, "player-jump-cast", true),
+			"death":       newAnimation(game, "player-death", false).withFrameTime(0.4),
+			"fall":        newAnimation(game, "player-fall", true),
+			"fall-cast":   newAnimation(game, "player-fall-cast", true),
+			"climb":       newAnimation(game, "player-climb", true).withFrameTime(0.2),
+			"hurt":        newAnimation(game, "player-hurt", true),
+			"cast":        newAnimation(game, "player-cast", false),
+			"wall-slide":  newAnimation(game, "player-wall-slide", true),
+			"wall-jump":   newAnimation(game, "player-wall-jump", true),
+			"double-jump": newAnimation(game, "player-double-jump", true),
 		},
 		velocityY:       0,
 		velocityX:       0,
 		targetVelocityX: 0,
+		standingOn:      common.EmptyTile,
+		physics:         game.Level.physics,
 	}
 	return p
 }
 
-func (r *Player) Update(delta float64, game *Game) {
-	var aimY float64
+// playerInput is what the controls ask the player to do this frame.
+type playerInput struct {
+	moveX    float64
+	moveY    float64
+	drop     bool
+	jump     bool
+	holdJump bool
+	cast     bool
+}
 
-	switch r.state {
-	case dyingState:
-		r.deathTimer -= delta
-		if r.deathTimer < 0 {
-			game.PlayerDeath()
-		}
-		r.currentAnimation = "death"
-		r.animations[r.currentAnimation].Update(delta)
-	case playingState:
-		var tryJump bool
-		var pressJump bool
-		var tryFall bool
-		var tryMoveY = 0.0
-		r.targetVelocityX = 0
-		r.currentAnimation = "idle"
-		shouldUpdateAnimation := false
-
-		if r.takeDamageTimer <= 0 {
-			if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
-				r.targetVelocityX = -maxRunVelocity
-				r.isFlip = true
-				r.currentAnimation = "run"
-				if r.lockedToLadder {
-					r.targetVelocityX = -maxRunVelocity / 2.0
-				}
-				shouldUpdateAnimation = true
-			}
-			if inpututil.IsKeyJustPressed(ebiten.KeyC) {
-				game.Actions.OpenBook("my title", "My friend,\n\n\nI have fallen and can't get up.\n\nCan you help me?\n\nI can write a PROPER sentence now, full of lore and \nsuspense.")
-			}
-			if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
-				r.targetVelocityX = maxRunVelocity
-				r.isFlip = false
-				r.currentAnimation = "run"
-				if r.lockedToLadder {
-					r.targetVelocityX = maxRunVelocity / 2.0
-				}
-				shouldUpdateAnimation = true
-			}
-			r.isCrouch = false
-			if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
-				tryFall = true
-				tryMoveY = 1
-				shouldUpdateAnimation = true
-				if r.targetVelocityX == 0 {
-					r.currentAnimation = "crouch"
-				}
-				r.isCrouch = true
-			}
-			if ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
-				tryMoveY = -1
-				shouldUpdateAnimation = true
-			}
+// playerMovement is what happened when the player moved, for the state to pick what is next.
+type playerMovement struct {
+	onFloor     bool
+	climbing    bool
+	wallSliding bool
+}
 
-			if ebiten.IsKeyPressed(ebiten.KeySpace) {
-				pressJump = true
-				shouldUpdateAnimation = true
-			}
+func (r *Player) Update(delta float64, game *Game) {
+	if game.noClip {
+		r.updateNoClip(delta, game.input)
+		return
+	}
 
-			if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-				tryJump = true
-				pressJump = true
-				r.lateJumpTimer = lateJumpMarginTime
-				shouldUpdateAnimation = true
-			}
-		} else {
-			r.targetVelocityX = maxRunVelocity / 2.0
-			if r.isFlip {
-				r.targetVelocityX = r.targetVelocityX * -1
-			}
-		}
+	// the level's profile can be changed or reloaded while playing
+	r.physics = game.Level.physics
+	r.input = readPlayerInput(game.input)
+	if r.input.cast && r.state != playerHurt && r.state != playerDying {
+		game.Actions.OpenBook("my title", "My friend,\n\n\nI have fallen and can't get up.\n\nCan you help me?\n\nI can write a PROPER sentence now, full of lore and \nsuspense.")
+	}
+	state := playerStates[r.state]
+	next := state.Update(r, delta, game)
+	// the update can change the state itself, from taking damage
+	if next != "" && r.state == state.Name {
+		r.changeState(next, game)
+	}
+	if r.postDamageTimer > 0 {
+		r.postDamageTimer -= delta
+	}
 
-		r.lateJumpTimer = r.lateJumpTimer - delta
-		time := standardJumpTime
-		if r.jumpTimer > (standardJumpTime) {
-			time = standardFallTime
+	state = playerStates[r.state]
+	r.currentAnimation = state.Animation(r)
+	if state.Animate == nil || state.Animate(r) {
+		r.animations[r.currentAnimation].Update(delta)
+	}
+	if r.castTimer > 0 {
+		r.castTimer = r.castTimer - delta
+		if state.CastAnimation != "" {
+			r.currentAnimation = state.CastAnimation
+			r.animations[r.currentAnimation].Update(delta)
 		}
-		gravity := (standardJumpHeight * -2) / (time * time)
+	}
+
+	if r.state != playerDying {
+		r.updateCasting(delta, game)
+	}
+}
+
+func readPlayerInput(keys Input) playerInput {
+	input := playerInput{
+		drop:     keys.IsKeyPressed(ebiten.KeyArrowDown),
+		jump:     keys.IsKeyJustPressed(ebiten.KeySpace),
+		holdJump: keys.IsKeyPressed(ebiten.KeySpace),
+		cast:     keys.IsKeyJustPressed(ebiten.KeyC),
+	}
+	if keys.IsKeyPressed(ebiten.KeyArrowLeft) {
+		input.moveX = -1
+	}
+	if keys.IsKeyPressed(ebiten.KeyArrowRight) {
+		input.moveX = 1
+	}
+	if input.drop {
+		input.moveY = 1
+	}
+	if keys.IsKeyPressed(ebiten.KeyArrowUp) {
+		input.moveY = -1
+	}
+	return input
+}
 
-		oldx := r.x
-		oldy := r.y
+// steer sets the speed the player is trying to run at from the controls, facing the way they
+// go. Straight after a wall jump they keep going away from the wall.
+func (r *Player) steer(speed float64) {
+	r.targetVelocityX = r.input.moveX * speed
+	if r.input.moveX != 0 {
+		r.isFlip = r.input.moveX < 0
+	}
+	if r.wallJumpTimer > 0 {
+		r.targetVelocityX = r.wallJumpDirection * r.physics.MaxRunVelocity
+		r.isFlip = r.wallJumpDirection < 0
+	}
+}
 
-		partial := 4.0
+// move runs the physics for a frame, gravity, jumping, ladders, walls and water, towards the
+// target velocity the state has set.
+func (r *Player) move(delta float64, game *Game) playerMovement {
+	input := r.input
+	climbing := r.state == playerClimb
+	if r.wallJumpTimer > 0 {
+		r.wallJumpTimer = r.wallJumpTimer - delta
+	}
+	if r.inWater {
+		r.targetVelocityX = r.targetVelocityX * swimSpeedScale
+	}
+	if input.jump {
+		r.lateJumpTimer = r.physics.LateJumpTime
+	}
 
-		newx := r.x + (delta * r.velocityX)
-		movey := 0.0
-		if !r.lockedToLadder {
-			movey = (r.velocityY * delta) + (0.5 * gravity * delta * delta)
-		}
-		newy := r.y - movey
-		r.velocityY = r.velocityY + (gravity * delta)
+	r.lateJumpTimer = r.lateJumpTimer - delta
+	gravity := r.physics.gravity(r.jumpTimer)
+	if r.inWater {
+		gravity = swimGravity
+		r.velocityY = math.Max(r.velocityY, -swimMaxSinkVelocity)
+	}
 
-		cr := DoCollision(oldx+partial, oldy+partial, newx+partial, newy+partial, r.sizex-partial-partial, r.sizey+partial, game.Level, tryFall)
+	oldx := r.x
+	oldy := r.y
 
-		newx = cr.newX - partial
-		newy = cr.newY - partial
+	partial := 4.0
 
-		if cr.hitFloor {
-			r.velocityY = 0
-			r.coyoteTimer = coyoteTimeAmount
-		}
+	newx := r.x + surfaceMoveX(r.velocityX, r.standingOn, delta)
+	movey := 0.0
+	if !climbing {
+		movey = (r.velocityY * delta) + (0.5 * gravity * delta * delta)
+	}
+	newy := r.y - movey
+	r.velocityY = r.velocityY + (gravity * delta)
+
+	box := CollisionBox{x: oldx + partial, y: oldy + partial, w: r.sizex - partial - partial, h: r.sizey + partial}
+	cr := DoCollision(box, newx-oldx, newy-oldy, game.Level, CollisionOptions{
+		dropThrough: input.drop,
+		groundSnap:  r.touchingGround && r.velocityY <= 0 && !climbing,
+	})
+	r.touchingGround = cr.hitFloor
+	r.standingOn = cr.floorTile()
+
+	newx = cr.newX - partial
+	newy = cr.newY - partial
+
+	if cr.hitFloor {
+		r.velocityY = 0
+		r.coyoteTimer = r.physics.CoyoteTime
+	}
 
-		var touchingLadder = false
-		tx, ty := int((oldx+(r.sizex/2.0))/common.TileSize), int((oldy+partial)/common.TileSize)
-		td := game.Level.tiledGrid.GetTileData(tx, ty)
-		if td.Ladder {
-			touchingLadder = true
-			if tryMoveY != 0 {
-				middle := float64(tx * common.TileSize)
-				left, right := middle-ladderGrabAllowance, middle+ladderGrabAllowance
-				if oldx > left && oldx < right {
-					r.lockedToLadder = true
-					newy = oldy + (delta * ladderVelocity * tryMoveY)
-				}
+	var touchingLadder = false
+	tx, ty := int((oldx+(r.sizex/2.0))/common.TileSize), int((oldy+partial)/common.TileSize)
+	td := game.Level.tiledGrid.GetTileData(tx, ty)
+	if td.Ladder {
+		touchingLadder = true
+		if input.moveY != 0 {
+			middle := float64(tx * common.TileSize)
+			left, right := middle-ladderGrabAllowance, middle+ladderGrabAllowance
+			if oldx > left && oldx < right {
+				climbing = true
+				newy = oldy + (delta * r.physics.LadderVelocity * input.moveY)
 			}
-
 		}
-		tx, ty = int((oldx+(r.sizex/2.0))/common.TileSize), int((oldy+r.sizey+partial+partial)/common.TileSize)
-		td = game.Level.tiledGrid.GetTileData(tx, ty)
-		if td.Ladder {
-			touchingLadder = true
-			if tryMoveY != 0 {
-				middle := float64(tx * common.TileSize)
-				left, right := middle-ladderGrabAllowance, middle+ladderGrabAllowance
-				if oldx > left && oldx < right {
-					r.lockedToLadder = true
-					newy = oldy + (delta * ladderVelocity * tryMoveY)
-
-					// if move down, check for block
-					if tryMoveY == 1 {
-						tx, ty = int((oldx+(r.sizex/2.0))/common.TileSize), int((newy+r.sizey+partial+partial)/common.TileSize)
-						td = game.Level.tiledGrid.GetTileData(tx, ty)
-						if td.Block {
-							r.lockedToLadder = false
-							newy = oldy
-						}
+
+	}
+	tx, ty = int((oldx+(r.sizex/2.0))/common.TileSize), int((oldy+r.sizey+partial+partial)/common.TileSize)
+	td = game.Level.tiledGrid.GetTileData(tx, ty)
+	if td.Ladder {
+		touchingLadder = true
+		if input.moveY != 0 {
+			middle := float64(tx * common.TileSize)
+			left, right := middle-ladderGrabAllowance, middle+ladderGrabAllowance
+			if oldx > left && oldx < right {
+				climbing = true
+				newy = oldy + (delta * r.physics.LadderVelocity * input.moveY)
+
+				// if move down, check for block
+				if input.moveY == 1 {
+					tx, ty = int((oldx+(r.sizex/2.0))/common.TileSize), int((newy+r.sizey+partial+partial)/common.TileSize)
+					td = game.Level.tiledGrid.GetTileData(tx, ty)
+					if td.Block {
+						climbing = false
+						newy = oldy
 					}
 				}
 			}
 		}
-		if !touchingLadder {
-			r.lockedToLadder = false
-		}
-		var hitDamage bool
-		tx, ty = int((oldx+(r.sizex/2.0))/common.TileSize), int((oldy+r.sizey)/common.TileSize)
-		td = game.Level.tiledGrid.GetTileData(tx, ty)
-		if td.Damage {
-			hitDamage = true
-		}
-
-		r.x = newx
-		r.y = newy
+	}
+	if !touchingLadder {
+		climbing = false
+	}
+	var hitDamage bool
+	var tileDamage DamageInfo
+	tx, ty = int((oldx+(r.sizex/2.0))/common.TileSize), int((oldy+r.sizey)/common.TileSize)
+	td = game.Level.tiledGrid.GetTileData(tx, ty)
+	if td.Damage {
+		hitDamage = true
+		tileDamage = newTileDamage(td.DamageAmount, tx, ty)
+	}
 
-		if tryJump || r.lateJumpTimer > 0 {
-			if cr.hitFloor || r.coyoteTimer > 0 || r.lockedToLadder {
-				r.lockedToLadder = false
-				r.coyoteTimer = 0
-				r.velocityY = (2 * standardJumpHeight) / standardJumpTime
-				r.jumpTimer = 0
-				r.alreadyAbortedJump = false
-			}
-		}
-		if !pressJump {
-			// if Player is currently jumping in the first half phase of jumping
-			if r.jumpTimer < (standardJumpTime*0.5) && r.wasPressingJump && !r.alreadyAbortedJump {
-				r.alreadyAbortedJump = true
-				r.velocityY = (2 * minimumJumpHeight) / (standardJumpTime)
+	r.x = newx
+	r.y = newy
+	wasInWater := r.inWater
+	r.inWater = game.Level.IsInWater(r.x+(r.sizex/2), r.y+(r.sizey/2))
+	r.splashTimer = r.splashTimer - delta
+	if r.inWater != wasInWater && canSplash(r.velocityY, r.splashTimer) {
+		r.splashTimer = splashCooldown
+		game.SpawnEffect(effectSplash, r.x+(r.sizex/2), r.y+(r.sizey/2), false, 0)
+	}
+	r.updateAir(delta, game)
+
+	r.wallDirection = 0
+	if r.HasAbility(abilityWallJump) && !cr.hitFloor && !climbing && !r.inWater {
+		wallBox := CollisionBox{x: r.x + partial, y: r.y + partial, w: r.sizex - partial - partial, h: r.sizey}
+		for _, direction := range []float64{-1, 1} {
+			if WallContact(wallBox, direction, game.Level) != nil {
+				r.wallDirection = direction
 			}
 		}
-		r.jumpTimer = r.jumpTimer + delta
-		if r.coyoteTimer > 0 {
-			r.coyoteTimer = r.coyoteTimer - delta
-		}
-		if cr.hitFloor {
-			r.lockedToLadder = false
-		}
-
-		if oldy != newy {
-			if r.velocityY <= 0 {
-				r.currentAnimation = "fall"
-			}
-			if r.velocityY > 0 {
-				r.currentAnimation = "jump"
-			}
+	}
+	wallSliding := r.wallDirection != 0 && r.wallDirection == input.moveX && r.velocityY <= 0
+	if wallSliding {
+		r.velocityY = math.Max(r.velocityY, -r.physics.WallSlideMaxVelocity)
+		r.isFlip = r.wallDirection > 0
+		r.dustTimer = r.dustTimer - delta
+		if r.dustTimer <= 0 {
+			r.dustTimer = wallSlideDustTime
+			r.spawnWallDust(game)
 		}
-		if r.lockedToLadder {
-			r.currentAnimation = "climb"
-			r.velocityY = 0
+	}
+	wallJumped := false
+
+	if input.jump && r.inWater && r.isHeadInWater(game) && !climbing {
+		// under water each press is a stroke upwards, a full jump needs the head out
+		r.velocityY = swimStrokeVelocity
+		r.jumpTimer = 0
+		r.lateJumpTimer = 0
+		r.alreadyAbortedJump = true
+	} else if input.jump || r.lateJumpTimer > 0 {
+		if cr.hitFloor || r.coyoteTimer > 0 || climbing || r.inWater {
+			climbing = false
+			r.coyoteTimer = 0
+			r.velocityY = r.physics.jumpVelocity(r.physics.JumpHeight)
+			r.jumpTimer = 0
 			r.alreadyAbortedJump = false
+		} else if r.wallDirection != 0 {
+			r.wallJump(game)
+			wallJumped = true
+		} else if input.jump && r.HasAbility(abilityDoubleJump) && !r.doubleJumpUsed {
+			r.doubleJump(game)
 		}
-		if cr.hitCeiling {
+	}
+	if !input.holdJump {
+		// if Player is currently jumping in the first half phase of jumping
+		if r.jumpTimer < (r.physics.JumpTime*0.5) && r.wasPressingJump && !r.alreadyAbortedJump {
 			r.alreadyAbortedJump = true
-			r.velocityY = -20
-		}
-		r.wasPressingJump = pressJump
-		if cr.hitWall {
-			r.targetVelocityX = 0
-			r.velocityX = 0
-		}
-		if (tryMoveY == 1 && !cr.hitFloor) || tryMoveY == -1 {
-			aimY = tryMoveY
-		}
-
-		if r.velocityX < r.targetVelocityX {
-			r.velocityX = r.velocityX + runAcc
-			if r.velocityX > r.targetVelocityX {
-				r.velocityX = r.targetVelocityX
-			}
-		}
-		if r.velocityX > r.targetVelocityX {
-			r.velocityX = r.velocityX - runAcc
-			if r.velocityX < r.targetVelocityX {
-				r.velocityX = r.targetVelocityX
-			}
-		}
-		if r.castTimer > 0 {
-			r.castTimer = r.castTimer - delta
-			switch r.currentAnimation {
-			case "run":
-				r.currentAnimation = "run-cast"
-				r.animations["run"].Update(delta)
-				shouldUpdateAnimation = true
-			case "jump":
-				r.currentAnimation = "jump-cast"
-			case "fall":
-				r.currentAnimation = "fall-cast"
-			default:
-				shouldUpdateAnimation = true
-				r.currentAnimation = "cast"
-			}
-
-		}
-		if hitDamage {
-			game.Player.TakeDamage(game)
-		}
-		if r.takeDamageTimer > 0 {
-			r.takeDamageTimer -= delta
-			if r.takeDamageTimer < 0 {
-				r.postDamageTimer = postDamageTime
-			}
-			r.currentAnimation = "hurt"
-		}
-		if r.postDamageTimer > 0 {
-			r.postDamageTimer -= delta
-			if r.postDamageTimer < 0 {
-				// normal
-			}
+			r.velocityY = r.physics.jumpVelocity(r.physics.MinimumJumpHeight)
 		}
+	}
+	r.jumpTimer = r.jumpTimer + delta
+	if r.coyoteTimer > 0 {
+		r.coyoteTimer = r.coyoteTimer - delta
+	}
+	if cr.hitFloor {
+		climbing = false
+		r.doubleJumpUsed = false
+	}
+	if r.velocityY <= 0 || cr.hitFloor {
+		r.doubleJumping = false
+	}
+	if bounce, bounced := surfaceBounce(cr, r.velocityY); bounced {
+		// springs launch harder than a jump and cannot be cut short by letting go
+		r.velocityY = bounce
+		r.jumpTimer = 0
+		r.coyoteTimer = 0
+		r.alreadyAbortedJump = true
+	}
+	if climbing {
+		r.velocityY = 0
+		r.alreadyAbortedJump = false
+	}
+	if cr.hitCeiling {
+		r.alreadyAbortedJump = true
+		r.velocityY = -20
+	}
+	r.wasPressingJump = input.holdJump
+	if cr.hitWall && !wallJumped {
+		r.targetVelocityX = 0
+		r.velocityX = 0
+	}
+	r.aimY = 0
+	if (input.moveY == 1 && !cr.hitFloor) || input.moveY == -1 {
+		r.aimY = input.moveY
+	}
 
-		if shouldUpdateAnimation {
-			r.animations[r.currentAnimation].Update(delta)
-		}
+	acc := surfaceAcc(r.physics.RunAcc, r.standingOn, r.touchingGround)
+	r.velocityX = approach(r.velocityX, r.targetVelocityX, acc)
+	if hitDamage {
+		r.TakeDamage(tileDamage, game)
+	}
+	return playerMovement{
+		onFloor:     cr.hitFloor,
+		climbing:    climbing,
+		wallSliding: wallSliding && !wallJumped,
 	}
+}
 
+// updateCasting casts the current spell, aimed the way the player is pressing.
+func (r *Player) updateCasting(delta float64, game *Game) {
 	r.castSpellTimer = r.castSpellTimer - delta
-	if inpututil.IsKeyJustPressed(ebiten.KeyD) {
+	if game.input.IsKeyJustPressed(ebiten.KeyS) {
+		r.NextSpell()
+		game.PlayerProgress.mostRecentSpell = r.currentSpell
+	}
+	if game.input.IsKeyJustPressed(ebiten.KeyD) {
 		if r.castSpellTimer < 0 {
 			switch r.currentSpell {
-			case "spell-bullet":
+			case spellBullet, spellRay:
 				r.castSpellTimer = castSpellCoolDownTime
 				r.castTimer = castSpellTimeTotal
 				r.animations["cast"].Reset()
@@ -453,17 +446,17 @@ func (r *Player) Update(delta float64, game *Game) {
 				var posY float64
 
 				// shoot up or down
-				if aimY != 0 {
+				if r.aimY != 0 {
 					moveY = -spellBulletSpeed
 					posX = r.x
 					posY = r.y - 8
-					if aimY == 1 {
+					if r.aimY == 1 {
 						moveY = spellBulletSpeed
 						posY = r.y + 16
 					}
 					ey := r.y - 12
 					rot := -ninetyDegreesInRads
-					if aimY == 1 {
+					if r.aimY == 1 {
 						ey = r.y + 24
 						rot = ninetyDegreesInRads
 					}
@@ -478,7 +471,7 @@ func (r *Player) Update(delta float64, game *Game) {
 					}
 
 					posY = r.y - 2
-					if r.isCrouch {
+					if r.state == playerCrouch {
 						posY = r.y + 6
 					}
 					offsetAmount := 12.0
@@ -491,14 +484,35 @@ func (r *Player) Update(delta float64, game *Game) {
 					}
 					game.SpawnEffect(effectCastSpell, ex, r.y, r.isFlip, 0)
 				}
-				spellObj := NewSpellObject(game, posX, posY, moveX, moveY)
-				game.AddSpellObject(spellObj)
-
+				if r.currentSpell == spellRay {
+					game.CastRay(posX+8, posY+8, moveX, moveY)
+				} else {
+					game.AddProjectile(NewSpellBullet(game, posX, posY, moveX, moveY))
+				}
 			}
 		}
 	}
+}
 
-	game.debug.DrawBox(color.Black, r.x, r.y, common.TileSize, common.TileSize)
+// updateNoClip flies the player with the arrow keys, through walls and without gravity.
+func (r *Player) updateNoClip(delta float64, input Input) {
+	r.velocityX, r.velocityY = 0, 0
+	r.currentAnimation = "fall"
+	if input.IsKeyPressed(ebiten.KeyArrowLeft) {
+		r.x = r.x - (noClipVelocity * delta)
+		r.isFlip = true
+	}
+	if input.IsKeyPressed(ebiten.KeyArrowRight) {
+		r.x = r.x + (noClipVelocity * delta)
+		r.isFlip = false
+	}
+	if input.IsKeyPressed(ebiten.KeyArrowUp) {
+		r.y = r.y - (noClipVelocity * delta)
+	}
+	if input.IsKeyPressed(ebiten.KeyArrowDown) {
+		r.y = r.y + (noClipVelocity * delta)
+	}
+	r.animations[r.currentAnimation].Update(delta)
 }
 
 func (r *Player) Draw(camera common.Camera) {
@@ -519,32 +533,162 @@ func (r *Player) Draw(camera common.Camera) {
 	camera.DrawImage(r.animations[r.currentAnimation].GetCurrentFrame(), op)
 }
 
+// GetCollisionBox is the part of the player that enemies and projectiles hit.
+func (r *Player) GetCollisionBox() CollisionBox {
+	return CollisionBox{
+		x: r.x + 4,
+		y: r.y + 8,
+		w: 8,
+		h: 8,
+	}
+}
+
 func (r *Player) GetPos() (float64, float64) {
 	return r.x, r.y
 }
 
-func (r *Player) TakeDamage(game *Game) {
+// cameraFollow recentres the camera when the player lands or climbs, and looks up or down
+// while they stand still holding up or down.
+func (r *Player) cameraFollow() cameraFollow {
+	follow := cameraFollow{
+		facing:    1,
+		velocityX: r.velocityX,
+		settled:   r.touchingGround || r.state == playerClimb,
+	}
+	if r.isFlip {
+		follow.facing = -1
+	}
+	if r.state == playerIdle || r.state == playerCrouch {
+		follow.look = r.input.moveY
+	}
+	return follow
+}
+
+// wallJump kicks the player up and away from the wall they are next to, locking their
+// horizontal movement for a moment.
+func (r *Player) wallJump(game *Game) {
+	r.spawnWallDust(game)
+	r.wallJumpDirection = -r.wallDirection
+	r.wallJumpTimer = r.physics.WallJumpLockTime
+	r.velocityY = r.physics.jumpVelocity(r.physics.JumpHeight)
+	r.velocityX = r.wallJumpDirection * r.physics.MaxRunVelocity
+	r.targetVelocityX = r.velocityX
+	r.isFlip = r.wallJumpDirection < 0
+	r.jumpTimer = 0
+	r.lateJumpTimer = 0
+	r.alreadyAbortedJump = false
+	r.animations["wall-jump"].Reset()
+}
+
+// doubleJump is a second jump in the air. It starts a new jump, so letting go early still cuts
+// it short like the first one.
+func (r *Player) doubleJump(game *Game) {
+	r.doubleJumpUsed = true
+	r.doubleJumping = true
+	r.velocityY = r.physics.jumpVelocity(r.physics.JumpHeight)
+	r.jumpTimer = 0
+	r.lateJumpTimer = 0
+	r.alreadyAbortedJump = false
+	r.animations["double-jump"].Reset()
+	game.SpawnEffect(effectDoubleJump, r.x+(r.sizex/2), r.y+18, false, 0)
+}
+
+// RefreshDoubleJump gives back a used double jump, it is false when there was nothing to give
+// back.
+func (r *Player) RefreshDoubleJump() bool {
+	if !r.HasAbility(abilityDoubleJump) || !r.doubleJumpUsed {
+		return false
+	}
+	r.doubleJumpUsed = false
+	return true
+}
+
+// spawnWallDust puts a puff of dust where the player's hand is on the wall.
+func (r *Player) spawnWallDust(game *Game) {
+	x := r.x + (r.sizex / 2) + (r.wallDirection * ((r.sizex / 2) - 4))
+	game.SpawnEffect(effectDust, x, r.y+14, r.wallDirection < 0, 0)
+}
+
+// HasAbility is true once the player has picked up a movement ability.
+func (r *Player) HasAbility(ability string) bool {
+	return r.abilities[ability]
+}
+
+func (r *Player) isHeadInWater(game *Game) bool {
+	return game.Level.IsInWater(r.x+(r.sizex/2), r.y+4)
+}
+
+// updateAir uses up air while the player's head is under water, in levels with an air time,
+// and hurts them once it has run out.
+func (r *Player) updateAir(delta float64, game *Game) {
+	airTime := game.Level.airTime
+	if airTime <= 0 || !r.isHeadInWater(game) {
+		r.airUsed = math.Max(0, r.airUsed-(delta*airRefillSpeed))
+		return
+	}
+	r.airUsed = math.Min(airTime, r.airUsed+delta)
+	if r.airUsed >= airTime {
+		r.TakeDamage(DamageInfo{
+			Amount:  1,
+			Type:    damageTypeDrown,
+			SourceX: r.x + (r.sizex / 2),
+			SourceY: r.y + (r.sizey / 2),
+		}, game)
+	}
+}
+
+// GetAir returns how much air the player has left, from 0 to 1, and whether the hud should
+// show it. It is only shown while some has been used.
+func (r *Player) GetAir(game *Game) (float64, bool) {
+	airTime := game.Level.airTime
+	if airTime <= 0 || r.airUsed <= 0 {
+		return 1, false
+	}
+	return 1 - (r.airUsed / airTime), true
+}
+
+func (r *Player) TakeDamage(damage DamageInfo, game *Game) {
+	if game.godMode {
+		return
+	}
 	// already busy taking damage
-	if r.takeDamageTimer > 0 {
+	if r.state == playerHurt || r.state == playerDying {
 		return
 	}
 	// a bit of iframes after damage
 	if r.postDamageTimer > 0 {
 		return
 	}
-	r.Health -= 1
+	r.Health -= damage.Amount
+	game.Feedback(feedbackPlayerHurt)
 	if r.Health > 0 {
-		r.takeDamageTimer = takeDamageTime
-		r.ForceJump()
+		r.applyKnockback(damage)
+		r.changeState(playerHurt, game)
 	} else {
-		r.deathTimer = playerDeathTime
-		r.state = dyingState
+		r.changeState(playerDying, game)
 	}
 }
 
+// applyKnockback throws the player away from whatever hurt them, turning to face it. Hits
+// from above push the player down instead of bouncing them up.
+func (r *Player) applyKnockback(damage DamageInfo) {
+	dirX, dirY := damage.directionFrom(r.x+(r.sizex/2), r.y+(r.sizey/2))
+	r.knockbackVelocityX = dirX * damage.Knockback
+	r.velocityX = r.knockbackVelocityX
+	if dirX != 0 {
+		r.isFlip = dirX > 0
+	}
+	if dirY > 0 {
+		r.alreadyAbortedJump = true
+		r.velocityY = -dirY * damage.Knockback
+		return
+	}
+	r.ForceJump()
+}
+
 func (r *Player) ForceJump() {
 	r.alreadyAbortedJump = true
-	r.velocityY = (2 * forcedJumpHeight) / (standardJumpTime)
+	r.velocityY = r.physics.jumpVelocity(r.physics.ForcedJumpHeight)
 }
 
 func (r *Player) AddHealth(amount int) {
@@ -563,3 +707,22 @@ func (r *Player) AddSpell(spell string) {
 		r.currentSpell = spell
 	}
 }
+
+// NextSpell switches to the next learned spell, in name order.
+func (r *Player) NextSpell() {
+	names := []string{}
+	for name := range r.spells {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	next := names[0]
+	for i, name := range names {
+		if name == r.currentSpell && i+1 < len(names) {
+			next = names[i+1]
+		}
+	}
+	r.currentSpell = next
+}
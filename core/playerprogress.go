@@ -3,6 +3,8 @@ package core
 type PlayerProgress struct {
 	mostRecentSpell string
 	spells          map[string]bool
+	abilities       map[string]bool
+	defeatedBosses  map[string]bool
 }
 
 func (r *PlayerProgress) AddSpell(spell string) {
@@ -18,4 +20,22 @@ func (r *PlayerProgress) AddSpell(spell string) {
 func (r *PlayerProgress) HydratePlayer(player *Player) {
 	player.currentSpell = r.mostRecentSpell
 	player.spells = r.spells
+	player.abilities = r.abilities
+}
+
+// AddAbility unlocks a movement ability, the player shares the map so it works straight away.
+func (r *PlayerProgress) AddAbility(ability string) {
+	r.abilities[ability] = true
+}
+
+func (r *PlayerProgress) HasAbility(ability string) bool {
+	return r.abilities[ability]
+}
+
+func (r *PlayerProgress) DefeatBoss(id string) {
+	r.defeatedBosses[id] = true
+}
+
+func (r *PlayerProgress) IsBossDefeated(id string) bool {
+	return r.defeatedBosses[id]
 }
@@ -0,0 +1,238 @@
+package core
+
+import "log"
+
+const (
+	playerIdle      = "idle"
+	playerRun       = "run"
+	playerCrouch    = "crouch"
+	playerJump      = "jump"
+	playerFall      = "fall"
+	playerWallSlide = "wall-slide"
+	playerClimb     = "climb"
+	playerHurt      = "hurt"
+	playerDying     = "dying"
+)
+
+// PlayerState is one thing the player can be doing. Enter and Exit run when the player changes
+// to and from the state, Update runs every frame while in it and returns the state to change
+// to, or nothing to stay. The player can only change to the states in Transitions.
+// Animation picks what to draw, CastAnimation is drawn over it while casting and Animate,
+// when set, says whether the animation should play this frame.
+type PlayerState struct {
+	Name          string
+	Transitions   []string
+	Animation     func(r *Player) string
+	CastAnimation string
+	Animate       func(r *Player) bool
+	Enter         func(r *Player, game *Game)
+	Exit          func(r *Player, game *Game)
+	Update        func(r *Player, delta float64, game *Game) string
+}
+
+// PlayerStateListener is told every time the player changes state.
+type PlayerStateListener func(from, to string)
+
+var playerStates = map[string]*PlayerState{}
+
+func init() {
+	for _, state := range []*PlayerState{
+		{
+			Name:          playerIdle,
+			Transitions:   []string{playerRun, playerCrouch, playerJump, playerFall, playerWallSlide, playerClimb, playerHurt, playerDying},
+			Animation:     playerAnimation("idle"),
+			CastAnimation: "cast",
+			Update:        updatePlayerMoving,
+		},
+		{
+			Name:          playerRun,
+			Transitions:   []string{playerIdle, playerCrouch, playerJump, playerFall, playerWallSlide, playerClimb, playerHurt, playerDying},
+			Animation:     playerAnimation("run"),
+			CastAnimation: "run-cast",
+			Update:        updatePlayerMoving,
+		},
+		{
+			Name:          playerCrouch,
+			Transitions:   []string{playerIdle, playerRun, playerJump, playerFall, playerWallSlide, playerClimb, playerHurt, playerDying},
+			Animation:     playerAnimation("crouch"),
+			CastAnimation: "cast",
+			Update:        updatePlayerMoving,
+		},
+		{
+			Name:          playerJump,
+			Transitions:   []string{playerIdle, playerRun, playerCrouch, playerFall, playerWallSlide, playerClimb, playerHurt, playerDying},
+			Animation:     playerJumpAnimation,
+			CastAnimation: "jump-cast",
+			Update:        updatePlayerMoving,
+		},
+		{
+			Name: playerFall,
+			// jumping again from a fall is coyote time, a double jump or a spring
+			Transitions:   []string{playerIdle, playerRun, playerCrouch, playerJump, playerWallSlide, playerClimb, playerHurt, playerDying},
+			Animation:     playerAnimation("fall"),
+			CastAnimation: "fall-cast",
+			Update:        updatePlayerMoving,
+		},
+		{
+			Name:          playerWallSlide,
+			Transitions:   []string{playerIdle, playerRun, playerCrouch, playerJump, playerFall, playerClimb, playerHurt, playerDying},
+			Animation:     playerAnimation("wall-slide"),
+			CastAnimation: "fall-cast",
+			Update:        updatePlayerMoving,
+		},
+		{
+			Name:          playerClimb,
+			Transitions:   []string{playerIdle, playerRun, playerCrouch, playerJump, playerFall, playerWallSlide, playerHurt, playerDying},
+			Animation:     playerAnimation("climb"),
+			CastAnimation: "cast",
+			Animate:       func(r *Player) bool { return r.input.moveX != 0 || r.input.moveY != 0 },
+			Enter:         enterPlayerClimb,
+			Update:        updatePlayerClimb,
+		},
+		{
+			Name: playerHurt,
+			// the controls are ignored while hurt, so it ends standing, thrown up or falling,
+			// and more damage waits until it is over
+			Transitions: []string{playerIdle, playerJump, playerFall},
+			Animation:   playerAnimation("hurt"),
+			Enter:       enterPlayerHurt,
+			Exit:        exitPlayerHurt,
+			Update:      updatePlayerHurt,
+		},
+		{
+			Name:        playerDying,
+			Transitions: []string{},
+			Animation:   playerAnimation("death"),
+			Enter:       enterPlayerDying,
+			Update:      updatePlayerDying,
+		},
+	} {
+		playerStates[state.Name] = state
+	}
+}
+
+func playerAnimation(name string) func(r *Player) string {
+	return func(r *Player) string {
+		return name
+	}
+}
+
+// playerJumpAnimation shows how the player left the ground, for as long as they are going up.
+func playerJumpAnimation(r *Player) string {
+	if r.wallJumpTimer > 0 {
+		return "wall-jump"
+	}
+	if r.doubleJumping {
+		return "double-jump"
+	}
+	return "jump"
+}
+
+// updatePlayerMoving is the update for running about on the ground and in the air, the state
+// after it comes from how the player moved.
+func updatePlayerMoving(r *Player, delta float64, game *Game) string {
+	r.steer(r.physics.MaxRunVelocity)
+	return r.nextState(r.move(delta, game))
+}
+
+func enterPlayerClimb(r *Player, game *Game) {
+	r.velocityY = 0
+	r.alreadyAbortedJump = false
+	r.doubleJumpUsed = false
+	r.doubleJumping = false
+}
+
+func updatePlayerClimb(r *Player, delta float64, game *Game) string {
+	r.steer(r.physics.MaxRunVelocity / 2.0)
+	return r.nextState(r.move(delta, game))
+}
+
+// enterPlayerHurt starts the stun, the knockback has already been applied by TakeDamage.
+func enterPlayerHurt(r *Player, game *Game) {
+	r.takeDamageTimer = r.physics.TakeDamageTime
+}
+
+func exitPlayerHurt(r *Player, game *Game) {
+	r.takeDamageTimer = 0
+	r.postDamageTimer = r.physics.PostDamageTime
+}
+
+// updatePlayerHurt ignores the controls while the knockback carries the player.
+func updatePlayerHurt(r *Player, delta float64, game *Game) string {
+	r.input = playerInput{}
+	r.targetVelocityX = r.knockbackVelocityX
+	m := r.move(delta, game)
+	r.takeDamageTimer = r.takeDamageTimer - delta
+	if r.takeDamageTimer < 0 {
+		return r.nextState(m)
+	}
+	return ""
+}
+
+func enterPlayerDying(r *Player, game *Game) {
+	r.deathTimer = playerDeathTime
+	r.animations["death"].Reset()
+}
+
+func updatePlayerDying(r *Player, delta float64, game *Game) string {
+	r.deathTimer -= delta
+	if r.deathTimer < 0 {
+		game.PlayerDeath()
+	}
+	return ""
+}
+
+// nextState picks the state that fits how the player just moved.
+func (r *Player) nextState(m playerMovement) string {
+	switch {
+	case m.climbing:
+		return playerClimb
+	case m.onFloor && r.velocityY <= 0:
+		if r.input.moveX != 0 {
+			return playerRun
+		}
+		if r.input.drop {
+			return playerCrouch
+		}
+		return playerIdle
+	case m.wallSliding:
+		return playerWallSlide
+	case r.velocityY > 0:
+		return playerJump
+	}
+	return playerFall
+}
+
+// changeState moves the player to another state, running the exit and enter hooks and
+// telling the listeners. Changes the current state does not declare are logged and ignored.
+func (r *Player) changeState(name string, game *Game) {
+	if name == r.state {
+		return
+	}
+	from := playerStates[r.state]
+	to, ok := playerStates[name]
+	if !ok || !contains(from.Transitions, name) {
+		log.Println("the player cannot change from", r.state, "to", name)
+		return
+	}
+	if from.Exit != nil {
+		from.Exit(r, game)
+	}
+	r.state = name
+	if to.Enter != nil {
+		to.Enter(r, game)
+	}
+	for _, listener := range r.stateListeners {
+		listener(from.Name, name)
+	}
+}
+
+// AddStateListener calls the listener every time the player changes state.
+func (r *Player) AddStateListener(listener PlayerStateListener) {
+	r.stateListeners = append(r.stateListeners, listener)
+}
+
+// State is the name of the state the player is in.
+func (r *Player) State() string {
+	return r.state
+}
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"platformer/common"
+)
+
+const spellBulletSpeed = 200.0
+const ninetyDegreesInRads = 1.57
+
+const (
+	playerFaction = "player"
+	enemyFaction  = "enemy"
+)
+
+// Projectile is anything fired by the player or an enemy, it only hurts the other faction.
+type Projectile struct {
+	x          float64
+	y          float64
+	w          float64
+	h          float64
+	animation  *Animation
+	moveX      float64
+	moveY      float64
+	gravity    float64
+	ttl        float64
+	damage     int
+	knockback  float64
+	pierce     int
+	faction    string
+	tint       []float64
+	onHit      func(r *Projectile, game *Game)
+	hitEnemies []Enemy
+	isFlipX    bool
+	isFlipY    bool
+}
+
+func NewSpellBullet(game *Game, x, y, moveX, moveY float64) *Projectile {
+	return &Projectile{
+		x:         x,
+		y:         y,
+		moveX:     moveX,
+		moveY:     moveY,
+		ttl:       10,
+		w:         16,
+		h:         16,
+		damage:    1,
+		knockback: spellKnockback,
+		faction:   playerFaction,
+		isFlipX:   moveX < 0,
+		isFlipY:   moveY < 0,
+		onHit:     spawnSpellHitEffect,
+		animation: newAnimation(game, "spell-bullet", true),
+	}
+}
+
+func spawnSpellHitEffect(r *Projectile, game *Game) {
+	game.SpawnEffect(effectSpellHit, r.x, r.y, r.moveX < 0, 0)
+}
+
+func (r *Projectile) Update(delta float64, game *Game) {
+	r.animation.Update(delta)
+	speed := 1.0
+	if game.Level.IsInWater(r.x+(r.w/2), r.y+(r.h/2)) {
+		speed = underwaterSpeedScale
+	}
+	r.x = r.x + (r.moveX * delta * speed)
+	r.y = r.y + (((r.moveY * delta) + (0.5 * r.gravity * delta * delta)) * speed)
+	r.moveY = r.moveY + (r.gravity * delta * speed)
+	r.ttl = r.ttl - delta
+	if r.ttl < 0 {
+		r.hit(game, true)
+		return
+	}
+	hx, hy, hw, hh := r.x+6, r.y+6, 4.0, 4.0
+
+	// check for collision with Level, enemies, Player etc
+	tx, ty := int((r.x+8)/common.TileSize), int((r.y+8)/common.TileSize)
+	td := game.Level.tiledGrid.GetTileData(tx, ty)
+	if td.Block {
+		r.hit(game, true)
+		return
+	}
+
+	switch r.faction {
+	case playerFaction:
+		for _, e := range game.Level.enemies {
+			cb := e.GetCollisionBox()
+			if common.Overlap(hx, hy, hw, hh, cb.x, cb.y, cb.w, cb.h) && !r.alreadyHit(e) {
+				e.GetHurt(game, r.getDamage())
+				r.hitEnemies = append(r.hitEnemies, e)
+				r.hit(game, false)
+				return
+			}
+		}
+	case enemyFaction:
+		pb := game.Player.GetCollisionBox()
+		if common.Overlap(hx, hy, hw, hh, pb.x, pb.y, pb.w, pb.h) {
+			game.Player.TakeDamage(r.getDamage(), game)
+			r.hit(game, true)
+			return
+		}
+	}
+
+	// the barriers of a boss fight keep shots in as well as the player
+	if game.Level.arena != nil {
+		for _, c := range game.Level.arena.GetColliders() {
+			cb := c.GetCollisionBox()
+			if common.Overlap(hx, hy, hw, hh, cb.x, cb.y, cb.w, cb.h) {
+				r.hit(game, true)
+				return
+			}
+		}
+	}
+
+	for _, f := range game.Level.flimsy {
+		cb := f.GetCollisionBox()
+		if common.Overlap(hx, hy, hw, hh, cb.x, cb.y, cb.w, cb.h) {
+			if r.faction == playerFaction {
+				game.Level.RemoveFlimsy(f)
+			}
+			r.hit(game, true)
+			return
+		}
+	}
+}
+
+// getDamage puts the source just behind the projectile so knockback follows its direction of travel.
+func (r *Projectile) getDamage() DamageInfo {
+	damageType := damageTypeProjectile
+	if r.faction == playerFaction {
+		damageType = damageTypeSpell
+	}
+	return DamageInfo{
+		Amount:    r.damage,
+		Type:      damageType,
+		SourceX:   r.x + (r.w / 2) - (r.moveX * 0.05),
+		SourceY:   r.y + (r.h / 2) - (r.moveY * 0.05),
+		Knockback: r.knockback,
+	}
+}
+
+// hit runs the hit callback and removes the projectile, unless it can still pierce and
+// what it hit was not solid.
+func (r *Projectile) hit(game *Game, isSolid bool) {
+	if r.onHit != nil {
+		r.onHit(r, game)
+	}
+	if !isSolid && r.pierce > 0 {
+		r.pierce = r.pierce - 1
+		return
+	}
+	game.RemoveProjectile(r)
+}
+
+func (r *Projectile) alreadyHit(enemy Enemy) bool {
+	for _, e := range r.hitEnemies {
+		if e == enemy {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Projectile) Draw(camera common.Camera) {
+	op := &ebiten.DrawImageOptions{}
+
+	if r.isFlipX {
+		op.GeoM.Scale(-1, 1)
+		op.GeoM.Translate(r.w, 0)
+	}
+	if r.moveY != 0 && r.gravity == 0 {
+		op.GeoM.Translate(-8, -8)
+		amount := ninetyDegreesInRads
+		if r.isFlipY {
+			amount = -ninetyDegreesInRads
+		}
+		op.GeoM.Rotate(amount) // 90 degrees in rads
+		op.GeoM.Translate(8, 8)
+	}
+	op.GeoM.Translate(r.x, r.y)
+
+	op.GeoM.Scale(common.Scale, common.Scale)
+	if len(r.tint) == 4 {
+		op.ColorM.Scale(r.tint[0], r.tint[1], r.tint[2], r.tint[3])
+	}
+	camera.DrawImage(r.animation.GetCurrentFrame(), op)
+}
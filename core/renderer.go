@@ -0,0 +1,34 @@
+package core
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// Renderer draws the game. The simulation does not draw anything itself, so a game made
+// without a renderer runs without a window.
+type Renderer interface {
+	Draw(screen *ebiten.Image, game *Game)
+}
+
+// screenRenderer draws the level and everything in it through the camera, with the developer
+// tools over the top.
+type screenRenderer struct{}
+
+func (r screenRenderer) Draw(screen *ebiten.Image, game *Game) {
+	game.Level.Draw(game.Camera)
+	game.Player.Draw(game.Camera)
+	for _, s := range game.projectiles {
+		s.Draw(game.Camera)
+	}
+	for _, e := range game.effectSprites {
+		e.Draw(game.Camera)
+	}
+	for _, s := range game.spellRays {
+		s.Draw(game.Camera)
+	}
+	game.Level.DrawWater(game.Camera)
+	game.debug.Draw(game.Camera)
+	game.editor.Draw(game.Camera)
+	game.Camera.DrawBuffer(screen)
+	game.debug.DrawScreen(screen)
+	game.editor.DrawUI(screen)
+	game.console.Draw(screen)
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"math"
+	"platformer/common"
+)
+
+const spellRayRange = common.TileSize * 12
+const spellRayTime = 0.15
+
+// SpellRay is the beam left behind by the hitscan spell, the hit itself happens
+// the moment the spell is cast.
+type SpellRay struct {
+	x1    float64
+	y1    float64
+	x2    float64
+	y2    float64
+	ttl   float64
+	image *ebiten.Image
+}
+
+func (r *Game) CastRay(x, y, dirX, dirY float64) {
+	length := math.Hypot(dirX, dirY)
+	endX, endY := x+(dirX/length*spellRayRange), y+(dirY/length*spellRayRange)
+
+	hit, collider := r.Raycast(x, y, endX, endY)
+	distance := hit.Distance
+	var hitEnemy Enemy
+	for _, e := range r.Level.enemies {
+		cb := e.GetCollisionBox()
+		ok, t := common.SegmentIntersectsBox(x, y, endX, endY, cb.x, cb.y, cb.w, cb.h)
+		if ok && t*spellRayRange < distance {
+			distance = t * spellRayRange
+			hitEnemy = e
+		}
+	}
+	hitX, hitY := x+(dirX/length*distance), y+(dirY/length*distance)
+	if hitEnemy != nil {
+		hitEnemy.GetHurt(r, DamageInfo{
+			Amount:    1,
+			Type:      damageTypeSpell,
+			SourceX:   x,
+			SourceY:   y,
+			Knockback: spellKnockback,
+		})
+	} else if f, ok := collider.(*Flimsy); ok {
+		r.Level.RemoveFlimsy(f)
+	}
+	if hitEnemy != nil || hit.Hit {
+		r.SpawnEffect(effectSpellHit, hitX-8, hitY-8, dirX < 0, 0)
+	}
+	r.AddSpellRay(&SpellRay{
+		x1:    x,
+		y1:    y,
+		x2:    hitX,
+		y2:    hitY,
+		ttl:   spellRayTime,
+		image: r.res.GetImage("spell-ray"),
+	})
+}
+
+func (r *SpellRay) Update(delta float64, game *Game) {
+	r.ttl = r.ttl - delta
+	if r.ttl < 0 {
+		game.RemoveSpellRay(r)
+	}
+}
+
+func (r *SpellRay) Draw(camera common.Camera) {
+	dx, dy := r.x2-r.x1, r.y2-r.y1
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(0, -8)
+	op.GeoM.Scale(math.Hypot(dx, dy)/16.0, 2/16.0)
+	op.GeoM.Rotate(math.Atan2(dy, dx))
+	op.GeoM.Translate(r.x1, r.y1)
+	op.GeoM.Scale(common.Scale, common.Scale)
+	op.ColorM.Scale(0.7, 0.5, 1, r.ttl/spellRayTime)
+	camera.DrawImage(r.image, op)
+}
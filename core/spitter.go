@@ -0,0 +1,182 @@
+package core
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"math"
+	"platformer/common"
+)
+
+const spitterMinFlightTime = 0.3
+
+// SpitterEnemy stays where it is placed and lobs projectiles at the player, it flashes
+// during the windup so the player has time to get out of the way.
+type SpitterEnemy struct {
+	x                float64
+	y                float64
+	def              *EnemyDefinition
+	currentAnimation string
+	animations       map[string]*Animation
+	health           int
+	// ai
+	directionX    int
+	hurtTimer     float64
+	windupTimer   float64
+	cooldownTimer float64
+}
+
+func NewSpitterEnemy(x float64, y float64, def *EnemyDefinition, game *Game) *SpitterEnemy {
+	return &SpitterEnemy{
+		x:                x,
+		y:                y,
+		def:              def,
+		currentAnimation: "idle",
+		animations:       def.newAnimations(game),
+		health:           def.Health,
+		directionX:       -1,
+		cooldownTimer:    def.FireCooldown,
+	}
+}
+
+func (r *SpitterEnemy) Update(delta float64, game *Game) {
+	cb, pb := r.GetCollisionBox(), game.Player.GetCollisionBox()
+	if common.Overlap(pb.x, pb.y, pb.w, pb.h, cb.x, cb.y, cb.w, cb.h) {
+		game.Player.TakeDamage(r.def.contactDamage(cb), game)
+	}
+	r.currentAnimation = "idle"
+	if r.hurtTimer > 0 {
+		r.currentAnimation = "hurt"
+		r.hurtTimer = r.hurtTimer - delta
+		r.windupTimer = 0
+	} else {
+		r.think(delta, game)
+	}
+	r.animations[r.currentAnimation].Update(delta)
+}
+
+func (r *SpitterEnemy) think(delta float64, game *Game) {
+	if r.windupTimer > 0 {
+		r.currentAnimation = "attack"
+		r.windupTimer = r.windupTimer - delta
+		if r.windupTimer <= 0 {
+			r.fire(game)
+		}
+		return
+	}
+	r.cooldownTimer = r.cooldownTimer - delta
+	if r.cooldownTimer > 0 || !r.canSeePlayer(game) {
+		return
+	}
+	cb := r.GetCollisionBox()
+	r.directionX = directionTo(cb.x+(cb.w/2), game.Player.x+(game.Player.sizex/2))
+	r.windupTimer = r.def.WindupTime
+	r.animations["attack"].Reset()
+}
+
+func (r *SpitterEnemy) canSeePlayer(game *Game) bool {
+	p := game.Player
+	if math.Abs(r.x-p.x) > r.def.ViewDistanceX || math.Abs(r.y-p.y) > r.def.ViewDistanceY {
+		return false
+	}
+	mouthX, mouthY := r.getMouthPos()
+	return game.HasLineOfSight(mouthX, mouthY, p.x+(p.sizex/2), p.y+(p.sizey/2))
+}
+
+func (r *SpitterEnemy) getMouthPos() (float64, float64) {
+	cb := r.GetCollisionBox()
+	return cb.x + (cb.w / 2) + (float64(r.directionX) * cb.w / 2), cb.y + 4
+}
+
+// fire aims the projectile so that its arc lands where the player is standing right now.
+func (r *SpitterEnemy) fire(game *Game) {
+	r.cooldownTimer = r.def.FireCooldown
+	mouthX, mouthY := r.getMouthPos()
+	targetX, targetY := game.Player.x+(game.Player.sizex/2), game.Player.y+(game.Player.sizey/2)
+	dx, dy := targetX-mouthX, targetY-mouthY
+	flightTime := math.Max(math.Abs(dx)/r.def.ProjectileSpeed, spitterMinFlightTime)
+	moveX := dx / flightTime
+	moveY := (dy - (0.5 * r.def.ProjectileGravity * flightTime * flightTime)) / flightTime
+	game.AddProjectile(NewSpitProjectile(game, mouthX-8, mouthY-8, moveX, moveY, r.def))
+}
+
+func NewSpitProjectile(game *Game, x, y, moveX, moveY float64, def *EnemyDefinition) *Projectile {
+	return &Projectile{
+		x:         x,
+		y:         y,
+		moveX:     moveX,
+		moveY:     moveY,
+		gravity:   def.ProjectileGravity,
+		ttl:       4,
+		w:         16,
+		h:         16,
+		damage:    def.ProjectileDamage,
+		knockback: def.Knockback,
+		faction:   enemyFaction,
+		tint:      []float64{0.5, 1, 0.4, 1},
+		isFlipX:   moveX < 0,
+		onHit:     spawnSpitHitEffect,
+		animation: newAnimation(game, "spell-bullet", true),
+	}
+}
+
+func spawnSpitHitEffect(r *Projectile, game *Game) {
+	game.SpawnEffect(effectCrawlerSpray, r.x-4, r.y-4, r.moveX < 0, 0)
+}
+
+func (r *SpitterEnemy) Draw(camera common.Camera) {
+	op := &ebiten.DrawImageOptions{}
+	if r.directionX > 0 {
+		op.GeoM.Scale(-1, 1)
+		op.GeoM.Translate(r.def.DrawSize, 0)
+	}
+	op.GeoM.Translate(r.x+r.def.DrawOffsetX, r.y+r.def.DrawOffsetY)
+	op.GeoM.Scale(common.Scale, common.Scale)
+	r.def.applyTint(op)
+	if r.windupTimer > 0 && math.Mod(r.windupTimer, 0.2) > 0.1 {
+		op.ColorM.Translate(0.4, 0.4, 0.4, 0)
+	}
+	camera.DrawImage(r.animations[r.currentAnimation].GetCurrentFrame(), op)
+}
+
+// GetHurt ignores knockback, spitters are rooted to the spot.
+func (r *SpitterEnemy) GetHurt(game *Game, damage DamageInfo) {
+	if r.health <= 0 {
+		return
+	}
+	r.health = r.health - damage.Amount
+	r.hurtTimer = r.def.HurtTime
+	r.animations["hurt"].Play()
+	game.Feedback(hurtFeedback(damage))
+	if r.def.HurtEffect != "" {
+		game.SpawnEffect(r.def.HurtEffect, r.x, r.y, r.directionX > 0, 0)
+	}
+	if r.health <= 0 {
+		game.SpawnEffect(r.def.DeathEffect, r.x, r.y, r.directionX > 0, 0)
+		game.Level.RemoveEnemy(r)
+	}
+}
+
+func (r *SpitterEnemy) describeDebug(game *Game) debugInfo {
+	info := debugInfo{
+		state: "watch",
+	}
+	switch {
+	case r.hurtTimer > 0:
+		info.state = "hurt"
+	case r.windupTimer > 0:
+		info.state = "windup"
+		info.hasTarget = true
+		info.targetX, info.targetY = game.Player.x+(game.Player.sizex/2), game.Player.y+(game.Player.sizey/2)
+	case r.cooldownTimer > 0:
+		info.state = "cooldown"
+	}
+	return info
+}
+
+func (r *SpitterEnemy) GetCollisionBox() CollisionBox {
+	return CollisionBox{
+		x: r.x + r.def.Hitbox.X,
+		y: r.y + r.def.Hitbox.Y,
+		w: r.def.Hitbox.W,
+		h: r.def.Hitbox.H,
+	}
+}
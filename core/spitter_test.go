@@ -0,0 +1,37 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestSpitterLoadsAndHitsPlayer(t *testing.T) {
+	game, _ := newTestGame("level-gamma")
+	var spitter *SpitterEnemy
+	for _, e := range game.Level.enemies {
+		if s, ok := e.(*SpitterEnemy); ok {
+			spitter = s
+		}
+	}
+	if spitter == nil {
+		t.Fatal("there are no spitters in level-gamma")
+	}
+	// stand on the floor in front of its block and keep still
+	cb := spitter.GetCollisionBox()
+	game.Player.x = cb.x - 96
+	game.Player.y = cb.y + cb.h
+	step(t, game, 30)
+
+	fired := false
+	for i := 0; i < 300 && game.Player.Health == startingHealth; i++ {
+		step(t, game, 1)
+		for _, p := range game.projectiles {
+			fired = fired || p.faction == enemyFaction
+		}
+	}
+	if !fired {
+		t.Fatalf("the spitter did not fire at the player, it is %v", spitter.describeDebug(game).state)
+	}
+	if game.Player.Health == startingHealth {
+		t.Errorf("the spit did not land on the player standing still")
+	}
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"math"
+	"platformer/common"
+)
+
+// The player and ground enemies all move over floors through these, so ice, conveyors and
+// springs treat every body the same.
+
+// surfaceAcc is how much a body can change its speed in an update, slippery floors scale it
+// down while the body is standing on them.
+func surfaceAcc(acc float64, floor *common.TileData, onFloor bool) float64 {
+	if onFloor {
+		return acc * floor.Friction
+	}
+	return acc
+}
+
+// approach moves the velocity towards the target, changing it by at most acc.
+func approach(velocity, target, acc float64) float64 {
+	if velocity < target {
+		return math.Min(velocity+acc, target)
+	}
+	return math.Max(velocity-acc, target)
+}
+
+// surfaceMoveX is how far a body going at the velocity moves in an update, conveyors carry it
+// along on top of that.
+func surfaceMoveX(velocityX float64, floor *common.TileData, delta float64) float64 {
+	return (velocityX + floor.Conveyor) * delta
+}
+
+// surfaceBounce returns the upwards velocity of a body after the collision, springs launch a
+// body that lands on them, true is returned when it was launched.
+func surfaceBounce(cr CollisionResult, velocityY float64) (float64, bool) {
+	floor := cr.floorTile()
+	if cr.hitFloor && floor.Bounce > 0 {
+		return math.Max(velocityY, floor.Bounce), true
+	}
+	return velocityY, false
+}
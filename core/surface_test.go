@@ -0,0 +1,117 @@
+package core
+
+import (
+	"math"
+	"platformer/common"
+	"testing"
+)
+
+// findTile returns the first tile of the level's ground that matches.
+func findTile(t *testing.T, game *Game, match func(td *common.TileData) bool) (int, int) {
+	t.Helper()
+	grid := game.Level.tiledGrid
+	for ty := 0; ty < grid.GroundLayer.Height; ty++ {
+		for tx := 0; tx < grid.GroundLayer.Width; tx++ {
+			if match(grid.GetTileData(tx, ty)) {
+				return tx, ty
+			}
+		}
+	}
+	t.Fatal("there is no such tile in the level")
+	return 0, 0
+}
+
+// crawlerOn puts a new crawler going right with its feet on top of the tile.
+func crawlerOn(game *Game, tx, ty int) *CrawlerEnemy {
+	def := game.enemyDefinitions["crawler"]
+	crawler := NewCrawlerEnemy(float64(tx)*common.TileSize, (float64(ty)*common.TileSize)-def.DrawOffsetY-def.DrawSize, def, game)
+	game.Level.AddEnemy(crawler)
+	return crawler
+}
+
+func isIce(td *common.TileData) bool {
+	return td.Friction < 1
+}
+
+func TestSurfacesLoadFromLevel(t *testing.T) {
+	game, _ := newTestGame("level-gamma")
+	findTile(t, game, isIce)
+	findTile(t, game, func(td *common.TileData) bool { return td.Conveyor < 0 })
+	findTile(t, game, func(td *common.TileData) bool { return td.Conveyor > 0 })
+	findTile(t, game, func(td *common.TileData) bool { return td.Bounce > 0 })
+}
+
+func TestCrawlerSlidesOnIce(t *testing.T) {
+	game, _ := newTestGame("level-gamma")
+	tx, ty := findTile(t, game, isIce)
+	onIce := crawlerOn(game, tx+4, ty)
+	onRock := crawlerOn(game, tx-3, ty)
+	step(t, game, 1)
+	iceStart, rockStart := onIce.x, onRock.x
+
+	step(t, game, 6)
+	if onIce.x-iceStart >= onRock.x-rockStart {
+		t.Errorf("on ice the crawler went %v, on rock %v, want it slower to get going on ice",
+			onIce.x-iceStart, onRock.x-rockStart)
+	}
+	if onRock.velocityX != onRock.moveSpeed {
+		t.Errorf("on rock the crawler is going %v, want full speed %v", onRock.velocityX, onRock.moveSpeed)
+	}
+}
+
+func TestConveyorsCarryCrawlers(t *testing.T) {
+	game, _ := newTestGame("level-gamma")
+	tx, ty := findTile(t, game, func(td *common.TileData) bool { return td.Conveyor < 0 })
+	crawler := crawlerOn(game, tx+2, ty)
+	step(t, game, 30)
+	start := crawler.x
+	step(t, game, 10)
+	want := (crawler.moveSpeed + game.Level.tiledGrid.GetTileData(tx, ty).Conveyor) * testDelta * 10
+	if moved := crawler.x - start; moved > want+0.01 || moved < want-0.01 {
+		t.Errorf("the crawler went %v against the conveyor, want %v", moved, want)
+	}
+}
+
+func TestSpringsLaunchEnemies(t *testing.T) {
+	game, _ := newTestGame("level-gamma")
+	tx, ty := findTile(t, game, func(td *common.TileData) bool { return td.Bounce > 0 })
+	crawler := crawlerOn(game, tx, ty)
+	blob := NewBlobEnemy(float64(tx)*common.TileSize, float64(ty-3)*common.TileSize, game.enemyDefinitions["blob"], game)
+	game.Level.AddEnemy(blob)
+	crawlerStart, blobStart := crawler.y, blob.y
+
+	crawlerTop, blobTop := crawler.y, blob.y
+	for i := 0; i < 60; i++ {
+		step(t, game, 1)
+		crawlerTop = math.Min(crawlerTop, crawler.y)
+		blobTop = math.Min(blobTop, blob.y)
+	}
+	if crawlerTop > crawlerStart-common.TileSize {
+		t.Errorf("the crawler only got up to %v from %v", crawlerTop, crawlerStart)
+	}
+	if blobTop > blobStart-common.TileSize {
+		t.Errorf("the blob only got up to %v from %v", blobTop, blobStart)
+	}
+}
+
+func TestBlobSlidesOnIce(t *testing.T) {
+	game, _ := newTestGame("level-gamma")
+	tx, ty := findTile(t, game, isIce)
+	def := game.enemyDefinitions["blob"]
+	blob := NewBlobEnemy((float64(tx+4)*common.TileSize)-def.Hitbox.X, (float64(ty)*common.TileSize)-def.Hitbox.Y-def.Hitbox.H, def, game)
+	game.Level.AddEnemy(blob)
+	step(t, game, 1)
+	if !blob.touchingGround || !isIce(blob.standingOn) {
+		t.Fatal("the blob is not standing on the ice")
+	}
+
+	// knocked back, it keeps going until the ice slows it down
+	blob.velocityX = def.GroundSpeed
+	blob.targetX = blob.x
+	start := blob.x
+	step(t, game, 20)
+	stopped := def.GroundSpeed * enemyAccelerationTime / 2
+	if blob.x-start <= stopped {
+		t.Errorf("the blob slid %v, on rock it would have stopped in %v", blob.x-start, stopped)
+	}
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"image/color"
+	"math"
+	"platformer/common"
+)
+
+const (
+	swimGravity         = -160.0
+	swimMaxSinkVelocity = 60.0
+	swimStrokeVelocity  = 120.0
+	swimSpeedScale      = 0.6
+	// underwaterSpeedScale slows projectiles down while they are in water
+	underwaterSpeedScale = 0.4
+	blobBuoyancy         = 300.0
+	blobMaxFloatVelocity = 50.0
+	// bodies only splash when they cross the surface faster than splashMinVelocity, and not
+	// again until splashCooldown has passed, so bobbing at the surface stays quiet
+	splashMinVelocity = 60.0
+	splashCooldown    = 0.5
+)
+
+var waterColor = color.RGBA{R: 40, G: 110, B: 200, A: 90}
+
+// findWaterTiles turns each row of water tiles into one area, so water drawn with tiles and
+// water drawn with objects are treated the same.
+func (r *Level) findWaterTiles() {
+	r.waterTiles = []CollisionBox{}
+	layer := r.tiledGrid.GroundLayer
+	for ty := 0; ty < layer.Height; ty++ {
+		start := -1
+		for tx := 0; tx <= layer.Width; tx++ {
+			isWater := tx < layer.Width && r.tiledGrid.GetTileData(tx, ty).Water
+			if isWater && start < 0 {
+				start = tx
+			}
+			if !isWater && start >= 0 {
+				r.waterTiles = append(r.waterTiles, CollisionBox{
+					x: float64(start) * common.TileSize,
+					y: float64(ty) * common.TileSize,
+					w: float64(tx-start) * common.TileSize,
+					h: common.TileSize,
+				})
+				start = -1
+			}
+		}
+	}
+}
+
+// IsInWater is true when the point is in a water tile or inside a water object.
+func (r *Level) IsInWater(x, y float64) bool {
+	for _, areas := range [][]CollisionBox{r.waterObjects, r.waterTiles} {
+		for _, a := range areas {
+			if common.Contains(a.x, a.y, a.w, a.h, x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// canSplash is true when a body going in or out of the water is moving fast enough to
+// splash and hasn't just splashed.
+func canSplash(velocityY, splashTimer float64) bool {
+	return splashTimer <= 0 && math.Abs(velocityY) >= splashMinVelocity
+}
+
+// DrawWater tints everything in the water, it is drawn after the player and effects so they
+// look submerged.
+func (r *Level) DrawWater(camera common.Camera) {
+	for _, areas := range [][]CollisionBox{r.waterObjects, r.waterTiles} {
+		for _, a := range areas {
+			drawRect(camera, r.waterImage, waterColor, a.x, a.y, a.w, a.h)
+		}
+	}
+}
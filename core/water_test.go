@@ -0,0 +1,81 @@
+package core
+
+import (
+	"testing"
+)
+
+// countSplashes steps the game and counts the updates where the body with the timer splashed.
+func countSplashes(t *testing.T, game *Game, splashTimer *float64, updates int) int {
+	t.Helper()
+	splashes := 0
+	for i := 0; i < updates; i++ {
+		step(t, game, 1)
+		// the timer counts down before the check, so it is only full after a splash
+		if *splashTimer == splashCooldown {
+			splashes++
+		}
+	}
+	return splashes
+}
+
+// findBlob returns the first blob in the level.
+func findBlob(t *testing.T, game *Game) *BlobEnemy {
+	t.Helper()
+	for _, e := range game.Level.enemies {
+		if blob, ok := e.(*BlobEnemy); ok {
+			return blob
+		}
+	}
+	t.Fatal("there are no blobs in the level")
+	return nil
+}
+
+func TestWaterLoadsFromLevel(t *testing.T) {
+	game, _ := newTestGame("level-gamma")
+	if len(game.Level.waterTiles) == 0 || len(game.Level.waterObjects) == 0 {
+		t.Fatalf("level-gamma has %v rows of water tiles and %v water objects, want some of both",
+			len(game.Level.waterTiles), len(game.Level.waterObjects))
+	}
+	for _, areas := range [][]CollisionBox{game.Level.waterTiles, game.Level.waterObjects} {
+		a := areas[0]
+		if !game.Level.IsInWater(a.x+1, a.y+1) {
+			t.Errorf("the top corner of the water at %v,%v is dry", a.x, a.y)
+		}
+		if game.Level.IsInWater(a.x+1, a.y-1) {
+			t.Errorf("above the water at %v,%v is wet", a.x, a.y)
+		}
+	}
+}
+
+func TestBobbingBlobSplashesOnce(t *testing.T) {
+	game, _ := newTestGame("level-gamma")
+	blob := findBlob(t, game)
+	if game.Level.IsInWater(blob.x, blob.y) {
+		t.Fatal("the blob should start above the water")
+	}
+
+	if splashes := countSplashes(t, game, &blob.splashTimer, 60); splashes != 1 {
+		t.Errorf("falling in splashed %v times, want 1", splashes)
+	}
+	if !blob.inWater {
+		t.Fatal("the blob is not in the water")
+	}
+	// long enough to bob up and down at the surface a few times
+	if splashes := countSplashes(t, game, &blob.splashTimer, 240); splashes != 0 {
+		t.Errorf("bobbing splashed %v times, want 0", splashes)
+	}
+}
+
+func TestPlayerSplashesIntoPool(t *testing.T) {
+	game, _ := newTestGame("level-gamma")
+	pool := game.Level.waterObjects[0]
+	game.Player.x = pool.x + (pool.w / 2)
+	game.Player.y = pool.y - 48
+
+	if splashes := countSplashes(t, game, &game.Player.splashTimer, 120); splashes != 1 {
+		t.Errorf("jumping in splashed %v times, want 1", splashes)
+	}
+	if !game.Player.inWater {
+		t.Errorf("the player is not in the water")
+	}
+}
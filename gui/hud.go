@@ -3,6 +3,7 @@ package gui
 import (
 	"github.com/hajimehoshi/ebiten/v2"
 	"image"
+	"math"
 	"platformer/common"
 	"platformer/core"
 	"platformer/res"
@@ -12,7 +13,13 @@ type Hud struct {
 	healthBarBackgroundImage *ebiten.Image
 	healthBarHealthImage     *ebiten.Image
 	healthBarEndImage        *ebiten.Image
+	airBubbleImage           *ebiten.Image
 	healthPercent            float64
+	airPercent               float64
+	showAir                  bool
+	showBoss                 bool
+	bossName                 string
+	bossHealthPercent        float64
 }
 
 func NewHud(resources *res.Resources) *Hud {
@@ -20,11 +27,19 @@ func NewHud(resources *res.Resources) *Hud {
 		healthBarBackgroundImage: resources.GetImage("health-bar-background"),
 		healthBarEndImage:        resources.GetImage("health-bar-end"),
 		healthBarHealthImage:     resources.GetImage("health-bar"),
+		airBubbleImage:           resources.GetImage("air-bubble"),
 	}
 }
 
 func (r *Hud) Update(delta float64, game *core.Game) {
 	r.healthPercent = float64(game.Player.Health) / float64(game.Player.MaxHealth)
+	r.airPercent, r.showAir = game.Player.GetAir(game)
+	boss := game.Level.GetActiveBoss()
+	r.showBoss = boss != nil
+	if boss != nil {
+		r.bossName = boss.GetName()
+		r.bossHealthPercent = boss.GetHealthPercent()
+	}
 }
 
 func (r *Hud) Draw(screen *ebiten.Image) {
@@ -46,4 +61,52 @@ func (r *Hud) Draw(screen *ebiten.Image) {
 	op.GeoM.Translate(3+healthPercentXPos, 5)
 	op.GeoM.Scale(common.Scale, common.Scale)
 	screen.DrawImage(r.healthBarEndImage, op)
+
+	if r.showAir {
+		r.drawAir(screen)
+	}
+	if r.showBoss {
+		r.drawBossBar(screen)
+	}
+}
+
+const airBubbles = 6
+
+// drawAir shows the air left as a row of bubbles under the health bar, the last one fades
+// out as it is used up.
+func (r *Hud) drawAir(screen *ebiten.Image) {
+	left := r.airPercent * airBubbles
+	for i := 0; i < airBubbles && float64(i) < left; i++ {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(4+float64(i*7), 12)
+		op.GeoM.Scale(common.Scale, common.Scale)
+		op.ColorM.Scale(1, 1, 1, math.Min(1, left-float64(i)))
+		screen.DrawImage(r.airBubbleImage, op)
+	}
+}
+
+const bossBarScale = 2
+
+func (r *Hud) drawBossBar(screen *ebiten.Image) {
+	x := (common.ScreenWidth / 2.0) - (48 * bossBarScale / 2.0)
+	y := float64(common.ScreenHeight - 12)
+	bossHealthXPos := 46 * r.bossHealthPercent
+
+	common.DrawText(screen, r.bossName, x, y-8)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(bossBarScale, 1)
+	op.GeoM.Translate(x, y)
+	op.GeoM.Scale(common.Scale, common.Scale)
+	screen.DrawImage(r.healthBarBackgroundImage, op)
+
+	if int(bossHealthXPos) > 0 {
+		op = &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(bossBarScale, 1)
+		op.GeoM.Translate(x, y)
+		op.GeoM.Scale(common.Scale, common.Scale)
+		op.ColorM.Scale(1, 0.4, 0.4, 1)
+		img := r.healthBarHealthImage.SubImage(image.Rect(0, 0, int(bossHealthXPos), 6)).(*ebiten.Image)
+		screen.DrawImage(img, op)
+	}
 }
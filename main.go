@@ -4,10 +4,19 @@ import (
 	"errors"
 	"github.com/hajimehoshi/ebiten/v2"
 	"log"
+	"os"
 	"platformer/common"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == convertAseCommand {
+		check := len(os.Args) > 2 && os.Args[2] == "-check"
+		if err := convertAsepriteFiles(resourceDirectory, check); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	runner := NewRunner()
 
 	ebiten.SetWindowSize(common.ScreenWidth*common.Scale, common.ScreenHeight*common.Scale)
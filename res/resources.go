@@ -1,62 +1,333 @@
 package res
 
 import (
+	"encoding/json"
 	"github.com/hajimehoshi/ebiten/v2"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"log"
 	"platformer/common"
+	"strings"
 )
 
+const manifestFileName = "res/manifest.json"
+const placeholderSize = 16
+
+type manifest struct {
+	Images []*manifestEntry `json:"images"`
+	Sounds []*manifestEntry `json:"sounds"`
+	Fonts  []*manifestEntry `json:"fonts"`
+}
+
+// manifestEntry is one asset, the file is relative to res/. Images can be a png or an .ase
+// file, a png can have an Aseprite json export as its sprite.
+type manifestEntry struct {
+	Name   string   `json:"name"`
+	File   string   `json:"file"`
+	Sprite string   `json:"sprite"`
+	Groups []string `json:"groups"`
+	// Loop is for sounds, like music, that play until they are stopped
+	Loop bool `json:"loop"`
+}
+
+// asset is loaded the first time it is used. refs counts the groups that are holding it,
+// an asset that was first used while no group was holding it is pinned and never freed.
+type asset struct {
+	entry    *manifestEntry
+	isSound  bool
+	isLoaded bool
+	refs     int
+	pinned   bool
+	image    *ebiten.Image
+	sheet    *common.SpriteSheet
+}
+
 type Resources struct {
-	images map[string]*ebiten.Image
+	images           map[string]*asset
+	fonts            map[string]*asset
+	sounds           map[string]*asset
+	groups           map[string][]*asset
+	soundManager     *common.SoundManager
+	placeholder      *ebiten.Image
+	placeholderSheet *common.SpriteSheet
+	warnings         map[string]bool
+	// headless resources only load sprite sheets, their images are nil and sounds are silent
+	headless bool
 }
 
 func NewResources() *Resources {
-	return &Resources{
-		images: map[string]*ebiten.Image{
-			"player-run":            common.LoadImage("player-run.png"),
-			"player-idle":           common.LoadImage("player-idle.png"),
-			"player-jump":           common.LoadImage("player-jump.png"),
-			"player-fall":           common.LoadImage("player-fall.png"),
-			"player-hurt":           common.LoadImage("player-hurt.png"),
-			"player-death":          common.LoadImage("player-death.png"),
-			"player-climb":          common.LoadImage("player-climb.png"),
-			"player-crouch":         common.LoadImage("player-crouch.png"),
-			"player-cast":           common.LoadImage("player-cast.png"),
-			"player-run-cast":       common.LoadImage("player-run-cast.png"),
-			"player-jump-cast":      common.LoadImage("player-jump-cast.png"),
-			"player-fall-cast":      common.LoadImage("player-fall-cast.png"),
-			"book-pickup":           common.LoadImage("book.png"),
-			"health-pickup":         common.LoadImage("health.png"),
-			"crawler-run":           common.LoadImage("crawler-run.png"),
-			"crawler-idle":          common.LoadImage("crawler-idle.png"),
-			"crawler-hurt":          common.LoadImage("crawler-hurt.png"),
-			"crawler-die":           common.LoadImage("crawler-die.png"),
-			"blob-run":              common.LoadImage("blob-run.png"),
-			"blob-idle":             common.LoadImage("blob-idle.png"),
-			"blob-hurt":             common.LoadImage("blob-hurt.png"),
-			"blob-die":              common.LoadImage("blob-die.png"),
-			"blob-jump":             common.LoadImage("blob-jump.png"),
-			"blob-attack":           common.LoadImage("blob-attack.png"),
-			"spell-bullet":          common.LoadImage("spell-bullet.png"),
-			"effect-spell-hit":      common.LoadImage("effect-spell-hit.png"),
-			"effect-cast-spell":     common.LoadImage("cast-effect.png"),
-			"effect-crawler-spray":  common.LoadImage("effect-crawler-spray.png"),
-			"health-bar":            common.LoadImage("health-bar.png"),
-			"health-bar-background": common.LoadImage("health-bar-background.png"),
-			"health-bar-end":        common.LoadImage("health-bar-end.png"),
-			"flimsy":                common.LoadImage("flimsy.png"),
-			"book-page":             common.LoadImage("book-page.png"),
-			"book-cover":            common.LoadImage("book-cover.png"),
-			"popup-sign":            common.LoadImage("pop-up-sign.png"),
-			"sign":                  common.LoadImage("sign.png"),
-			"stone-sign":            common.LoadImage("stone-sign.png"),
-		},
+	return newResources(false)
+}
+
+// NewHeadlessResources loads the same assets without making any images or opening an audio
+// device, for running the game without a window. Every image it returns is nil, so nothing
+// using them can be drawn.
+func NewHeadlessResources() *Resources {
+	return newResources(true)
+}
+
+func newResources(headless bool) *Resources {
+	b, err := ioutil.ReadFile(manifestFileName)
+	if err != nil {
+		log.Fatal("opening resource manifest ", err.Error())
+	}
+	m := &manifest{}
+	if err = json.Unmarshal(b, m); err != nil {
+		log.Fatal("parsing resource manifest ", err.Error())
+	}
+	r := &Resources{
+		images:           map[string]*asset{},
+		fonts:            map[string]*asset{},
+		sounds:           map[string]*asset{},
+		groups:           map[string][]*asset{},
+		placeholderSheet: common.NewStripSpriteSheet(placeholderSize, placeholderSize),
+		warnings:         map[string]bool{},
+		headless:         headless,
+	}
+	if headless {
+		r.soundManager = common.NewSilentManager()
+	} else {
+		r.soundManager = common.NewManager()
+		r.placeholder = newPlaceholderImage()
+	}
+	r.addAssets(r.images, m.Images, false)
+	r.addAssets(r.fonts, m.Fonts, false)
+	r.addAssets(r.sounds, m.Sounds, true)
+	for _, e := range m.Sounds {
+		if e.Loop {
+			r.soundManager.AddLoopingSoundFile(e.Name, "res/"+e.File)
+		} else {
+			r.soundManager.AddSoundFile(e.Name, "res/"+e.File)
+		}
+	}
+	return r
+}
+
+func (r *Resources) addAssets(assets map[string]*asset, entries []*manifestEntry, isSound bool) {
+	for _, e := range entries {
+		a := &asset{
+			entry:   e,
+			isSound: isSound,
+		}
+		assets[e.Name] = a
+		for _, group := range e.Groups {
+			r.groups[group] = append(r.groups[group], a)
+		}
 	}
 }
 
+// GetImage returns the image, loading it if this is the first time it is used. Missing
+// images are drawn as a placeholder so they are easy to spot. Headless resources return nil
+// for every image, a headless game never draws so only code that uses an image while
+// updating has to check for nil.
 func (r *Resources) GetImage(name string) *ebiten.Image {
-	img, ok := r.images[name]
+	a, ok := r.images[name]
 	if !ok {
-		panic("missing resource " + name)
+		r.warn(name, "missing image")
+		return r.placeholder
+	}
+	r.load(a)
+	return a.image
+}
+
+// GetSpriteSheet returns the frames and tags of the image, images without sprite metadata
+// are split into square frames.
+func (r *Resources) GetSpriteSheet(name string) *common.SpriteSheet {
+	a, ok := r.images[name]
+	if !ok {
+		r.warn(name, "missing image")
+		return r.placeholderSheet
+	}
+	r.load(a)
+	return a.sheet
+}
+
+// GetFont returns the font image, nil for headless resources like GetImage.
+func (r *Resources) GetFont(name string) *ebiten.Image {
+	a, ok := r.fonts[name]
+	if !ok {
+		r.warn(name, "missing font")
+		return r.placeholder
+	}
+	r.load(a)
+	return a.image
+}
+
+func (r *Resources) GetSoundManager() *common.SoundManager {
+	return r.soundManager
+}
+
+// PreloadGroup loads every asset in the group and holds on to them until the group is unloaded.
+func (r *Resources) PreloadGroup(group string) {
+	assets, ok := r.groups[group]
+	if !ok {
+		r.warn(group, "missing resource group")
+		return
+	}
+	for _, a := range assets {
+		a.refs = a.refs + 1
+		r.load(a)
+	}
+}
+
+// UnloadGroup lets go of the assets in the group, any that are not held by another group are freed.
+func (r *Resources) UnloadGroup(group string) {
+	for _, a := range r.groups[group] {
+		if a.refs == 0 {
+			continue
+		}
+		a.refs = a.refs - 1
+		if a.refs == 0 && !a.pinned {
+			r.unload(a)
+		}
+	}
+}
+
+func (r *Resources) load(a *asset) {
+	if a.isLoaded {
+		return
+	}
+	a.isLoaded = true
+	a.pinned = a.refs == 0
+	if a.isSound {
+		r.soundManager.LoadSound(a.entry.Name, "res/"+a.entry.File)
+		return
+	}
+	img, sheet, err := decodeImageAndSheet(a.entry)
+	if err != nil {
+		r.warn(a.entry.Name, err.Error())
+		a.image, a.sheet = r.placeholder, r.placeholderSheet
+		return
+	}
+	if !r.headless {
+		a.image = ebiten.NewImageFromImage(img)
+	}
+	a.sheet = sheet
+}
+
+// ReloadFile reads any loaded images that use the file, relative to res/, again and returns
+// their names. Images that are still the same size are updated in place, so sprites that are
+// already drawing them change straight away.
+func (r *Resources) ReloadFile(fileName string) []string {
+	reloaded := []string{}
+	for name, a := range r.images {
+		if a.isLoaded && (a.entry.File == fileName || a.entry.Sprite == fileName) {
+			r.reload(a)
+			reloaded = append(reloaded, name)
+		}
+	}
+	for name, a := range r.fonts {
+		if a.isLoaded && a.entry.File == fileName {
+			r.reload(a)
+			reloaded = append(reloaded, name)
+		}
+	}
+	return reloaded
+}
+
+// ReloadAll reads every loaded image again.
+func (r *Resources) ReloadAll() {
+	for _, a := range r.images {
+		if a.isLoaded {
+			r.reload(a)
+		}
+	}
+	for _, a := range r.fonts {
+		if a.isLoaded {
+			r.reload(a)
+		}
+	}
+}
+
+func (r *Resources) reload(a *asset) {
+	img, sheet, err := decodeImageAndSheet(a.entry)
+	if err != nil {
+		r.warn(a.entry.Name, err.Error())
+		return
+	}
+	if !r.headless {
+		if a.image == r.placeholder {
+			a.image = ebiten.NewImageFromImage(img)
+		} else if !common.ReplacePixels(a.image, img) {
+			log.Println("warning: size of", a.entry.Name, "changed, only new sprites will use it")
+			a.image = ebiten.NewImageFromImage(img)
+		}
+	}
+	if a.sheet == r.placeholderSheet {
+		a.sheet = sheet
+	} else {
+		version := a.sheet.Version
+		*a.sheet = *sheet
+		a.sheet.Version = version + 1
+	}
+}
+
+func (r *Resources) unload(a *asset) {
+	if !a.isLoaded {
+		return
+	}
+	a.isLoaded = false
+	if a.isSound {
+		r.soundManager.UnloadSound(a.entry.Name)
+		return
+	}
+	if a.image != nil && a.image != r.placeholder {
+		a.image.Dispose()
+	}
+	a.image = nil
+	a.sheet = nil
+}
+
+// warn logs each problem once, rather than every frame the asset is asked for.
+func (r *Resources) warn(name string, problem string) {
+	key := name + problem
+	if r.warnings[key] {
+		return
+	}
+	r.warnings[key] = true
+	log.Println("warning:", problem, name)
+}
+
+// decodeImageAndSheet composites .ase files, otherwise it reads the png and the sprite sheet
+// json if there is one.
+func decodeImageAndSheet(e *manifestEntry) (image.Image, *common.SpriteSheet, error) {
+	if strings.HasSuffix(e.File, ".ase") {
+		ase, err := common.LoadAseprite(e.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		img, sheet := ase.Composite()
+		return img, sheet, nil
+	}
+	img, err := common.DecodeImage("res/" + e.File)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e.Sprite != "" {
+		sheet, err := common.LoadSpriteSheet(e.Sprite)
+		if err != nil {
+			return nil, nil, err
+		}
+		return img, sheet, nil
+	}
+	bounds := img.Bounds()
+	return img, common.NewStripSpriteSheet(bounds.Dx(), bounds.Dy()), nil
+}
+
+// newPlaceholderImage is a magenta and black checker board.
+func newPlaceholderImage() *ebiten.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, placeholderSize, placeholderSize))
+	magenta := color.NRGBA{R: 255, B: 255, A: 255}
+	black := color.NRGBA{A: 255}
+	for y := 0; y < placeholderSize; y++ {
+		for x := 0; x < placeholderSize; x++ {
+			c := black
+			if ((x/4)+(y/4))%2 == 0 {
+				c = magenta
+			}
+			img.SetNRGBA(x, y, c)
+		}
 	}
-	return img
+	return ebiten.NewImageFromImage(img)
 }
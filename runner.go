@@ -22,6 +22,7 @@ type Runner struct {
 
 func NewRunner() *Runner {
 	resources := res.NewResources()
+	common.SetTextImage(resources.GetFont("text"))
 	r := &Runner{
 		firstUpdate:   true,
 		res:           resources,
@@ -42,6 +43,7 @@ func (r *Runner) Update() error {
 	}
 	delta := float64(time.Now().Sub(r.lastUpdateCalled).Milliseconds()) / 1000
 	r.lastUpdateCalled = time.Now()
+	isTyping := r.game.IsTyping()
 
 	err := r.game.Update(delta)
 	if err != nil {
@@ -53,12 +55,18 @@ func (r *Runner) Update() error {
 		return err
 	}
 
+	if isTyping {
+		return nil
+	}
 	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
 		return common.NormalEscapeError
 	}
 	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
 		ebiten.SetFullscreen(!ebiten.IsFullscreen())
 	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyM) {
+		r.game.SetReduceMotion(!r.game.ReduceMotion())
+	}
 
 	return nil
 }
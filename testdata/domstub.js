@@ -0,0 +1,21 @@
+// a do-nothing browser for running ebiten's js init under node
+const anything = new Proxy(function () {}, {
+  get(target, prop) {
+    if (prop === Symbol.toPrimitive) return () => 0;
+    if (prop === "then") return undefined;
+    return anything;
+  },
+  apply() { return anything; },
+  construct() { return anything; },
+  set() { return true; },
+});
+class Document { get hidden() { return false; } }
+const doc = Object.create(Document.prototype);
+doc.hasFocus = () => true;
+doc.body = anything; doc.head = anything; doc.documentElement = anything;
+doc.createElement = () => anything;
+doc.addEventListener = () => {};
+globalThis.Document = Document;
+globalThis.document = doc;
+globalThis.window = anything;
+globalThis.requestAnimationFrame = anything;